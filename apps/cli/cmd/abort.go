@@ -0,0 +1,68 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"cli/pkg/util"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"types"
+
+	"github.com/spf13/cobra"
+)
+
+// abortCmd represents the abort command
+var abortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Abort running or pending resources",
+	Long: `Abort running or pending resources.
+
+An aborted scenario cannot be activated again.`,
+}
+
+var abortScenarioCmd = &cobra.Command{
+	Use:   "scenario",
+	Short: "Abort a scenario",
+	Long:  `Abort a scenario.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scenarioId, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			return err
+		}
+		reason, err := cmd.Flags().GetString("reason")
+		if err != nil {
+			return err
+		}
+		reqBytes, err := json.Marshal(types.ScenarioTransitionRequest{Reason: reason})
+		if err != nil {
+			return err
+		}
+		endpoint, err := config.SubresourceEndpoint("scenarios", "abort", "create", "id", strconv.Itoa(scenarioId))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("failed to abort scenario: %s", string(resp.Body))
+		}
+
+		fmt.Printf("Successfully aborted scenario with ID %d\n", scenarioId)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(abortCmd)
+
+	abortScenarioCmd.Flags().IntP("id", "i", 0, "The ID of the scenario to abort")
+	abortScenarioCmd.Flags().StringP("reason", "r", "", "Why the scenario is being aborted")
+	abortCmd.AddCommand(abortScenarioCmd)
+}
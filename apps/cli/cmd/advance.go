@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"cli/pkg/util"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"types"
+
+	"github.com/spf13/cobra"
+)
+
+// advanceCmd represents the advance command
+var advanceCmd = &cobra.Command{
+	Use:   "advance",
+	Short: "Run the next step of a test session",
+	Long: `Run the next step of a test session.
+
+The active scenario of the session is completed and the next pending one, in
+the order of the session, is activated. Its condition values are printed so
+the chamber can be set up for it.`,
+}
+
+var advanceTestSessionCmd = &cobra.Command{
+	Use:   "test-session",
+	Short: "Complete the active scenario and activate the next one",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			return err
+		}
+		reason, err := cmd.Flags().GetString("reason")
+		if err != nil {
+			return err
+		}
+		reqBytes, err := json.Marshal(types.ScenarioTransitionRequest{Reason: reason})
+		if err != nil {
+			return err
+		}
+		endpoint, err := config.SubresourceEndpoint("test-sessions", "advance", "create", "id", strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("failed to advance test session: %s", string(resp.Body))
+		}
+
+		var advanced types.TestSessionAdvanceResponse
+		if err := json.Unmarshal(resp.Body, &advanced); err != nil {
+			return err
+		}
+		if advanced.Completed != nil {
+			fmt.Printf("Completed scenario %d\n", *advanced.Completed)
+		}
+		if advanced.Active == nil {
+			fmt.Printf("Test session %d has no pending scenarios left\n", id)
+			return nil
+		}
+
+		fmt.Printf("Activated scenario %d (%s), %d scenario(s) left\n", advanced.Active.ID, advanced.Active.Name, advanced.Remaining)
+		names := make([]string, 0, len(advanced.Active.Conditions))
+		for name := range advanced.Active.Conditions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %s = %s\n", name, advanced.Active.Conditions[name])
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(advanceCmd)
+
+	advanceTestSessionCmd.Flags().IntP("id", "i", 0, "The ID of the test session to advance")
+	advanceTestSessionCmd.Flags().StringP("reason", "r", "", "The reason recorded in the scenario history")
+	advanceCmd.AddCommand(advanceTestSessionCmd)
+}
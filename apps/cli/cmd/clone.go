@@ -0,0 +1,121 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"cli/pkg/util"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"types"
+
+	"github.com/spf13/cobra"
+)
+
+// cloneCmd represents the clone command
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Copy resources",
+	Long: `Copy resources.
+
+A cloned test session brings along all of its scenarios and their condition
+values, so a campaign can be repeated on another device. Copies always start
+out inactive.`,
+}
+
+var cloneTestSessionCmd = &cobra.Command{
+	Use:   "test-session",
+	Short: "Copy a test session with its scenarios",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			return err
+		}
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			return err
+		}
+		deviceID, err := cmd.Flags().GetInt("device-id")
+		if err != nil {
+			return err
+		}
+		reqBytes, err := json.Marshal(types.CloneTestSessionRequest{Name: name, DeviceID: uint(deviceID)})
+		if err != nil {
+			return err
+		}
+		endpoint, err := config.SubresourceEndpoint("test-sessions", "clone", "create", "id", strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("failed to clone test session: %s", string(resp.Body))
+		}
+
+		var cloned types.TestSessionCloneResponse
+		if err := json.Unmarshal(resp.Body, &cloned); err != nil {
+			return err
+		}
+		fmt.Printf("Successfully cloned test session %d as test session %d with %d scenario(s)\n", id, cloned.TestSessionID, len(cloned.Scenarios))
+
+		return nil
+	},
+}
+
+var cloneScenarioCmd = &cobra.Command{
+	Use:   "scenario",
+	Short: "Copy a scenario with its condition values",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			return err
+		}
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			return err
+		}
+		testSessionID, err := cmd.Flags().GetInt("test-session-id")
+		if err != nil {
+			return err
+		}
+		reqBytes, err := json.Marshal(types.CloneScenarioRequest{Name: name, TestSessionID: uint(testSessionID)})
+		if err != nil {
+			return err
+		}
+		endpoint, err := config.SubresourceEndpoint("scenarios", "clone", "create", "id", strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("failed to clone scenario: %s", string(resp.Body))
+		}
+
+		fmt.Println(string(resp.Body))
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneTestSessionCmd.Flags().IntP("id", "i", 0, "The ID of the test session to clone")
+	cloneTestSessionCmd.Flags().StringP("name", "n", "", "The name of the copy")
+	cloneTestSessionCmd.Flags().IntP("device-id", "d", 0, "The device to run the copy on (defaults to the device of the source)")
+	cloneCmd.AddCommand(cloneTestSessionCmd)
+
+	cloneScenarioCmd.Flags().IntP("id", "i", 0, "The ID of the scenario to clone")
+	cloneScenarioCmd.Flags().StringP("name", "n", "", "The name of the copy")
+	cloneScenarioCmd.Flags().IntP("test-session-id", "t", 0, "The test session to add the copy to (defaults to the test session of the source)")
+	cloneCmd.AddCommand(cloneScenarioCmd)
+}
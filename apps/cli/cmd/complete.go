@@ -33,7 +33,11 @@ var completeScenarioCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/scenario/complete/"+strconv.Itoa(scenarioId), nil)
+		endpoint, err := config.SubresourceEndpoint("scenarios", "complete", "create", "id", strconv.Itoa(scenarioId))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			return err
 		}
@@ -39,12 +39,18 @@ var createDeviceCmd = &cobra.Command{
 		}
 		var req types.CreateDeviceRequest
 		req.Name = deviceName
+		req.Metadata = metadataFlags(cmd)
 		reqBytes, marshalErr := json.Marshal(req)
 		if marshalErr != nil {
 			fmt.Println("Error marshaling request: ", marshalErr)
 			return
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/device", reqBytes)
+		endpoint, err := config.ResourceEndpoint("devices", "create")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -64,6 +70,39 @@ var createDeviceCmd = &cobra.Command{
 	},
 }
 
+var createNamespaceCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "Create a new namespace",
+	Run: func(cmd *cobra.Command, args []string) {
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		reqBytes, marshalErr := json.Marshal(types.CreateNamespaceRequest{Name: name})
+		if marshalErr != nil {
+			fmt.Println("Error marshaling request: ", marshalErr)
+			return
+		}
+		endpoint, err := config.ResourceEndpoint("namespaces", "create")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+			return
+		}
+
+		fmt.Println(string(resp.Body))
+	},
+}
+
 var createTestSessionCmd = &cobra.Command{
 	Use:   "test-session",
 	Short: "Create a new test session",
@@ -80,13 +119,19 @@ var createTestSessionCmd = &cobra.Command{
 		}
 		var req types.CreateTestSessionRequest
 		req.Name = name
+		req.Metadata = metadataFlags(cmd)
 		req.DeviceID = uint(deviceID)
 		reqBytes, marshalErr := json.Marshal(req)
 		if marshalErr != nil {
 			fmt.Println("Error marshaling request: ", marshalErr)
 			return
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/test-session", reqBytes)
+		endpoint, err := config.ResourceEndpoint("test-sessions", "create")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -117,12 +162,29 @@ var createConditionCmd = &cobra.Command{
 		}
 		var req types.CreateConditionRequest
 		req.Name = name
+		req.Metadata = metadataFlags(cmd)
+		req.ValueType, _ = cmd.Flags().GetString("type")
+		req.Unit, _ = cmd.Flags().GetString("unit")
+		req.Options, _ = cmd.Flags().GetStringSlice("options")
+		if cmd.Flags().Changed("min") {
+			min, _ := cmd.Flags().GetFloat64("min")
+			req.Min = &min
+		}
+		if cmd.Flags().Changed("max") {
+			max, _ := cmd.Flags().GetFloat64("max")
+			req.Max = &max
+		}
 		reqBytes, marshalErr := json.Marshal(req)
 		if marshalErr != nil {
 			fmt.Println("Error marshaling request: ", marshalErr)
 			return
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/condition", reqBytes)
+		endpoint, err := config.ResourceEndpoint("conditions", "create")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -164,7 +226,12 @@ var createConditionValueCmd = &cobra.Command{
 			fmt.Println("Error marshaling request: ", marshalErr)
 			return
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/condition-value", reqBytes)
+		endpoint, err := config.ResourceEndpoint("condition-values", "create")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -206,33 +273,41 @@ var createScenarioCmd = &cobra.Command{
 
 		var reqBytes []byte
 		var marshalErr error
-		var endpoint string
+		var endpoint *config.Endpoint
 
 		if len(conditionValueIDs) > 0 {
 			// Use scenario with condition values endpoint
 			var req types.CreateScenarioWithConditionValuesRequest
 			req.Name = name
+			req.Metadata = metadataFlags(cmd)
 			req.TestSessionID = uint(testSessionID)
+			req.Target = targetFlags(cmd)
 			req.ConditionValueIDs = make([]uint, len(conditionValueIDs))
 			for i, id := range conditionValueIDs {
 				req.ConditionValueIDs[i] = uint(id)
 			}
 			reqBytes, marshalErr = json.Marshal(req)
-			endpoint = config.GetAPIEndpoint() + "/scenario/with-condition-values"
+			endpoint, err = config.SubresourceEndpoint("scenarios", "with-condition-values", "create")
 		} else {
 			// Use basic scenario endpoint
 			var req types.CreateScenarioRequest
 			req.Name = name
+			req.Metadata = metadataFlags(cmd)
 			req.TestSessionID = uint(testSessionID)
+			req.Target = targetFlags(cmd)
 			reqBytes, marshalErr = json.Marshal(req)
-			endpoint = config.GetAPIEndpoint() + "/scenario"
+			endpoint, err = config.ResourceEndpoint("scenarios", "create")
 		}
 
 		if marshalErr != nil {
 			fmt.Println("Error marshaling request: ", marshalErr)
 			return
 		}
-		resp, err := util.SendRequest("POST", endpoint, reqBytes)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -252,17 +327,61 @@ var createScenarioCmd = &cobra.Command{
 	},
 }
 
+// addMetadataFlags registers the description, label and annotation flags of
+// the resources that carry metadata.
+func addMetadataFlags(cmd *cobra.Command) {
+	cmd.Flags().String("description", "", "A description")
+	cmd.Flags().StringToStringP("label", "l", map[string]string{}, "Labels as key=value pairs (repeatable or comma-separated)")
+	cmd.Flags().StringToString("annotation", map[string]string{}, "Annotations as key=value pairs (repeatable or comma-separated)")
+}
+
+// metadataFlags reads the flags registered by addMetadataFlags.
+func metadataFlags(cmd *cobra.Command) types.Metadata {
+	var metadata types.Metadata
+	metadata.Description, _ = cmd.Flags().GetString("description")
+	metadata.Labels, _ = cmd.Flags().GetStringToString("label")
+	metadata.Annotations, _ = cmd.Flags().GetStringToString("annotation")
+	return metadata
+}
+
+// targetFlags reads the acquisition target flags of create scenario.
+func targetFlags(cmd *cobra.Command) types.ScenarioTarget {
+	var target types.ScenarioTarget
+	if duration, _ := cmd.Flags().GetDuration("target-duration"); duration > 0 {
+		seconds := int64(duration.Seconds())
+		target.DurationSeconds = &seconds
+	}
+	if frames, _ := cmd.Flags().GetInt64("target-frames"); frames > 0 {
+		target.Frames = &frames
+	}
+	if samples, _ := cmd.Flags().GetInt64("target-samples"); samples > 0 {
+		target.Samples = &samples
+	}
+	target.Action, _ = cmd.Flags().GetString("target-action")
+	return target
+}
+
 func init() {
 	rootCmd.AddCommand(createCmd)
 
 	createDeviceCmd.Flags().StringP("name", "n", "", "The name of the device")
+	addMetadataFlags(createDeviceCmd)
+	createCmd.AddCommand(createNamespaceCmd)
+	createNamespaceCmd.Flags().StringP("name", "n", "", "The name of the namespace")
 	createCmd.AddCommand(createDeviceCmd)
 
 	createTestSessionCmd.Flags().StringP("name", "n", "", "The name of the test session")
 	createTestSessionCmd.Flags().IntP("device-id", "d", 0, "The device ID")
+	addMetadataFlags(createTestSessionCmd)
 	createCmd.AddCommand(createTestSessionCmd)
 
 	createConditionCmd.Flags().StringP("name", "n", "", "The name of the condition")
+	createConditionCmd.Flags().String("type", "", "The value type: text (default), numeric, enum, boolean or range")
+	createConditionCmd.Flags().String("unit", "", "The unit of numeric and range values, e.g. °C, V or %RH")
+	createConditionCmd.Flags().StringSlice("options", []string{}, "The allowed values of an enum condition (comma-separated)")
+	createConditionCmd.Flags().Float64("min", 0, "The smallest allowed numeric value")
+	createConditionCmd.Flags().Float64("max", 0, "The largest allowed numeric value")
+	addMetadataFlags(createConditionCmd)
 	createCmd.AddCommand(createConditionCmd)
 
 	createConditionValueCmd.Flags().StringP("value", "v", "", "The condition value")
@@ -272,5 +391,10 @@ func init() {
 	createScenarioCmd.Flags().StringP("name", "n", "", "The name of the scenario")
 	createScenarioCmd.Flags().IntP("test-session-id", "t", 0, "The test session ID")
 	createScenarioCmd.Flags().IntSliceP("condition-value-ids", "c", []int{}, "The condition value IDs (comma-separated, optional)")
+	createScenarioCmd.Flags().Duration("target-duration", 0, "Complete the scenario after it has been active this long, e.g. 10m")
+	createScenarioCmd.Flags().Int64("target-frames", 0, "Complete the scenario once it has recorded this many frames")
+	createScenarioCmd.Flags().Int64("target-samples", 0, "Complete the scenario once every channel has recorded this many samples")
+	createScenarioCmd.Flags().String("target-action", "", "What to do when the target is reached: complete (default) or flag as overdue")
+	addMetadataFlags(createScenarioCmd)
 	createCmd.AddCommand(createScenarioCmd)
 }
@@ -0,0 +1,26 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// currentNamespaceCmd represents the current-namespace command
+var currentNamespaceCmd = &cobra.Command{
+	Use:   "current-namespace",
+	Short: "Display the current namespace",
+	Long: `Display the namespace used for devices, conditions, test sessions
+and scenarios. The default namespace is used until another one is selected.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("Current namespace: %s\n", config.GetNamespace())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(currentNamespaceCmd)
+}
@@ -4,12 +4,16 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 
 	"cli/pkg/config"
 	"cli/pkg/util"
 
+	"types"
+
 	"github.com/spf13/cobra"
 )
 
@@ -32,7 +36,12 @@ var deleteDeviceCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("DELETE", config.GetAPIEndpoint()+"/device/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("devices", "delete", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -46,16 +55,77 @@ var deleteDeviceCmd = &cobra.Command{
 	},
 }
 
+var deleteNamespaceCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "Delete an empty namespace",
+	Run: func(cmd *cobra.Command, args []string) {
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		endpoint, err := config.ResourceEndpoint("namespaces", "delete", "ns", name)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+			return
+		}
+
+		fmt.Printf("Successfully deleted namespace %s\n", name)
+	},
+}
+
 var deleteTestSessionCmd = &cobra.Command{
 	Use:   "test-session",
 	Short: "Delete a test session",
+	Long: `Delete a test session.
+
+A test session that still has scenarios can only be deleted with --cascade,
+which also deletes its scenarios and their scenario conditions. Use --dry-run
+to list what would be deleted without deleting anything.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("DELETE", config.GetAPIEndpoint()+"/test-session/"+strconv.Itoa(id), nil)
+		cascade, err := cmd.Flags().GetBool("cascade")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+
+		query := url.Values{}
+		if cascade {
+			query.Set("cascade", "true")
+		}
+		if dryRun {
+			query.Set("dryRun", "true")
+		}
+		endpoint, err := config.ResourceEndpoint("test-sessions", "delete", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		target := endpoint.URL
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+
+		resp, err := util.SendRequest(endpoint.Method, target, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -65,7 +135,24 @@ var deleteTestSessionCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Printf("Successfully deleted test session with ID %d\n", id)
+		if !cascade && !dryRun {
+			fmt.Printf("Successfully deleted test session with ID %d\n", id)
+			return
+		}
+
+		var deleted types.TestSessionDeleteResponse
+		if err := json.Unmarshal(resp.Body, &deleted); err != nil {
+			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
+			return
+		}
+		verb := "Deleted"
+		if deleted.DryRun {
+			verb = "Would delete"
+		}
+		fmt.Printf("%s test session %d with %d scenario(s) and %d scenario condition(s)\n", verb, id, len(deleted.Scenarios), len(deleted.ScenarioConditions))
+		if len(deleted.Scenarios) > 0 {
+			fmt.Println("Scenarios: ", deleted.Scenarios)
+		}
 	},
 }
 
@@ -78,7 +165,12 @@ var deleteConditionCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("DELETE", config.GetAPIEndpoint()+"/condition/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("conditions", "delete", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -101,7 +193,12 @@ var deleteConditionValueCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("DELETE", config.GetAPIEndpoint()+"/condition-value/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("condition-values", "delete", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -124,7 +221,12 @@ var deleteScenarioCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("DELETE", config.GetAPIEndpoint()+"/scenario/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("scenarios", "delete", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -147,7 +249,12 @@ var deleteScenarioConditionCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("DELETE", config.GetAPIEndpoint()+"/scenario-condition/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("scenario-conditions", "delete", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -167,7 +274,12 @@ func init() {
 	deleteDeviceCmd.Flags().IntP("id", "i", 0, "The ID of the device to delete")
 	deleteCmd.AddCommand(deleteDeviceCmd)
 
+	deleteNamespaceCmd.Flags().StringP("name", "n", "", "The name of the namespace to delete")
+	deleteCmd.AddCommand(deleteNamespaceCmd)
+
 	deleteTestSessionCmd.Flags().IntP("id", "i", 0, "The ID of the test session to delete")
+	deleteTestSessionCmd.Flags().Bool("cascade", false, "Also delete the scenarios and scenario conditions of the test session")
+	deleteTestSessionCmd.Flags().Bool("dry-run", false, "Only show what would be deleted")
 	deleteCmd.AddCommand(deleteTestSessionCmd)
 
 	deleteConditionCmd.Flags().IntP("id", "i", 0, "The ID of the condition to delete")
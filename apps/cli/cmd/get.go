@@ -5,14 +5,19 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"cli/pkg/config"
 	"cli/pkg/util"
 
 	"database"
+	"types"
 
 	"github.com/spf13/cobra"
 )
@@ -29,14 +34,27 @@ condition values, scenarios, and scenario conditions.`,
 
 var getDeviceCmd = &cobra.Command{
 	Use:   "device",
-	Short: "Get a device by ID",
+	Short: "Get a device by ID or name",
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/device/"+strconv.Itoa(id), nil)
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		endpoint, err := config.ResourceEndpoint("devices", "get", "id", strconv.Itoa(id))
+		if name != "" {
+			endpoint, err = config.ResourceEndpoint("devices", "getByName", "name", name)
+		}
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -60,7 +78,12 @@ var getDevicesCmd = &cobra.Command{
 	Use:   "devices",
 	Short: "Get all devices",
 	Run: func(cmd *cobra.Command, args []string) {
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/device/", nil)
+		endpoint, err := config.ResourceEndpoint("devices", "list")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL+listQuery(cmd), nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -69,14 +92,52 @@ var getDevicesCmd = &cobra.Command{
 			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
 			return
 		}
-		var devices []database.Device
+		var devices types.List[database.Device]
 		err = json.Unmarshal(resp.Body, &devices)
 		if err != nil {
 			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
 			return
 		}
 
-		fmt.Println("Response: ", devices)
+		fmt.Println("Response: ", devices.Items)
+		printContinue(devices.Metadata)
+	},
+}
+
+var getNamespacesCmd = &cobra.Command{
+	Use:   "namespaces",
+	Short: "Get all namespaces",
+	Run: func(cmd *cobra.Command, args []string) {
+		endpoint, err := config.ResourceEndpoint("namespaces", "list")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL+listQuery(cmd), nil)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+			return
+		}
+		var namespaces types.List[database.Namespace]
+		err = json.Unmarshal(resp.Body, &namespaces)
+		if err != nil {
+			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
+			return
+		}
+
+		current := config.GetNamespace()
+		for _, namespace := range namespaces.Items {
+			marker := " "
+			if namespace.Name == current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, namespace.Name)
+		}
+		printContinue(namespaces.Metadata)
 	},
 }
 
@@ -89,7 +150,12 @@ var getTestSessionCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/test-session/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("test-sessions", "get", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -118,7 +184,12 @@ var getTestSessionsCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/test-session/list/"+strconv.Itoa(deviceID), nil)
+		endpoint, err := config.SubresourceEndpoint("devices", "test-sessions", "list", "deviceID", strconv.Itoa(deviceID))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL+listQuery(cmd), nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -127,14 +198,15 @@ var getTestSessionsCmd = &cobra.Command{
 			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
 			return
 		}
-		var testSessions []database.TestSession
+		var testSessions types.List[database.TestSession]
 		err = json.Unmarshal(resp.Body, &testSessions)
 		if err != nil {
 			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
 			return
 		}
 
-		fmt.Println("Response: ", testSessions)
+		fmt.Println("Response: ", testSessions.Items)
+		printContinue(testSessions.Metadata)
 	},
 }
 
@@ -147,7 +219,12 @@ var getConditionCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/condition/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("conditions", "get", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -171,7 +248,12 @@ var getConditionsCmd = &cobra.Command{
 	Use:   "conditions",
 	Short: "Get all conditions",
 	Run: func(cmd *cobra.Command, args []string) {
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/condition/", nil)
+		endpoint, err := config.ResourceEndpoint("conditions", "list")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL+listQuery(cmd), nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -180,14 +262,15 @@ var getConditionsCmd = &cobra.Command{
 			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
 			return
 		}
-		var conditions []database.Condition
+		var conditions types.List[database.Condition]
 		err = json.Unmarshal(resp.Body, &conditions)
 		if err != nil {
 			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
 			return
 		}
 
-		fmt.Println("Response: ", conditions)
+		fmt.Println("Response: ", conditions.Items)
+		printContinue(conditions.Metadata)
 	},
 }
 
@@ -200,7 +283,12 @@ var getConditionValueCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/condition-value/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("condition-values", "get", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -225,15 +313,19 @@ var getConditionValuesCmd = &cobra.Command{
 	Short: "Get all condition values or filter by condition ID",
 	Run: func(cmd *cobra.Command, args []string) {
 		conditionID, err := cmd.Flags().GetInt("condition-id")
-		var endpoint string
+		var endpoint *config.Endpoint
 		if err == nil && conditionID > 0 {
 			// Filter by condition ID
-			endpoint = config.GetAPIEndpoint() + "/condition-value/list/" + strconv.Itoa(conditionID)
+			endpoint, err = config.SubresourceEndpoint("conditions", "values", "list", "conditionID", strconv.Itoa(conditionID))
 		} else {
 			// Get all
-			endpoint = config.GetAPIEndpoint() + "/condition-value/"
+			endpoint, err = config.ResourceEndpoint("condition-values", "list")
 		}
-		resp, err := util.SendRequest("GET", endpoint, nil)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL+listQuery(cmd), nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -242,27 +334,50 @@ var getConditionValuesCmd = &cobra.Command{
 			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
 			return
 		}
-		var conditionValues []database.ConditionValue
+		var conditionValues types.List[database.ConditionValue]
 		err = json.Unmarshal(resp.Body, &conditionValues)
 		if err != nil {
 			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
 			return
 		}
 
-		fmt.Println("Response: ", conditionValues)
+		fmt.Println("Response: ", conditionValues.Items)
+		printContinue(conditionValues.Metadata)
 	},
 }
 
 var getScenarioCmd = &cobra.Command{
 	Use:   "scenario",
-	Short: "Get a scenario by ID (includes related data)",
+	Short: "Get a scenario by ID, or by name within a test session (includes related data)",
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/scenario/"+strconv.Itoa(id), nil)
+		name, err := cmd.Flags().GetString("name")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		testSessionID, err := cmd.Flags().GetInt("test-session-id")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		endpoint, err := config.ResourceEndpoint("scenarios", "get", "id", strconv.Itoa(id))
+		if name != "" {
+			if testSessionID == 0 {
+				fmt.Println("Error: --test-session-id is required when looking up a scenario by name")
+				return
+			}
+			endpoint, err = config.SubresourceEndpoint("test-sessions", "scenarios", "getByName", "id", strconv.Itoa(testSessionID), "name", name)
+		}
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -291,7 +406,12 @@ var getScenariosCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/scenario/list/"+strconv.Itoa(testSessionID), nil)
+		endpoint, err := config.SubresourceEndpoint("test-sessions", "scenarios", "list", "testSessionID", strconv.Itoa(testSessionID))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL+listQuery(cmd), nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -300,14 +420,160 @@ var getScenariosCmd = &cobra.Command{
 			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
 			return
 		}
-		var scenarios []database.Scenario
+		var scenarios types.List[database.Scenario]
 		err = json.Unmarshal(resp.Body, &scenarios)
 		if err != nil {
 			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
 			return
 		}
 
-		fmt.Println("Response: ", scenarios)
+		fmt.Println("Response: ", scenarios.Items)
+		printContinue(scenarios.Metadata)
+	},
+}
+
+var getScenarioHistoryCmd = &cobra.Command{
+	Use:   "scenario-history",
+	Short: "Get the status transitions of a scenario",
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		endpoint, err := config.SubresourceEndpoint("scenarios", "history", "list", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+			return
+		}
+		var history []database.ScenarioTransition
+		err = json.Unmarshal(resp.Body, &history)
+		if err != nil {
+			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
+			return
+		}
+
+		for _, transition := range history {
+			fmt.Printf("%s  %s -> %s  %s\n", transition.OccurredAt.Format(time.RFC3339), transition.FromStatus, transition.ToStatus, transition.Reason)
+		}
+	},
+}
+
+var getScenarioStatsCmd = &cobra.Command{
+	Use:   "scenario-stats",
+	Short: "Summarize the data recorded for a scenario",
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		endpoint, err := config.SubresourceEndpoint("scenarios", "stats", "get", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+			return
+		}
+		var stats types.ScenarioStats
+		err = json.Unmarshal(resp.Body, &stats)
+		if err != nil {
+			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
+			return
+		}
+
+		if stats.Source == "" {
+			fmt.Printf("No data recorded for scenario %d\n", id)
+			return
+		}
+		fmt.Printf("Source:  %s\n", stats.Source)
+		fmt.Printf("Frames:  %d\n", stats.Frames)
+		fmt.Printf("First:   %s\n", stats.FirstTimestamp.Format(time.RFC3339Nano))
+		fmt.Printf("Last:    %s\n", stats.LastTimestamp.Format(time.RFC3339Nano))
+		if stats.SampleRate != nil {
+			fmt.Printf("Rate:    %.2f Hz\n", *stats.SampleRate)
+		}
+		for _, channel := range stats.Channels {
+			stddev := "-"
+			if channel.StdDev != nil {
+				stddev = strconv.FormatFloat(*channel.StdDev, 'g', 6, 64)
+			}
+			fmt.Printf("%s  count=%d min=%g max=%g mean=%g stddev=%s\n", channel.Name, channel.Count, channel.Min, channel.Max, channel.Mean, stddev)
+		}
+	},
+}
+
+var getRuntimeCmd = &cobra.Command{
+	Use:   "runtime",
+	Short: "Get the runtime configuration of a test session or device",
+	Long: `Get the runtime configuration of a test session or device as JSON.
+
+With --test-session-id the full test session runtime state is returned.
+Otherwise the simplified configuration of --device-id (or the current
+device) is returned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		testSessionID, err := cmd.Flags().GetInt("test-session-id")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		deviceID, err := cmd.Flags().GetInt("device-id")
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+
+		var endpoint *config.Endpoint
+		if testSessionID > 0 {
+			endpoint, err = config.SubresourceEndpoint("test-sessions", "runtime", "get", "id", strconv.Itoa(testSessionID))
+		} else {
+			if deviceID == 0 {
+				device, err := config.GetCurrentDeviceInfo()
+				if err != nil {
+					fmt.Println("Error: ", err)
+					return
+				}
+				deviceID = int(device.DeviceID)
+			}
+			endpoint, err = config.SubresourceEndpoint("devices", "runtime", "get", "id", strconv.Itoa(deviceID))
+		}
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		if resp.StatusCode >= 400 {
+			fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+			return
+		}
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, resp.Body, "", "  "); err != nil {
+			fmt.Printf("Error formatting response: %v\nResponse body: %s\n", err, string(resp.Body))
+			return
+		}
+		fmt.Println(out.String())
 	},
 }
 
@@ -320,7 +586,12 @@ var getScenarioConditionCmd = &cobra.Command{
 			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/scenario-condition/"+strconv.Itoa(id), nil)
+		endpoint, err := config.ResourceEndpoint("scenario-conditions", "get", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -340,37 +611,118 @@ var getScenarioConditionCmd = &cobra.Command{
 	},
 }
 
+// addListFlags registers the pagination, filtering and sorting flags shared
+// by the list commands.
+func addListFlags(cmd *cobra.Command, status bool) {
+	cmd.Flags().Int("limit", 0, "Maximum number of items to return")
+	cmd.Flags().String("continue", "", "Continue token returned by the previous page")
+	cmd.Flags().String("name", "", "Only return items whose name contains this text")
+	cmd.Flags().String("sort", "", "Field to sort by, prefixed with - for descending order")
+	cmd.Flags().String("created-after", "", "Only return items created after this RFC 3339 timestamp")
+	cmd.Flags().Bool("include-deleted", false, "Also return deleted items")
+	if status {
+		cmd.Flags().StringSlice("status", nil, "Only return items with one of these statuses")
+	}
+}
+
+// listQuery builds the query string of a list request from the flags
+// registered by addListFlags.
+func listQuery(cmd *cobra.Command) string {
+	query := url.Values{}
+	if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	for flag, param := range map[string]string{
+		"continue":      "continue",
+		"name":          "name",
+		"sort":          "sort",
+		"created-after": "created_after",
+	} {
+		if value, _ := cmd.Flags().GetString(flag); value != "" {
+			query.Set(param, value)
+		}
+	}
+	if includeDeleted, _ := cmd.Flags().GetBool("include-deleted"); includeDeleted {
+		query.Set("includeDeleted", "true")
+	}
+	if cmd.Flags().Lookup("selector") != nil {
+		if selector, _ := cmd.Flags().GetString("selector"); selector != "" {
+			query.Set("labelSelector", selector)
+		}
+	}
+	if cmd.Flags().Lookup("status") != nil {
+		if statuses, _ := cmd.Flags().GetStringSlice("status"); len(statuses) > 0 {
+			query.Set("status", strings.Join(statuses, ","))
+		}
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+func printContinue(meta types.ListMeta) {
+	if meta.Continue != "" {
+		fmt.Printf("More results available, use --continue %s\n", meta.Continue)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(getCmd)
 
 	getDeviceCmd.Flags().IntP("id", "i", 0, "The ID of the device")
+	getDeviceCmd.Flags().StringP("name", "n", "", "The name of the device (instead of --id)")
 	getCmd.AddCommand(getDeviceCmd)
 
+	addListFlags(getDevicesCmd, false)
+	getDevicesCmd.Flags().StringP("selector", "l", "", "Only return items whose labels match this selector, e.g. campaign=Q3,firmware!=1.3")
 	getCmd.AddCommand(getDevicesCmd)
 
+	addListFlags(getNamespacesCmd, false)
+	getCmd.AddCommand(getNamespacesCmd)
+
 	getTestSessionCmd.Flags().IntP("id", "i", 0, "The ID of the test session")
 	getCmd.AddCommand(getTestSessionCmd)
 
 	getTestSessionsCmd.Flags().IntP("device-id", "d", 0, "The device ID to filter by")
+	addListFlags(getTestSessionsCmd, false)
+	getTestSessionsCmd.Flags().StringP("selector", "l", "", "Only return items whose labels match this selector, e.g. campaign=Q3,firmware!=1.3")
 	getCmd.AddCommand(getTestSessionsCmd)
 
 	getConditionCmd.Flags().IntP("id", "i", 0, "The ID of the condition")
 	getCmd.AddCommand(getConditionCmd)
 
+	addListFlags(getConditionsCmd, false)
+	getConditionsCmd.Flags().StringP("selector", "l", "", "Only return items whose labels match this selector, e.g. campaign=Q3,firmware!=1.3")
 	getCmd.AddCommand(getConditionsCmd)
 
 	getConditionValueCmd.Flags().IntP("id", "i", 0, "The ID of the condition value")
 	getCmd.AddCommand(getConditionValueCmd)
 
 	getConditionValuesCmd.Flags().IntP("condition-id", "c", 0, "The condition ID to filter by (optional)")
+	addListFlags(getConditionValuesCmd, false)
 	getCmd.AddCommand(getConditionValuesCmd)
 
 	getScenarioCmd.Flags().IntP("id", "i", 0, "The ID of the scenario")
+	getScenarioCmd.Flags().StringP("name", "n", "", "The name of the scenario (instead of --id, requires --test-session-id)")
+	getScenarioCmd.Flags().IntP("test-session-id", "t", 0, "The test session the named scenario belongs to")
 	getCmd.AddCommand(getScenarioCmd)
 
 	getScenariosCmd.Flags().IntP("test-session-id", "t", 0, "The test session ID to filter by")
+	addListFlags(getScenariosCmd, true)
+	getScenariosCmd.Flags().StringP("selector", "l", "", "Only return items whose labels match this selector, e.g. campaign=Q3,firmware!=1.3")
 	getCmd.AddCommand(getScenariosCmd)
 
+	getScenarioHistoryCmd.Flags().IntP("id", "i", 0, "The ID of the scenario")
+	getCmd.AddCommand(getScenarioHistoryCmd)
+
+	getScenarioStatsCmd.Flags().IntP("id", "i", 0, "The ID of the scenario")
+	getCmd.AddCommand(getScenarioStatsCmd)
+
+	getRuntimeCmd.Flags().IntP("test-session-id", "t", 0, "The test session to get the runtime state of")
+	getRuntimeCmd.Flags().IntP("device-id", "d", 0, "The device to get the runtime configuration of (defaults to the current device)")
+	getCmd.AddCommand(getRuntimeCmd)
+
 	getScenarioConditionCmd.Flags().IntP("id", "i", 0, "The ID of the scenario condition")
 	getCmd.AddCommand(getScenarioConditionCmd)
 }
@@ -8,6 +8,9 @@ import (
 	"cli/pkg/util"
 	"encoding/json"
 	"fmt"
+	"net/url"
+
+	"types"
 
 	"github.com/spf13/cobra"
 )
@@ -34,20 +37,50 @@ to interact with registered devices.`,
 			return
 		}
 
-		// Fetch devices from API
-		apiEndpoint := config.GetAPIEndpoint()
-		apiURL := apiEndpoint + "/device"
-		resp, err := util.SendRequest("GET", apiURL, nil)
+		// Fetch and cache API resources; every request below is built from them
+		fmt.Println("Fetching API resources...")
+		if err := config.FetchAndCacheDiscovery(config.GetAPIEndpoint()); err != nil {
+			fmt.Printf("Error fetching API resources: %v\n", err)
+			return
+		}
+		resources, _ := config.GetDiscoveryResources()
+		fmt.Printf("✓ Cached %d API resource type(s)\n", len(resources))
+
+		// Fetch devices from API, following continue tokens until every page is read
+		endpoint, err := config.ResourceEndpoint("devices", "list")
 		if err != nil {
 			fmt.Printf("Error fetching devices from API: %v\n", err)
 			return
 		}
-
-		// Parse JSON response
 		var devices []Device
-		if err := json.Unmarshal(resp.Body, &devices); err != nil {
-			fmt.Printf("Error parsing device response: %v\n", err)
-			return
+		continueToken := ""
+		for {
+			apiURL := endpoint.URL
+			if continueToken != "" {
+				apiURL += "?continue=" + url.QueryEscape(continueToken)
+			}
+			resp, err := util.SendRequest(endpoint.Method, apiURL, nil)
+			if err != nil {
+				fmt.Printf("Error fetching devices from API: %v\n", err)
+				return
+			}
+			if resp.StatusCode >= 400 {
+				fmt.Printf("Error fetching devices from API: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+				return
+			}
+
+			// Parse JSON response
+			var page types.List[Device]
+			if err := json.Unmarshal(resp.Body, &page); err != nil {
+				fmt.Printf("Error parsing device response: %v\n", err)
+				return
+			}
+			devices = append(devices, page.Items...)
+
+			continueToken = page.Metadata.Continue
+			if continueToken == "" {
+				break
+			}
 		}
 
 		// Convert to config format
@@ -67,18 +100,6 @@ to interact with registered devices.`,
 			return
 		}
 
-		// Fetch and cache API resources
-		fmt.Println("Fetching API resources...")
-		if err := config.FetchAndCacheDiscovery(apiEndpoint); err != nil {
-			// Don't fail the whole init if discovery fails
-			fmt.Printf("Warning: Failed to fetch API resources: %v\n", err)
-			fmt.Println("  (You can continue without discovery cache)")
-		} else {
-			// Get cached resources to show count
-			resources, _ := config.GetDiscoveryResources()
-			fmt.Printf("✓ Cached %d API resource type(s)\n", len(resources))
-		}
-
 		fmt.Printf("✓ Configuration initialized successfully\n")
 		fmt.Printf("✓ Found %d device(s)\n", len(devices))
 		fmt.Printf("✓ Config saved to: %s\n", config.ConfigFileUsed())
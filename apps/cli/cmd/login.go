@@ -0,0 +1,67 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"cli/pkg/util"
+	"encoding/json"
+	"fmt"
+
+	"types"
+
+	"github.com/spf13/cobra"
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store the API token used to authenticate",
+	Long: `Store the API token used to authenticate against the config API.
+
+The token is checked against the API before it is saved. It can also be
+provided through the NEUROLAB_TOKEN environment variable.
+
+Example:
+  cli login --token nl_...`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := cmd.Flags().GetString("token")
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return fmt.Errorf("--token is required")
+		}
+
+		previous := config.GetToken()
+		if err := config.SetToken(token); err != nil {
+			return err
+		}
+
+		resp, err := util.SendRequest("GET", config.GetAPIEndpoint()+"/whoami", nil)
+		if err == nil && resp.StatusCode >= 400 {
+			err = fmt.Errorf("HTTP %d - %s", resp.StatusCode, string(resp.Body))
+		}
+		if err != nil {
+			if restoreErr := config.SetToken(previous); restoreErr != nil {
+				return restoreErr
+			}
+			return fmt.Errorf("token rejected: %w", err)
+		}
+
+		var whoami types.APITokenResponse
+		if err := json.Unmarshal(resp.Body, &whoami); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Logged in as %s (%s)\n", whoami.Name, whoami.Role)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+
+	loginCmd.Flags().StringP("token", "t", "", "The API token")
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"cli/pkg/util"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"types"
+
+	"github.com/spf13/cobra"
+)
+
+// reorderCmd represents the reorder command
+var reorderCmd = &cobra.Command{
+	Use:   "reorder",
+	Short: "Change the order of resources",
+}
+
+var reorderTestSessionCmd = &cobra.Command{
+	Use:   "test-session",
+	Short: "Set the order in which the scenarios of a test session run",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := cmd.Flags().GetInt("id")
+		if err != nil {
+			return err
+		}
+		scenarioIDs, err := cmd.Flags().GetUintSlice("scenarios")
+		if err != nil {
+			return err
+		}
+		reqBytes, err := json.Marshal(types.ReorderScenariosRequest{ScenarioIDs: scenarioIDs})
+		if err != nil {
+			return err
+		}
+		endpoint, err := config.SubresourceEndpoint("test-sessions", "order", "update", "id", strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("failed to reorder test session: %s", string(resp.Body))
+		}
+
+		fmt.Printf("Successfully reordered the %d scenario(s) of test session %d\n", len(scenarioIDs), id)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reorderCmd)
+
+	reorderTestSessionCmd.Flags().IntP("id", "i", 0, "The ID of the test session to reorder")
+	reorderTestSessionCmd.Flags().UintSliceP("scenarios", "s", nil, "The IDs of all scenarios of the session, in the order to run them")
+	reorderTestSessionCmd.MarkFlagRequired("scenarios")
+	reorderCmd.AddCommand(reorderTestSessionCmd)
+}
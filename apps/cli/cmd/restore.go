@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"cli/pkg/config"
+	"cli/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore deleted resources",
+	Long: `Restore deleted resources.
+
+Deleted devices, test sessions, conditions, condition values, scenarios and
+scenario conditions can be listed with 'get ... --include-deleted' and
+brought back with this command.`,
+}
+
+// newRestoreCmd builds the restore subcommand for the discovered resource.
+func newRestoreCmd(use, resource, label string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: "Restore a deleted " + label,
+		Run: func(cmd *cobra.Command, args []string) {
+			id, err := cmd.Flags().GetInt("id")
+			if err != nil {
+				fmt.Println("Error: ", err)
+				return
+			}
+			endpoint, err := config.ResourceEndpoint(resource, "restore", "id", strconv.Itoa(id))
+			if err != nil {
+				fmt.Println("Error: ", err)
+				return
+			}
+			target := endpoint.URL
+			if cascade, _ := cmd.Flags().GetBool("cascade"); cascade {
+				target += "?cascade=true"
+			}
+			resp, err := util.SendRequest(endpoint.Method, target, nil)
+			if err != nil {
+				fmt.Println("Error: ", err)
+				return
+			}
+			if resp.StatusCode >= 400 {
+				fmt.Printf("Error: HTTP %d - %s\n", resp.StatusCode, string(resp.Body))
+				return
+			}
+
+			fmt.Printf("Successfully restored %s with ID %d\n", label, id)
+		},
+	}
+	cmd.Flags().IntP("id", "i", 0, "The ID of the "+label+" to restore")
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.AddCommand(newRestoreCmd("device", "devices", "device"))
+
+	restoreTestSessionCmd := newRestoreCmd("test-session", "test-sessions", "test session")
+	restoreTestSessionCmd.Flags().Bool("cascade", false, "Also restore the scenarios deleted together with the test session")
+	restoreCmd.AddCommand(restoreTestSessionCmd)
+
+	restoreCmd.AddCommand(newRestoreCmd("condition", "conditions", "condition"))
+	restoreCmd.AddCommand(newRestoreCmd("condition-value", "condition-values", "condition value"))
+	restoreCmd.AddCommand(newRestoreCmd("scenario", "scenarios", "scenario"))
+	restoreCmd.AddCommand(newRestoreCmd("scenario-condition", "scenario-conditions", "scenario condition"))
+}
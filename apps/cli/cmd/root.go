@@ -10,6 +10,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -60,4 +61,6 @@ func Execute() {
 func init() {
 	// Add the persistent --config flag to the root command
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.neurolab/config.yaml)")
+	rootCmd.PersistentFlags().String("namespace", "", "namespace to use for this command (default is the current namespace)")
+	viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
 }
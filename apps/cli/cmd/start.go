@@ -33,7 +33,19 @@ var startScenarioCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/scenario/activate/"+strconv.Itoa(scenarioId), nil)
+		preempt, err := cmd.Flags().GetBool("preempt")
+		if err != nil {
+			return err
+		}
+		endpoint, err := config.SubresourceEndpoint("scenarios", "activate", "create", "id", strconv.Itoa(scenarioId))
+		if err != nil {
+			return err
+		}
+		url := endpoint.URL
+		if preempt {
+			url += "?preempt=true"
+		}
+		resp, err := util.SendRequest(endpoint.Method, url, nil)
 		if err != nil {
 			return err
 		}
@@ -51,5 +63,6 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 
 	startScenarioCmd.Flags().IntP("id", "i", 0, "The ID of the scenario to start")
+	startScenarioCmd.Flags().Bool("preempt", false, "Deactivate the scenario currently active on the same device")
 	startCmd.AddCommand(startScenarioCmd)
 }
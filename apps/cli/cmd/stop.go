@@ -33,7 +33,11 @@ var stopScenarioCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		resp, err := util.SendRequest("POST", config.GetAPIEndpoint()+"/scenario/deactivate/"+strconv.Itoa(scenarioId), nil)
+		endpoint, err := config.SubresourceEndpoint("scenarios", "deactivate", "create", "id", strconv.Itoa(scenarioId))
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
 		if err != nil {
 			return err
 		}
@@ -12,7 +12,6 @@ import (
 	"cli/pkg/util"
 
 	"database"
-	"types"
 
 	"github.com/spf13/cobra"
 )
@@ -30,26 +29,28 @@ condition values, and scenarios.`,
 var updateDeviceCmd = &cobra.Command{
 	Use:   "device",
 	Short: "Update an existing device",
+	Long: `Update an existing device.
+
+Only the fields given as flags are changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		deviceName, err := cmd.Flags().GetString("name")
+		reqBytes, err := mergePatch(cmd, map[string]string{
+			"name": "name",
+		})
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		var req types.UpdateDeviceRequest
-		req.ID = uint(id)
-		req.Name = deviceName
-		reqBytes, marshalErr := json.Marshal(req)
-		if marshalErr != nil {
-			fmt.Println("Error marshaling request: ", marshalErr)
+		endpoint, err := config.ResourceEndpoint("devices", "patch", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("PUT", config.GetAPIEndpoint()+"/device/"+strconv.Itoa(id), reqBytes)
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -72,32 +73,29 @@ var updateDeviceCmd = &cobra.Command{
 var updateTestSessionCmd = &cobra.Command{
 	Use:   "test-session",
 	Short: "Update an existing test session",
+	Long: `Update an existing test session.
+
+Only the fields given as flags are changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		name, err := cmd.Flags().GetString("name")
+		reqBytes, err := mergePatch(cmd, map[string]string{
+			"name":      "name",
+			"device-id": "device_id",
+		})
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		deviceID, err := cmd.Flags().GetInt("device-id")
+		endpoint, err := config.ResourceEndpoint("test-sessions", "patch", "id", strconv.Itoa(id))
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		var req types.UpdateTestSessionRequest
-		req.ID = uint(id)
-		req.Name = name
-		req.DeviceID = uint(deviceID)
-		reqBytes, marshalErr := json.Marshal(req)
-		if marshalErr != nil {
-			fmt.Println("Error marshaling request: ", marshalErr)
-			return
-		}
-		resp, err := util.SendRequest("PUT", config.GetAPIEndpoint()+"/test-session/"+strconv.Itoa(id), reqBytes)
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -120,26 +118,28 @@ var updateTestSessionCmd = &cobra.Command{
 var updateConditionCmd = &cobra.Command{
 	Use:   "condition",
 	Short: "Update an existing condition",
+	Long: `Update an existing condition.
+
+Only the fields given as flags are changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		name, err := cmd.Flags().GetString("name")
+		reqBytes, err := mergePatch(cmd, map[string]string{
+			"name": "name",
+		})
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		var req types.UpdateConditionRequest
-		req.ID = uint(id)
-		req.Name = name
-		reqBytes, marshalErr := json.Marshal(req)
-		if marshalErr != nil {
-			fmt.Println("Error marshaling request: ", marshalErr)
+		endpoint, err := config.ResourceEndpoint("conditions", "patch", "id", strconv.Itoa(id))
+		if err != nil {
+			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("PUT", config.GetAPIEndpoint()+"/condition/"+strconv.Itoa(id), reqBytes)
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -162,32 +162,29 @@ var updateConditionCmd = &cobra.Command{
 var updateConditionValueCmd = &cobra.Command{
 	Use:   "condition-value",
 	Short: "Update an existing condition value",
+	Long: `Update an existing condition value.
+
+Only the fields given as flags are changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		value, err := cmd.Flags().GetString("value")
+		reqBytes, err := mergePatch(cmd, map[string]string{
+			"value":        "value",
+			"condition-id": "condition_id",
+		})
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		conditionID, err := cmd.Flags().GetInt("condition-id")
+		endpoint, err := config.ResourceEndpoint("condition-values", "patch", "id", strconv.Itoa(id))
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
-		var req types.UpdateConditionValueRequest
-		req.ID = uint(id)
-		req.Value = value
-		req.ConditionID = uint(conditionID)
-		reqBytes, marshalErr := json.Marshal(req)
-		if marshalErr != nil {
-			fmt.Println("Error marshaling request: ", marshalErr)
-			return
-		}
-		resp, err := util.SendRequest("PUT", config.GetAPIEndpoint()+"/condition-value/"+strconv.Itoa(id), reqBytes)
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -212,8 +209,9 @@ var updateScenarioCmd = &cobra.Command{
 	Short: "Update an existing scenario or scenario condition",
 	Long: `Update an existing scenario or scenario condition.
 
-If you provide scenario-id and condition-value-id, it will update a scenario condition.
-Otherwise, it will update the basic scenario properties (name and test-session-id).`,
+If you provide scenario-id or condition-value-id, it will update a scenario condition.
+Otherwise, it will update the basic scenario properties (name and test-session-id).
+Only the fields given as flags are changed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetInt("id")
 		if err != nil {
@@ -222,46 +220,34 @@ Otherwise, it will update the basic scenario properties (name and test-session-i
 		}
 
 		// Check if this is a scenario condition update
-		scenarioID, scenarioIDErr := cmd.Flags().GetInt("scenario-id")
-		conditionValueID, conditionValueIDErr := cmd.Flags().GetInt("condition-value-id")
+		isScenarioCondition := cmd.Flags().Changed("scenario-id") || cmd.Flags().Changed("condition-value-id")
 
 		var reqBytes []byte
-		var marshalErr error
-		var endpoint string
-
-		if scenarioIDErr == nil && conditionValueIDErr == nil && scenarioID > 0 && conditionValueID > 0 {
-			// Update scenario condition
-			var req types.UpdateScenarioConditionRequest
-			req.ID = uint(id)
-			req.ScenarioID = uint(scenarioID)
-			req.ConditionValueID = uint(conditionValueID)
-			reqBytes, marshalErr = json.Marshal(req)
-			endpoint = config.GetAPIEndpoint() + "/scenario-condition/" + strconv.Itoa(id)
-		} else {
-			// Update basic scenario
-			name, err := cmd.Flags().GetString("name")
-			if err != nil {
-				fmt.Println("Error: ", err)
-				return
+		var endpoint *config.Endpoint
+
+		if isScenarioCondition {
+			reqBytes, err = mergePatch(cmd, map[string]string{
+				"scenario-id":        "scenario_id",
+				"condition-value-id": "condition_value_id",
+			})
+			if err == nil {
+				endpoint, err = config.ResourceEndpoint("scenario-conditions", "patch", "id", strconv.Itoa(id))
 			}
-			testSessionID, err := cmd.Flags().GetInt("test-session-id")
-			if err != nil {
-				fmt.Println("Error: ", err)
-				return
+		} else {
+			reqBytes, err = mergePatch(cmd, map[string]string{
+				"name":            "name",
+				"test-session-id": "test_session_id",
+			})
+			if err == nil {
+				endpoint, err = config.ResourceEndpoint("scenarios", "patch", "id", strconv.Itoa(id))
 			}
-			var req types.UpdateScenarioRequest
-			req.ID = uint(id)
-			req.Name = name
-			req.TestSessionID = uint(testSessionID)
-			reqBytes, marshalErr = json.Marshal(req)
-			endpoint = config.GetAPIEndpoint() + "/scenario/" + strconv.Itoa(id)
 		}
 
-		if marshalErr != nil {
-			fmt.Println("Error marshaling request: ", marshalErr)
+		if err != nil {
+			fmt.Println("Error: ", err)
 			return
 		}
-		resp, err := util.SendRequest("PUT", endpoint, reqBytes)
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, reqBytes)
 		if err != nil {
 			fmt.Println("Error: ", err)
 			return
@@ -271,10 +257,7 @@ Otherwise, it will update the basic scenario properties (name and test-session-i
 			return
 		}
 
-		// Try to unmarshal as Scenario first, if that fails try ScenarioCondition
-		var scenarioResponse database.Scenario
-		err = json.Unmarshal(resp.Body, &scenarioResponse)
-		if err != nil {
+		if isScenarioCondition {
 			var scenarioConditionResponse database.ScenarioCondition
 			err = json.Unmarshal(resp.Body, &scenarioConditionResponse)
 			if err != nil {
@@ -285,10 +268,46 @@ Otherwise, it will update the basic scenario properties (name and test-session-i
 			return
 		}
 
+		var scenarioResponse database.Scenario
+		err = json.Unmarshal(resp.Body, &scenarioResponse)
+		if err != nil {
+			fmt.Printf("Error unmarshaling response: %v\nResponse body: %s\n", err, string(resp.Body))
+			return
+		}
+
 		fmt.Println("Response: ", scenarioResponse)
 	},
 }
 
+// mergePatch builds a JSON merge patch from the flags that were set on the
+// command line. fields maps flag names to the JSON fields they patch.
+func mergePatch(cmd *cobra.Command, fields map[string]string) ([]byte, error) {
+	patch := map[string]interface{}{}
+	for flag, field := range fields {
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+		switch cmd.Flags().Lookup(flag).Value.Type() {
+		case "int":
+			value, err := cmd.Flags().GetInt(flag)
+			if err != nil {
+				return nil, err
+			}
+			patch[field] = value
+		default:
+			value, err := cmd.Flags().GetString(flag)
+			if err != nil {
+				return nil, err
+			}
+			patch[field] = value
+		}
+	}
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("nothing to update, set at least one field flag")
+	}
+	return json.Marshal(patch)
+}
+
 func init() {
 	rootCmd.AddCommand(updateCmd)
 
@@ -0,0 +1,55 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"cli/pkg/config"
+	"cli/pkg/util"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// useNamespaceCmd represents the use-namespace command
+var useNamespaceCmd = &cobra.Command{
+	Use:   "use-namespace [namespace]",
+	Short: "Switch to a specific namespace",
+	Long: `Switch the current namespace. Devices, conditions, test sessions
+and scenarios are read from and created in this namespace.
+
+The device list belongs to a namespace, so switching clears it together
+with the current device. Run 'init' afterwards to fetch the devices of
+the new namespace.
+
+Example:
+  cli use-namespace lab-a`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace := args[0]
+
+		// Verify the namespace exists
+		endpoint, err := config.ResourceEndpoint("namespaces", "get", "ns", namespace)
+		if err != nil {
+			return err
+		}
+		resp, err := util.SendRequest(endpoint.Method, endpoint.URL, nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("namespace '%s' not found: HTTP %d - %s", namespace, resp.StatusCode, string(resp.Body))
+		}
+
+		if err := config.SetNamespace(namespace); err != nil {
+			return fmt.Errorf("failed to set current namespace: %w", err)
+		}
+
+		fmt.Printf("✓ Current namespace set to: %s\n", namespace)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(useNamespaceCmd)
+}
@@ -18,8 +18,10 @@ import (
 type Config struct {
 	Devices       []DeviceWrapper     `yaml:"devices" mapstructure:"devices"`
 	CurrentDevice string              `yaml:"current_device,omitempty" mapstructure:"current_device"`
+	Namespace     string              `yaml:"namespace,omitempty" mapstructure:"namespace"`
 	APIEndpoint   string              `yaml:"api_endpoint,omitempty" mapstructure:"api_endpoint"`
 	APIResources  []types.APIResource `yaml:"api_resources,omitempty" mapstructure:"api_resources"`
+	Token         string              `yaml:"token,omitempty" mapstructure:"token"`
 }
 
 // DeviceWrapper wraps a device for YAML structure
@@ -44,6 +46,8 @@ type DiscoveryCache struct {
 const (
 	// DefaultAPIEndpoint is the default API base URL
 	DefaultAPIEndpoint = "http://localhost:3002/api/v1"
+	// DefaultNamespace is the namespace used when none is selected
+	DefaultNamespace = "default"
 	// ConfigDirName is the config directory name in user's home
 	ConfigDirName = ".neurolab"
 	// ConfigFileName is the name of the config file
@@ -116,7 +120,9 @@ func SaveConfig(cfg *Config) error {
 	// Set all values in Viper
 	viper.Set("devices", cfg.Devices)
 	viper.Set("current_device", cfg.CurrentDevice)
+	viper.Set("namespace", cfg.Namespace)
 	viper.Set("api_endpoint", cfg.APIEndpoint)
+	viper.Set("token", cfg.Token)
 
 	// Get the config file path
 	configFile := viper.ConfigFileUsed()
@@ -230,6 +236,48 @@ func SetAPIEndpoint(endpoint string) error {
 	return SaveConfig(cfg)
 }
 
+// GetNamespace returns the current namespace
+// It can be overridden with the --namespace flag or the NEUROLAB_NAMESPACE environment variable
+func GetNamespace() string {
+	if namespace := viper.GetString("namespace"); namespace != "" {
+		return namespace
+	}
+	return DefaultNamespace
+}
+
+// SetNamespace updates the current namespace
+// The device list belongs to the previous namespace, so it is cleared together with the current device
+func SetNamespace(namespace string) error {
+	cfg, err := GetConfig()
+	if err != nil {
+		cfg = &Config{}
+	}
+
+	if cfg.Namespace != namespace {
+		cfg.Devices = nil
+		cfg.CurrentDevice = ""
+	}
+	cfg.Namespace = namespace
+	return SaveConfig(cfg)
+}
+
+// GetToken returns the API token sent with every request
+// It can be overridden with the NEUROLAB_TOKEN environment variable
+func GetToken() string {
+	return viper.GetString("token")
+}
+
+// SetToken updates the API token
+func SetToken(token string) error {
+	cfg, err := GetConfig()
+	if err != nil {
+		cfg = &Config{}
+	}
+
+	cfg.Token = token
+	return SaveConfig(cfg)
+}
+
 // ConfigFileUsed returns the config file being used
 func ConfigFileUsed() string {
 	return viper.ConfigFileUsed()
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"types"
+)
+
+var placeholder = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Endpoint is a request resolved from a discovered endpoint template
+type Endpoint struct {
+	Method string
+	URL    string
+}
+
+// ResourceEndpoint resolves the request performing verb on a resource, found by its name, singular name or short name
+// {ns} is filled with the current namespace, the other placeholders from params given as name/value pairs
+func ResourceEndpoint(resource, verb string, params ...string) (*Endpoint, error) {
+	return resolveEndpoint(resource, "", verb, params)
+}
+
+// SubresourceEndpoint resolves the request performing verb on a subresource such as scenarios/activate
+func SubresourceEndpoint(resource, subresource, verb string, params ...string) (*Endpoint, error) {
+	return resolveEndpoint(resource, subresource, verb, params)
+}
+
+func resolveEndpoint(resource, subresource, verb string, params []string) (*Endpoint, error) {
+	template, err := findEndpoint(resource, subresource, verb)
+	if err != nil {
+		// The cache may predate the server; look again with fresh discovery data
+		if fetchErr := FetchAndCacheDiscovery(GetAPIEndpoint()); fetchErr != nil {
+			return nil, err
+		}
+		if template, err = findEndpoint(resource, subresource, verb); err != nil {
+			return nil, err
+		}
+	}
+
+	values := map[string]string{"ns": GetNamespace()}
+	for i := 0; i+1 < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	var missing []string
+	path := placeholder.ReplaceAllStringFunc(template.Path, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return url.PathEscape(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("endpoint %s needs a value for %v", template.Path, missing)
+	}
+
+	return &Endpoint{Method: template.Method, URL: GetAPIEndpoint() + path}, nil
+}
+
+func findEndpoint(resource, subresource, verb string) (types.APIEndpoint, error) {
+	resources, err := GetDiscoveryResources()
+	if err != nil {
+		return types.APIEndpoint{}, err
+	}
+
+	for _, r := range resources {
+		if r.Name != resource && r.SingularName != resource && !slices.Contains(r.ShortNames, resource) {
+			continue
+		}
+		endpoints := r.Endpoints
+		if subresource != "" {
+			endpoints = nil
+			for _, sub := range r.Subresources {
+				if sub.Name == subresource {
+					endpoints = sub.Endpoints
+				}
+			}
+			if endpoints == nil {
+				return types.APIEndpoint{}, fmt.Errorf("resource %q has no subresource %q", resource, subresource)
+			}
+			resource += "/" + subresource
+		}
+		endpoint, ok := endpoints[verb]
+		if !ok {
+			return types.APIEndpoint{}, fmt.Errorf("resource %q does not support verb %q", resource, verb)
+		}
+		return endpoint, nil
+	}
+
+	return types.APIEndpoint{}, fmt.Errorf("resource %q not found, the server does not support it", resource)
+}
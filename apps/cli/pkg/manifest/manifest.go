@@ -7,14 +7,15 @@ import (
 )
 
 type Manifest struct {
-	APIVersion string      `yaml:"apiVersion"`
-	Kind       string      `yaml:"kind"`
-	Metadata   Metadata    `yaml:"metadata"`
-	Spec       interface{} `yaml:"spec"`
+	APIVersion string      `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string      `yaml:"kind" json:"kind"`
+	Metadata   Metadata    `yaml:"metadata" json:"metadata"`
+	Spec       interface{} `yaml:"spec" json:"spec"`
 }
 
 type Metadata struct {
-	Name string `yaml:"name"`
+	Name   string `yaml:"name" json:"name"`
+	Device string `yaml:"device,omitempty" json:"device,omitempty"`
 }
 
 func ParseManifest(filename string) (*Manifest, error) {
@@ -14,36 +14,63 @@ import (
 
 func ApplyResource(manifest *manifest.Manifest) error {
 
-	apiEndpoint := config.GetAPIEndpoint()
-
 	resources, err := config.GetDiscoveryResources()
 	if err != nil {
 		return err
 	}
 
 	var resourceConfig *types.APIResource
-	for _, resource := range resources {
-		if resource.Kind == manifest.Kind {
-			resourceConfig = &resource
+	for i := range resources {
+		if resources[i].Kind == manifest.Kind {
+			resourceConfig = &resources[i]
 			break
 		}
 	}
 
-	if resourceConfig.Kind == "" {
+	if resourceConfig == nil {
 		return fmt.Errorf("resource %s not found", manifest.Kind)
 	}
 
-	return sendRequest(apiEndpoint, resourceConfig.SingularName, manifest)
-}
+	endpoint, err := config.ResourceEndpoint(resourceConfig.Name, "create")
+	if err != nil {
+		return err
+	}
+
+	if manifest.Kind == "TestSessionWorkflow" {
+		return applyWorkflow(endpoint, manifest)
+	}
 
-func sendRequest(apiEndpoint string, singularName string, manifest *manifest.Manifest) error {
 	body, err := json.Marshal(manifest.Spec)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Applying ", manifest.Kind)
-	resp, err := util.SendRequest("POST", apiEndpoint+"/"+singularName, body)
+	return post(endpoint, manifest.Kind, body)
+}
+
+// applyWorkflow posts the whole workflow document, since the server resolves
+// the device and all condition references from it. The current device is
+// used when the manifest does not name one.
+func applyWorkflow(endpoint *config.Endpoint, manifest *manifest.Manifest) error {
+	if manifest.Metadata.Device == "" {
+		device, err := config.GetCurrentDevice()
+		if err != nil {
+			return err
+		}
+		manifest.Metadata.Device = device
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	return post(endpoint, manifest.Kind, body)
+}
+
+func post(endpoint *config.Endpoint, kind string, body []byte) error {
+	fmt.Println("Applying ", kind)
+	resp, err := util.SendRequest(endpoint.Method, endpoint.URL, body)
 	if err != nil {
 		return err
 	}
@@ -62,7 +89,7 @@ func sendRequest(apiEndpoint string, singularName string, manifest *manifest.Man
 	}
 
 	// Success: status code is 2xx
-	fmt.Println(manifest.Kind, " applied successfully")
+	fmt.Println(kind, " applied successfully")
 	fmt.Println("--------------------------------")
 	fmt.Println(string(resp.Body))
 	fmt.Println("--------------------------------")
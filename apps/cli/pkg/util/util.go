@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"cli/pkg/config"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -18,6 +19,9 @@ func SendRequest(method string, url string, body []byte) (*Response, error) {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if token := config.GetToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,148 @@
+// Package acquisition tracks how much data the active scenarios have recorded
+// and ends their acquisition once they reach their target.
+package acquisition
+
+import (
+	"database"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Progress is what a scenario has recorded so far.
+type Progress struct {
+	// Active is the time the scenario has spent active.
+	Active time.Duration
+	// Frames is the number of distinct frames stored for the scenario.
+	Frames int64
+	// Samples is the number of samples of the channel with the fewest.
+	Samples int64
+}
+
+// Reached returns why progress meets target, or "" when it does not.
+func Reached(target database.Target, progress Progress) string {
+	reasons := []string{}
+	if target.DurationSeconds != nil && progress.Active >= time.Duration(*target.DurationSeconds)*time.Second {
+		reasons = append(reasons, fmt.Sprintf("active for %s of %ds", progress.Active.Truncate(time.Second), *target.DurationSeconds))
+	}
+	if target.Frames != nil && progress.Frames >= *target.Frames {
+		reasons = append(reasons, fmt.Sprintf("%d of %d frames", progress.Frames, *target.Frames))
+	}
+	if target.Samples != nil && progress.Samples >= *target.Samples {
+		reasons = append(reasons, fmt.Sprintf("%d of %d samples per channel", progress.Samples, *target.Samples))
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "target reached: " + strings.Join(reasons, ", ")
+}
+
+// Source is a table holding the processed data of scenarios.
+type Source string
+
+const (
+	// ProcessedChannels holds one row per frame and channel, the samples of
+	// the frame in an array. It is written by the processor.
+	ProcessedChannels Source = "processed_channels"
+	// ProcessedSamples holds one row per sample. It is written by the
+	// transformer.
+	ProcessedSamples Source = "processed_samples"
+)
+
+// AvailableSources returns the sources that exist in the database, so that
+// progress can be measured on deployments running only some of the
+// pipelines.
+func AvailableSources(db *gorm.DB) []Source {
+	sources := []Source{}
+	for _, source := range []Source{ProcessedChannels, ProcessedSamples} {
+		if db.Migrator().HasTable(string(source)) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// Measure returns the progress of the given scenarios, keyed by scenario ID,
+// as of now. Frames and samples are counted in every source and the larger
+// count is taken; the active time is summed up from the scenario history.
+func Measure(db *gorm.DB, sources []Source, scenarioIDs []uint, now time.Time) (map[uint]Progress, error) {
+	progress := make(map[uint]Progress, len(scenarioIDs))
+	if len(scenarioIDs) == 0 {
+		return progress, nil
+	}
+
+	for _, source := range sources {
+		counts := []struct {
+			ScenarioID uint
+			Frames     int64
+			Samples    int64
+		}{}
+		if err := db.Raw(countQuery(source), scenarioIDs, scenarioIDs).Scan(&counts).Error; err != nil {
+			return nil, fmt.Errorf("counting %s: %w", source, err)
+		}
+		for _, count := range counts {
+			p := progress[count.ScenarioID]
+			p.Frames = max(p.Frames, count.Frames)
+			p.Samples = max(p.Samples, count.Samples)
+			progress[count.ScenarioID] = p
+		}
+	}
+
+	history := []database.ScenarioTransition{}
+	err := db.Where("scenario_id IN ?", scenarioIDs).Order("occurred_at ASC, id ASC").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	for scenarioID, active := range activeTime(history, now) {
+		p := progress[scenarioID]
+		p.Active = active
+		progress[scenarioID] = p
+	}
+
+	return progress, nil
+}
+
+// activeTime sums up, per scenario, the time spent active according to
+// history, which is ordered by time. A scenario still active counts until
+// now; notes recorded while active do not restart the clock.
+func activeTime(history []database.ScenarioTransition, now time.Time) map[uint]time.Duration {
+	active := map[uint]time.Duration{}
+	activeSince := map[uint]time.Time{}
+	for _, transition := range history {
+		if since, ok := activeSince[transition.ScenarioID]; ok && transition.ToStatus != database.StatusActive {
+			active[transition.ScenarioID] += transition.OccurredAt.Sub(since)
+			delete(activeSince, transition.ScenarioID)
+		}
+		if transition.ToStatus == database.StatusActive && transition.FromStatus != database.StatusActive {
+			activeSince[transition.ScenarioID] = transition.OccurredAt
+		}
+	}
+	for scenarioID, since := range activeSince {
+		active[scenarioID] += now.Sub(since)
+	}
+	return active
+}
+
+// countQuery returns the query counting the frames and the samples per
+// channel of the scenarios whose IDs are passed twice as its arguments.
+func countQuery(source Source) string {
+	samples := "COUNT(*)"
+	if source == ProcessedChannels {
+		samples = `COALESCE(SUM(cardinality("values")), 0)`
+	}
+	table := string(source)
+	return `SELECT frames.scenario_id, frames.frames, COALESCE(channels.samples, 0) AS samples
+		FROM (
+			SELECT scenario_id, COUNT(DISTINCT frame_id) AS frames FROM ` + table + `
+			WHERE scenario_id IN ? AND deleted_at IS NULL
+			GROUP BY scenario_id
+		) AS frames LEFT JOIN (
+			SELECT scenario_id, MIN(samples) AS samples FROM (
+				SELECT scenario_id, ` + samples + ` AS samples FROM ` + table + `
+				WHERE scenario_id IN ? AND deleted_at IS NULL
+				GROUP BY scenario_id, metric_name
+			) AS per_channel GROUP BY scenario_id
+		) AS channels ON channels.scenario_id = frames.scenario_id`
+}
@@ -0,0 +1,139 @@
+package acquisition
+
+import (
+	"database"
+	"testing"
+	"time"
+)
+
+func limit(n int64) *int64 {
+	return &n
+}
+
+func TestReached(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   database.Target
+		progress Progress
+		want     string
+	}{
+		{name: "no target", progress: Progress{Active: time.Hour, Frames: 10, Samples: 10}},
+		{name: "duration below", target: database.Target{DurationSeconds: limit(60)}, progress: Progress{Active: 59 * time.Second}},
+		{
+			name:     "duration met",
+			target:   database.Target{DurationSeconds: limit(60)},
+			progress: Progress{Active: 60*time.Second + 400*time.Millisecond},
+			want:     "target reached: active for 1m0s of 60s",
+		},
+		{name: "frames below", target: database.Target{Frames: limit(100)}, progress: Progress{Frames: 99}},
+		{name: "frames met", target: database.Target{Frames: limit(100)}, progress: Progress{Frames: 100}, want: "target reached: 100 of 100 frames"},
+		{
+			name:     "samples met",
+			target:   database.Target{Samples: limit(1000)},
+			progress: Progress{Samples: 1200},
+			want:     "target reached: 1200 of 1000 samples per channel",
+		},
+		{
+			name:     "one limit of several is enough",
+			target:   database.Target{DurationSeconds: limit(3600), Frames: limit(100)},
+			progress: Progress{Active: time.Minute, Frames: 150},
+			want:     "target reached: 150 of 100 frames",
+		},
+		{
+			name:     "every limit met is reported",
+			target:   database.Target{DurationSeconds: limit(60), Frames: limit(100), Samples: limit(10)},
+			progress: Progress{Active: 2 * time.Minute, Frames: 100, Samples: 10},
+			want:     "target reached: active for 2m0s of 60s, 100 of 100 frames, 10 of 10 samples per channel",
+		},
+		{name: "nothing recorded", target: database.Target{Frames: limit(1), Samples: limit(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reached(tt.target, tt.progress); got != tt.want {
+				t.Errorf("Reached() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActiveTime(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+	move := func(scenarioID uint, from, to database.Status, minutes int) database.ScenarioTransition {
+		return database.ScenarioTransition{ScenarioID: scenarioID, FromStatus: from, ToStatus: to, OccurredAt: at(minutes)}
+	}
+	const (
+		inactive  = database.StatusInactive
+		active    = database.StatusActive
+		completed = database.StatusCompleted
+		aborted   = database.StatusAborted
+	)
+
+	tests := []struct {
+		name    string
+		history []database.ScenarioTransition
+		now     int
+		want    map[uint]time.Duration
+	}{
+		{name: "no history", now: 60, want: map[uint]time.Duration{}},
+		{
+			name:    "still active counts until now",
+			history: []database.ScenarioTransition{move(1, inactive, active, 10)},
+			now:     25,
+			want:    map[uint]time.Duration{1: 15 * time.Minute},
+		},
+		{
+			name:    "completed",
+			history: []database.ScenarioTransition{move(1, inactive, active, 0), move(1, active, completed, 30)},
+			now:     90,
+			want:    map[uint]time.Duration{1: 30 * time.Minute},
+		},
+		{
+			name: "paused periods are left out",
+			history: []database.ScenarioTransition{
+				move(1, inactive, active, 0), move(1, active, inactive, 10),
+				move(1, inactive, active, 40), move(1, active, aborted, 45),
+			},
+			now:  90,
+			want: map[uint]time.Duration{1: 15 * time.Minute},
+		},
+		{
+			name: "notes do not restart the clock",
+			history: []database.ScenarioTransition{
+				move(1, inactive, active, 0), move(1, active, active, 20), move(1, active, completed, 30),
+			},
+			now:  90,
+			want: map[uint]time.Duration{1: 30 * time.Minute},
+		},
+		{
+			name: "scenarios are summed up separately",
+			history: []database.ScenarioTransition{
+				move(1, inactive, active, 0), move(2, inactive, active, 5),
+				move(1, active, completed, 20), move(2, active, inactive, 6),
+			},
+			now:  60,
+			want: map[uint]time.Duration{1: 20 * time.Minute, 2: time.Minute},
+		},
+		{
+			name:    "never activated",
+			history: []database.ScenarioTransition{move(3, inactive, aborted, 5)},
+			now:     60,
+			want:    map[uint]time.Duration{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := activeTime(tt.history, at(tt.now))
+			if len(got) != len(tt.want) {
+				t.Fatalf("activeTime() = %v, want %v", got, tt.want)
+			}
+			for scenarioID, want := range tt.want {
+				if got[scenarioID] != want {
+					t.Errorf("active time of scenario %d = %s, want %s", scenarioID, got[scenarioID], want)
+				}
+			}
+		})
+	}
+}
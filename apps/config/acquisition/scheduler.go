@@ -0,0 +1,145 @@
+package acquisition
+
+import (
+	"context"
+	"database"
+	"log"
+	"time"
+
+	"config/lifecycle"
+	"config/watch"
+	"config/webhook"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultInterval is how often the scheduler checks the active scenarios.
+const DefaultInterval = 10 * time.Second
+
+// Scheduler ends the acquisition of the active scenarios that reach their
+// target: it completes them, or flags them overdue when their target asks
+// for it, and records the reason in the scenario history.
+type Scheduler struct {
+	db       *gorm.DB
+	events   *watch.Broker
+	hooks    *webhook.Dispatcher
+	interval time.Duration
+}
+
+func NewScheduler(db *gorm.DB, events *watch.Broker, hooks *webhook.Dispatcher, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Scheduler{db: db, events: events, hooks: hooks, interval: interval}
+}
+
+// Run checks the active scenarios every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Check(ctx); err != nil {
+			log.Printf("acquisition: checking scenarios: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check measures the progress of the active scenarios with a target that
+// have not been flagged yet and ends the acquisition of those that reached
+// it.
+func (s *Scheduler) Check(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+
+	scenarios := []database.Scenario{}
+	err := db.Where("status = ? AND overdue_at IS NULL", database.StatusActive).
+		Where("target_duration_seconds IS NOT NULL OR target_frames IS NOT NULL OR target_samples IS NOT NULL").
+		Find(&scenarios).Error
+	if err != nil || len(scenarios) == 0 {
+		return err
+	}
+
+	ids := make([]uint, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		ids = append(ids, scenario.ID)
+	}
+	progress, err := Measure(db, AvailableSources(db), ids, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	for _, ending := range due(scenarios, progress) {
+		if err := s.end(db, ending.scenario, ending.reason); err != nil {
+			log.Printf("acquisition: ending scenario %d: %v", ending.scenario.ID, err)
+		}
+	}
+	return nil
+}
+
+// ending is a scenario that reached its target, with the reason recorded in
+// its history.
+type ending struct {
+	scenario database.Scenario
+	reason   string
+}
+
+// due returns the scenarios whose progress meets their target, in the order
+// given. A scenario without progress has recorded nothing yet.
+func due(scenarios []database.Scenario, progress map[uint]Progress) []ending {
+	endings := []ending{}
+	for _, scenario := range scenarios {
+		if reason := Reached(scenario.Target, progress[scenario.ID]); reason != "" {
+			endings = append(endings, ending{scenario: scenario, reason: reason})
+		}
+	}
+	return endings
+}
+
+// end completes or flags a scenario that reached its target. A scenario
+// changed by someone else in the meantime is left alone.
+func (s *Scheduler) end(db *gorm.DB, scenario database.Scenario, reason string) error {
+	var ended *database.Scenario
+	err := db.Transaction(func(tx *gorm.DB) error {
+		current := database.Scenario{}
+		if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&current, scenario.ID).Error; err != nil {
+			return err
+		}
+		if lifecycle.CurrentStatus(&current) != database.StatusActive || current.OverdueAt != nil {
+			return nil
+		}
+
+		if current.Target.Action == database.TargetActionFlag {
+			now := time.Now().UTC()
+			if err := tx.Model(&current).Update("overdue_at", now).Error; err != nil {
+				return err
+			}
+			current.OverdueAt = &now
+			if err := lifecycle.Note(tx, &current, "overdue, "+reason); err != nil {
+				return err
+			}
+			ended = &current
+			return nil
+		}
+
+		var err error
+		ended, err = lifecycle.Transition(tx, scenario.ID, database.StatusCompleted, reason)
+		return err
+	})
+	if err != nil || ended == nil {
+		return err
+	}
+
+	s.events.Publish(watch.Modified, ended.Namespace, *ended)
+	if ended.Status == database.StatusCompleted {
+		s.hooks.Notify(ended.Namespace, webhook.ScenarioCompleted, *ended)
+	} else {
+		s.hooks.Notify(ended.Namespace, webhook.ScenarioOverdue, *ended)
+	}
+	return nil
+}
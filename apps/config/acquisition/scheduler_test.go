@@ -0,0 +1,55 @@
+package acquisition
+
+import (
+	"database"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestDue(t *testing.T) {
+	scenario := func(id uint, target database.Target) database.Scenario {
+		return database.Scenario{Model: gorm.Model{ID: id}, Status: database.StatusActive, Target: target}
+	}
+	scenarios := []database.Scenario{
+		scenario(1, database.Target{Frames: limit(100)}),
+		scenario(2, database.Target{DurationSeconds: limit(600), Action: database.TargetActionFlag}),
+		scenario(3, database.Target{Samples: limit(50)}),
+		scenario(4, database.Target{Frames: limit(10)}),
+	}
+	progress := map[uint]Progress{
+		1: {Frames: 120, Samples: 40},
+		2: {Active: 11 * time.Minute},
+		3: {Frames: 5, Samples: 49},
+	}
+
+	endings := due(scenarios, progress)
+	want := []struct {
+		id     uint
+		reason string
+	}{
+		{id: 1, reason: "target reached: 120 of 100 frames"},
+		{id: 2, reason: "target reached: active for 11m0s of 600s"},
+	}
+	if len(endings) != len(want) {
+		t.Fatalf("due() returned %d scenarios, want %d: %v", len(endings), len(want), endings)
+	}
+	for i, w := range want {
+		if endings[i].scenario.ID != w.id || endings[i].reason != w.reason {
+			t.Errorf("ending %d = scenario %d (%q), want scenario %d (%q)", i, endings[i].scenario.ID, endings[i].reason, w.id, w.reason)
+		}
+	}
+	if endings[1].scenario.Target.Action != database.TargetActionFlag {
+		t.Error("due() lost the target action the scheduler acts on")
+	}
+}
+
+func TestNewSchedulerDefaultsInterval(t *testing.T) {
+	if s := NewScheduler(nil, nil, nil, 0); s.interval != DefaultInterval {
+		t.Errorf("interval = %s, want %s", s.interval, DefaultInterval)
+	}
+	if s := NewScheduler(nil, nil, nil, time.Second); s.interval != time.Second {
+		t.Errorf("interval = %s, want 1s", s.interval)
+	}
+}
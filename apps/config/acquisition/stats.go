@@ -0,0 +1,85 @@
+package acquisition
+
+import (
+	"fmt"
+	"time"
+	"types"
+
+	"gorm.io/gorm"
+)
+
+// Stats describes what was recorded for a scenario in the first of sources
+// holding data for it. The per-channel statistics are aggregated by the
+// database, unnesting the sample arrays of processed_channels.
+func Stats(db *gorm.DB, sources []Source, scenarioID uint) (types.ScenarioStats, error) {
+	stats := types.ScenarioStats{ScenarioID: scenarioID, Channels: []types.ChannelStats{}}
+	for _, source := range sources {
+		summary := struct {
+			Frames int64
+			First  *time.Time
+			Last   *time.Time
+		}{}
+		err := db.Raw(`SELECT COUNT(DISTINCT frame_id) AS frames, MIN("timestamp") AS first, MAX("timestamp") AS last
+			FROM `+string(source)+` WHERE scenario_id = ? AND deleted_at IS NULL`, scenarioID).
+			Scan(&summary).Error
+		if err != nil {
+			return stats, fmt.Errorf("summarizing %s: %w", source, err)
+		}
+		if summary.Frames == 0 {
+			continue
+		}
+
+		if err := db.Raw(channelQuery(source), scenarioID).Scan(&stats.Channels).Error; err != nil {
+			return stats, fmt.Errorf("aggregating %s: %w", source, err)
+		}
+		stats.Source = string(source)
+		stats.Frames = summary.Frames
+		stats.FirstTimestamp = summary.First
+		stats.LastTimestamp = summary.Last
+		stats.SampleRate = sampleRate(source, stats)
+		return stats, nil
+	}
+	return stats, nil
+}
+
+// channelQuery returns the query aggregating the samples of every channel of
+// the scenario whose ID is its argument.
+func channelQuery(source Source) string {
+	value, from := "value", string(source)
+	if source == ProcessedChannels {
+		value, from = "sample", `processed_channels CROSS JOIN LATERAL unnest("values") AS sample`
+	}
+	return `SELECT metric_name AS name, COUNT(` + value + `) AS count,
+			MIN(` + value + `) AS min, MAX(` + value + `) AS max,
+			AVG(` + value + `) AS mean, STDDEV_SAMP(` + value + `) AS std_dev
+		FROM ` + from + `
+		WHERE scenario_id = ? AND deleted_at IS NULL
+		GROUP BY metric_name
+		ORDER BY metric_name`
+}
+
+// sampleRate returns the effective rate of the channel with the fewest
+// samples. processed_samples stamps every sample, so its samples span the
+// time between the first and the last; processed_channels stamps frames, so
+// the samples of the last frame fall after it and are left out, assuming
+// frames of equal length.
+func sampleRate(source Source, stats types.ScenarioStats) *float64 {
+	if stats.FirstTimestamp == nil || stats.LastTimestamp == nil || len(stats.Channels) == 0 {
+		return nil
+	}
+	span := stats.LastTimestamp.Sub(*stats.FirstTimestamp).Seconds()
+	if span <= 0 {
+		return nil
+	}
+
+	samples := stats.Channels[0].Count
+	for _, channel := range stats.Channels[1:] {
+		samples = min(samples, channel.Count)
+	}
+	spanned := float64(samples - 1)
+	if source == ProcessedChannels {
+		spanned = float64(samples) * float64(stats.Frames-1) / float64(stats.Frames)
+	}
+	rate := spanned / span
+	return &rate
+}
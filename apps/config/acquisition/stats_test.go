@@ -0,0 +1,49 @@
+package acquisition
+
+import (
+	"math"
+	"testing"
+	"time"
+	"types"
+)
+
+func TestSampleRate(t *testing.T) {
+	first := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	stats := func(span time.Duration, frames int64, counts ...int64) types.ScenarioStats {
+		last := first.Add(span)
+		s := types.ScenarioStats{Frames: frames, FirstTimestamp: &first, LastTimestamp: &last}
+		for _, count := range counts {
+			s.Channels = append(s.Channels, types.ChannelStats{Count: count})
+		}
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		source Source
+		stats  types.ScenarioStats
+		want   float64 // 0 when no rate can be given
+	}{
+		// 1001 samples stamped over 10s: 1000 intervals.
+		{name: "samples", source: ProcessedSamples, stats: stats(10*time.Second, 1001, 1001), want: 100},
+		// 11 frames of 100 samples stamped over 10s: the last frame starts at the end.
+		{name: "frames", source: ProcessedChannels, stats: stats(10*time.Second, 11, 1100), want: 100},
+		{name: "channel with the fewest samples", source: ProcessedSamples, stats: stats(10*time.Second, 2001, 2001, 1001), want: 100},
+		{name: "single timestamp", source: ProcessedSamples, stats: stats(0, 1, 1)},
+		{name: "no channels", source: ProcessedChannels, stats: stats(10*time.Second, 11)},
+		{name: "no timestamps", source: ProcessedSamples, stats: types.ScenarioStats{Channels: []types.ChannelStats{{Count: 10}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sampleRate(tt.source, tt.stats)
+			switch {
+			case tt.want == 0 && got != nil:
+				t.Errorf("sampleRate() = %g, want none", *got)
+			case tt.want != 0 && got == nil:
+				t.Errorf("sampleRate() = none, want %g", tt.want)
+			case tt.want != 0 && math.Abs(*got-tt.want) > 1e-9:
+				t.Errorf("sampleRate() = %g, want %g", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database"
+	"encoding/base64"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+)
+
+// TokenPrefix marks config API tokens so they are easy to spot in logs and
+// secret scanners.
+const TokenPrefix = "nl_"
+
+// Principal is the caller a request was authenticated as.
+type Principal struct {
+	TokenID  uint
+	Name     string
+	Role     database.Role
+	DeviceID *uint
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the request, or nil when the route is
+// public.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// HashToken returns the hex encoded SHA-256 hash under which a token is stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateToken returns a new random token.
+func GenerateToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// EnsureBootstrapToken makes sure token exists as an admin token, so a fresh
+// installation can create its first real tokens.
+func EnsureBootstrapToken(db *gorm.DB, token string) error {
+	apiToken := database.APIToken{}
+	return db.Where(database.APIToken{TokenHash: HashToken(token)}).
+		Attrs(database.APIToken{Name: "bootstrap", Role: database.RoleAdmin}).
+		FirstOrCreate(&apiToken).Error
+}
@@ -0,0 +1,321 @@
+// Package design builds test matrices from conditions and their values:
+// the full factorial of all combinations or a pairwise covering array, in
+// which every pair of values of two conditions appears in at least one run.
+package design
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Factor is a condition with the values to combine.
+type Factor struct {
+	Name   string
+	Levels []string
+}
+
+// Rule excludes every run that sets all of its factors to the given levels,
+// e.g. {"voltage": "1.8V", "temperature": "50C"}.
+type Rule map[string]string
+
+// Run holds the index of the level chosen for each factor, in factor order.
+type Run []int
+
+// Size returns the number of runs of the full factorial. It saturates at
+// math.MaxInt rather than overflowing, so that the result can be compared
+// against a limit.
+func Size(factors []Factor) int {
+	for _, factor := range factors {
+		if len(factor.Levels) == 0 {
+			return 0
+		}
+	}
+	size := 1
+	for _, factor := range factors {
+		if size > math.MaxInt/len(factor.Levels) {
+			return math.MaxInt
+		}
+		size *= len(factor.Levels)
+	}
+	return size
+}
+
+// Factorial returns every combination of levels that no rule excludes.
+func Factorial(factors []Factor, rules []Rule) ([]Run, error) {
+	excluded, err := compile(factors, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []Run{}
+	if Size(factors) == 0 {
+		return runs, nil
+	}
+	run := make(Run, len(factors))
+	for {
+		if !excluded.any(run, len(factors)) {
+			runs = append(runs, slices.Clone(run))
+		}
+		// Advance like an odometer, the last factor turning fastest.
+		i := len(factors) - 1
+		for ; i >= 0; i-- {
+			run[i]++
+			if run[i] < len(factors[i].Levels) {
+				break
+			}
+			run[i] = 0
+		}
+		if i < 0 {
+			return runs, nil
+		}
+	}
+}
+
+// maxCandidates bounds the number of runs built and compared for each run
+// added to a pairwise array.
+const maxCandidates = 32
+
+// Pairwise returns runs covering every pair of levels of two factors that
+// the rules allow. The array is built greedily: of several candidate runs,
+// each seeded with a pair not covered yet, the one covering the most new
+// pairs is kept. It is small but not necessarily minimal, and the same input
+// always yields the same runs.
+func Pairwise(factors []Factor, rules []Rule) ([]Run, error) {
+	if len(factors) < 2 {
+		return Factorial(factors, rules)
+	}
+	excluded, err := compile(factors, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	uncovered := map[pair]bool{}
+	order := []pair{}
+	for i := range factors {
+		for j := i + 1; j < len(factors); j++ {
+			for a := range factors[i].Levels {
+				for b := range factors[j].Levels {
+					p := pair{i, a, j, b}
+					if excluded.pair(p) {
+						continue
+					}
+					uncovered[p] = true
+					order = append(order, p)
+				}
+			}
+		}
+	}
+
+	runs := []Run{}
+	for len(uncovered) > 0 {
+		var best Run
+		bestGain := 0
+		candidates := 0
+		for _, seed := range order {
+			if !uncovered[seed] {
+				continue
+			}
+			if candidates == maxCandidates {
+				break
+			}
+			candidates++
+
+			run, ok := complete(factors, excluded, uncovered, seed)
+			if !ok {
+				run, ok = search(factors, excluded, seed)
+			}
+			if !ok {
+				// Only runs excluded by a wider rule contain this
+				// pair; it cannot be covered.
+				delete(uncovered, seed)
+				continue
+			}
+			if gain := covers(run, uncovered); gain > bestGain {
+				best, bestGain = run, gain
+			}
+		}
+		if best == nil {
+			continue
+		}
+		for i := range best {
+			for j := i + 1; j < len(best); j++ {
+				delete(uncovered, pair{i, best[i], j, best[j]})
+			}
+		}
+		runs = append(runs, best)
+	}
+	return runs, nil
+}
+
+// pair is level a of factor i together with level b of factor j, i < j.
+type pair struct {
+	i, a, j, b int
+}
+
+// complete builds a run containing seed, choosing for every other factor the
+// level that covers the most uncovered pairs with the factors chosen before.
+func complete(factors []Factor, excluded rules, uncovered map[pair]bool, seed pair) (Run, bool) {
+	run := make(Run, len(factors))
+	set := make([]bool, len(factors))
+	run[seed.i], run[seed.j] = seed.a, seed.b
+	set[seed.i], set[seed.j] = true, true
+
+	for k := range factors {
+		if set[k] {
+			continue
+		}
+		bestLevel, bestGain := -1, -1
+		for level := range factors[k].Levels {
+			run[k] = level
+			set[k] = true
+			if excluded.anySet(run, set) {
+				set[k] = false
+				continue
+			}
+			set[k] = false
+			gain := 0
+			for other := range factors {
+				if !set[other] {
+					continue
+				}
+				p := pair{other, run[other], k, level}
+				if k < other {
+					p = pair{k, level, other, run[other]}
+				}
+				if uncovered[p] {
+					gain++
+				}
+			}
+			if gain > bestGain {
+				bestLevel, bestGain = level, gain
+			}
+		}
+		if bestLevel < 0 {
+			return nil, false
+		}
+		run[k] = bestLevel
+		set[k] = true
+	}
+	return run, true
+}
+
+// search looks for any run containing seed that no rule excludes. It is the
+// fallback for seeds complete runs into a dead end with: every level of the
+// other factors is tried in turn, backtracking as soon as a rule matches the
+// levels chosen so far.
+func search(factors []Factor, excluded rules, seed pair) (Run, bool) {
+	run := make(Run, len(factors))
+	set := make([]bool, len(factors))
+	run[seed.i], run[seed.j] = seed.a, seed.b
+	set[seed.i], set[seed.j] = true, true
+	if excluded.anySet(run, set) {
+		return nil, false
+	}
+
+	var fill func(k int) bool
+	fill = func(k int) bool {
+		if k == len(factors) {
+			return true
+		}
+		if k == seed.i || k == seed.j {
+			return fill(k + 1)
+		}
+		for level := range factors[k].Levels {
+			run[k], set[k] = level, true
+			if !excluded.anySet(run, set) && fill(k+1) {
+				return true
+			}
+		}
+		set[k] = false
+		return false
+	}
+	if !fill(0) {
+		return nil, false
+	}
+	return run, true
+}
+
+// covers counts the uncovered pairs in run.
+func covers(run Run, uncovered map[pair]bool) int {
+	count := 0
+	for i := range run {
+		for j := i + 1; j < len(run); j++ {
+			if uncovered[pair{i, run[i], j, run[j]}] {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// rules are the exclusion rules with factors and levels given by index.
+type rules []map[int]int
+
+// compile resolves the names in the rules, rejecting unknown ones so that a
+// typo does not silently exclude nothing.
+func compile(factors []Factor, in []Rule) (rules, error) {
+	compiled := make(rules, 0, len(in))
+	for n, rule := range in {
+		resolved := map[int]int{}
+		for name, level := range rule {
+			i := slices.IndexFunc(factors, func(f Factor) bool { return f.Name == name })
+			if i < 0 {
+				return nil, fmt.Errorf("exclusion %d refers to condition %q, which is not being combined", n+1, name)
+			}
+			l := slices.Index(factors[i].Levels, level)
+			if l < 0 {
+				return nil, fmt.Errorf("exclusion %d refers to value %q, which is not combined for condition %q", n+1, level, name)
+			}
+			resolved[i] = l
+		}
+		compiled = append(compiled, resolved)
+	}
+	return compiled, nil
+}
+
+// any reports whether a rule excludes the complete run.
+func (r rules) any(run Run, factors int) bool {
+	set := make([]bool, factors)
+	for i := range set {
+		set[i] = true
+	}
+	return r.anySet(run, set)
+}
+
+// anySet reports whether a rule is violated by the factors already set.
+func (r rules) anySet(run Run, set []bool) bool {
+	for _, rule := range r {
+		matched := true
+		for i, level := range rule {
+			if !set[i] || run[i] != level {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// pair reports whether a rule excludes the pair on its own.
+func (r rules) pair(p pair) bool {
+	for _, rule := range r {
+		if len(rule) > 2 {
+			continue
+		}
+		matched := true
+		for i, level := range rule {
+			if !(i == p.i && level == p.a) && !(i == p.j && level == p.b) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
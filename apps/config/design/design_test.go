@@ -0,0 +1,145 @@
+package design
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func levels(prefix string, n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = prefix + strconv.Itoa(i)
+	}
+	return values
+}
+
+// randomCase builds 3 to 5 factors of 2 to 4 levels and a few rules over two
+// or three of them.
+func randomCase(rng *rand.Rand) ([]Factor, []Rule) {
+	factors := make([]Factor, 3+rng.Intn(3))
+	for i := range factors {
+		name := fmt.Sprintf("f%d", i)
+		factors[i] = Factor{Name: name, Levels: levels(name+"=", 2+rng.Intn(3))}
+	}
+	rules := make([]Rule, rng.Intn(5))
+	for n := range rules {
+		rule := Rule{}
+		for _, i := range rng.Perm(len(factors))[:2+rng.Intn(2)] {
+			rule[factors[i].Name] = factors[i].Levels[rng.Intn(len(factors[i].Levels))]
+		}
+		rules[n] = rule
+	}
+	return factors, rules
+}
+
+// pairsOf returns every pair of levels of two factors occurring in runs.
+func pairsOf(runs []Run) map[pair]bool {
+	pairs := map[pair]bool{}
+	for _, run := range runs {
+		for i := range run {
+			for j := i + 1; j < len(run); j++ {
+				pairs[pair{i, run[i], j, run[j]}] = true
+			}
+		}
+	}
+	return pairs
+}
+
+func TestPairwiseCoversFactorialPairs(t *testing.T) {
+	voltage := Factor{Name: "voltage", Levels: []string{"1.8V", "3.3V", "5V"}}
+	temperature := Factor{Name: "temperature", Levels: []string{"-20C", "25C", "50C"}}
+	humidity := Factor{Name: "humidity", Levels: []string{"20%", "80%"}}
+
+	tests := []struct {
+		name    string
+		factors []Factor
+		rules   []Rule
+	}{
+		{name: "no rules", factors: []Factor{voltage, temperature, humidity}},
+		{name: "two-condition exclusion", factors: []Factor{voltage, temperature, humidity}, rules: []Rule{
+			{"voltage": "1.8V", "temperature": "50C"},
+		}},
+		{name: "three-condition exclusion", factors: []Factor{voltage, temperature, humidity}, rules: []Rule{
+			{"voltage": "5V", "temperature": "-20C", "humidity": "80%"},
+		}},
+		{name: "single-level exclusion", factors: []Factor{voltage, temperature, humidity}, rules: []Rule{
+			{"humidity": "80%"},
+		}},
+		{name: "pair only in one run", factors: []Factor{
+			{Name: "a", Levels: levels("a", 2)},
+			{Name: "b", Levels: levels("b", 2)},
+			{Name: "c", Levels: levels("c", 2)},
+		}, rules: []Rule{
+			{"a": "a0", "c": "c0"},
+			{"b": "b0", "c": "c1"},
+		}},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		factors, rules := randomCase(rng)
+		tests = append(tests, struct {
+			name    string
+			factors []Factor
+			rules   []Rule
+		}{name: fmt.Sprintf("random %d", n), factors: factors, rules: rules})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factorial, err := Factorial(tt.factors, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pairwise, err := Pairwise(tt.factors, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			allowed := map[string]bool{}
+			for _, run := range factorial {
+				allowed[fmt.Sprint(run)] = true
+			}
+			for _, run := range pairwise {
+				if !allowed[fmt.Sprint(run)] {
+					t.Errorf("pairwise run %v is excluded", run)
+				}
+			}
+
+			covered := pairsOf(pairwise)
+			for p := range pairsOf(factorial) {
+				if !covered[p] {
+					t.Errorf("pair %s=%s, %s=%s is not covered", tt.factors[p.i].Name, tt.factors[p.i].Levels[p.a], tt.factors[p.j].Name, tt.factors[p.j].Levels[p.b])
+				}
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	wide := make([]Factor, 64)
+	for i := range wide {
+		wide[i] = Factor{Name: strconv.Itoa(i), Levels: levels("v", 4)}
+	}
+
+	tests := []struct {
+		name    string
+		factors []Factor
+		want    int
+	}{
+		{name: "no factors", want: 1},
+		{name: "product", factors: []Factor{{Levels: levels("a", 3)}, {Levels: levels("b", 4)}}, want: 12},
+		{name: "factor without levels", factors: append([]Factor{{Name: "empty"}}, wide...), want: 0},
+		{name: "overflowing product saturates", factors: wide, want: math.MaxInt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Size(tt.factors); got != tt.want {
+				t.Errorf("Size() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+// cloneName returns the name of a copy: the requested one, otherwise the
+// source name, suffixed when the copy ends up next to its source.
+func cloneName(requested, source string, sameParent bool) string {
+	switch {
+	case requested != "":
+		return requested
+	case sameParent:
+		return source + " (copy)"
+	default:
+		return source
+	}
+}
+
+// cloneScenario creates an inactive copy of source in testSession, linked to
+// the same condition values and with the same target, labels and
+// annotations. It returns the IDs of the new scenario
+// conditions and must be called inside a transaction.
+func cloneScenario(tx *gorm.DB, source *database.Scenario, testSession *database.TestSession, name string) (database.Scenario, []uint, error) {
+	conditionValueIDs := []uint{}
+	err := tx.Model(&database.ScenarioCondition{}).
+		Where("scenario_id = ?", source.ID).
+		Order("id").
+		Pluck("condition_value_id", &conditionValueIDs).Error
+	if err != nil {
+		return database.Scenario{}, nil, err
+	}
+
+	clone := database.Scenario{
+		Namespace:     testSession.Namespace,
+		Name:          name,
+		Status:        database.StatusInactive,
+		TestSessionID: testSession.ID,
+		Target:        source.Target,
+		Metadata:      source.Metadata,
+	}
+	if err := createScenarioWithConditionValues(tx, &clone, conditionValueIDs); err != nil {
+		return database.Scenario{}, nil, err
+	}
+
+	scenarioConditionIDs := []uint{}
+	err = tx.Model(&database.ScenarioCondition{}).
+		Where("scenario_id = ?", clone.ID).
+		Order("id").
+		Pluck("id", &scenarioConditionIDs).Error
+	return clone, scenarioConditionIDs, err
+}
+
+// decodeCloneRequest reads the optional body of a clone request. It writes
+// the error response itself and reports whether the handler may continue.
+func decodeCloneRequest[T any](w http.ResponseWriter, r *http.Request) (T, bool) {
+	var req T
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return req, false
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return req, false
+	}
+
+	return req, true
+}
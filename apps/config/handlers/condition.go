@@ -3,9 +3,13 @@ package handlers
 import (
 	"database"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 
 	"config/utils"
+	"config/values"
+	"config/watch"
 	"types"
 
 	apierrors "github.com/neuro-lab/errors"
@@ -13,12 +17,19 @@ import (
 	"gorm.io/gorm"
 )
 
+var conditionListSpec = utils.ListSpec{
+	NameColumn: "name",
+	SortFields: []string{"name", "updated_at"},
+	Labels:     true,
+}
+
 type ConditionHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *watch.Broker
 }
 
-func NewConditionHandler(db *gorm.DB) *ConditionHandler {
-	return &ConditionHandler{db: db}
+func NewConditionHandler(db *gorm.DB, events *watch.Broker) *ConditionHandler {
+	return &ConditionHandler{db: db, events: events}
 }
 
 func (h *ConditionHandler) CreateCondition(w http.ResponseWriter, r *http.Request) {
@@ -33,15 +44,28 @@ func (h *ConditionHandler) CreateCondition(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
 	condition := database.Condition{
-		Name: req.Name,
+		Namespace: utils.Namespace(r),
+		Name:      req.Name,
+		Metadata:  metadata,
+	}
+	if err := setConditionType(&condition, req.ValueType, req.Unit, req.Options, req.Min, req.Max); err != nil {
+		writeDatabaseError(w, r, err)
+		return
 	}
 
 	result := h.db.Create(&condition)
 	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		writeDatabaseError(w, r, conflictOn(result.Error, conditionConflict(&condition)))
 		return
 	}
+	h.events.Publish(watch.Added, condition.Namespace, condition)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
@@ -67,47 +91,155 @@ func (h *ConditionHandler) UpdateCondition(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	condition := database.Condition{
-		Name: req.Name,
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
 	}
-	condition.ID = id
 
-	result := h.db.Save(&condition)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	condition := database.Condition{}
+	var retyped []database.ConditionValue
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &condition, &condition.Model, id); err != nil {
+			return err
+		}
+		condition.Name = req.Name
+		condition.Metadata = metadata
+		retyped, err = updateConditionType(tx, &condition, &req)
+		if err != nil {
+			return err
+		}
+		return conflictOn(tx.Save(&condition).Error, conditionConflict(&condition))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Modified, condition.Namespace, condition)
+	for _, conditionValue := range retyped {
+		h.events.Publish(watch.Modified, condition.Namespace, conditionValue)
+	}
 
+	utils.SetETag(w, &condition.Model)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(condition)
 }
 
-func (h *ConditionHandler) DeleteCondition(w http.ResponseWriter, r *http.Request) {
+// PatchCondition applies a JSON merge patch (RFC 7396) to a condition. Only the
+// fields present in the patch are changed.
+func (h *ConditionHandler) PatchCondition(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid condition ID: "+err.Error(), r.URL.Path))
 		return
 	}
 
-	// First check if the condition exists
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
 	condition := database.Condition{}
-	result := h.db.First(&condition, id)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	var retyped []database.ConditionValue
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &condition, &condition.Model, id); err != nil {
+			return err
+		}
+		req := types.UpdateConditionRequest{
+			ID:        id,
+			Name:      condition.Name,
+			ValueType: string(condition.ValueType),
+			Unit:      condition.Unit,
+			Options:   condition.Options,
+			Min:       condition.Min,
+			Max:       condition.Max,
+			Metadata:  requestMetadata(condition.Metadata),
+		}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		metadata, err := metadataOf(req.Metadata)
+		if err != nil {
+			return err
+		}
+		condition.Name = req.Name
+		condition.Metadata = metadata
+		retyped, err = updateConditionType(tx, &condition, &req)
+		if err != nil {
+			return err
+		}
+		return conflictOn(tx.Save(&condition).Error, conditionConflict(&condition))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Modified, condition.Namespace, condition)
+	for _, conditionValue := range retyped {
+		h.events.Publish(watch.Modified, condition.Namespace, conditionValue)
+	}
 
-	// Delete the condition
-	result = h.db.Delete(&condition, id)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	utils.SetETag(w, &condition.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(condition)
+}
+
+func (h *ConditionHandler) DeleteCondition(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid condition ID: "+err.Error(), r.URL.Path))
 		return
 	}
 
+	condition := database.Condition{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &condition, &condition.Model, id); err != nil {
+			return err
+		}
+		return tx.Delete(&condition).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Deleted, condition.Namespace, condition)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreCondition undeletes a soft-deleted condition.
+func (h *ConditionHandler) RestoreCondition(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid condition ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	condition := database.Condition{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := restoreRow(namespaced(tx, r), &condition, &condition.Model, id, "Condition"); err != nil {
+			return conflictOn(err, conditionConflict(&condition))
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Added, condition.Namespace, condition)
+
+	utils.SetETag(w, &condition.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(condition)
+}
+
 func (h *ConditionHandler) GetCondition(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
@@ -116,26 +248,155 @@ func (h *ConditionHandler) GetCondition(w http.ResponseWriter, r *http.Request)
 	}
 
 	condition := database.Condition{}
-	result := h.db.First(&condition, id)
+	result := namespaced(h.db, r).First(&condition, id)
 	if result.Error != nil {
 		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
 		return
 	}
 
+	utils.SetETag(w, &condition.Model)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(condition)
 }
 
 func (h *ConditionHandler) GetConditions(w http.ResponseWriter, r *http.Request) {
-	conditions := []database.Condition{}
-	result := h.db.Find(&conditions)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	opts, err := utils.ParseListOptions(r, conditionListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	if serveWatchIfRequested(w, r, h.events, "Condition", utils.Namespace(r), selecting(opts, nil)) {
+		return
+	}
+
+	version := listVersion(h.events)
+	conditions, meta, err := utils.Paginate[database.Condition](namespaced(h.db, r), opts, conditionListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 		return
 	}
+	meta.ResourceVersion = version
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(conditions)
+	json.NewEncoder(w).Encode(types.NewList("ConditionList", conditions, meta))
+}
+
+// ApplyConditionLibrary creates the conditions and values of a library that do
+// not exist yet. Existing entries are left untouched, so re-applying the same
+// library is a no-op.
+func (h *ConditionHandler) ApplyConditionLibrary(w http.ResponseWriter, r *http.Request) {
+	var req types.ConditionLibraryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	var resp types.ConditionLibraryResponse
+	var resolver *conditionResolver
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		resolver = newConditionResolver(tx, utils.Namespace(r))
+		for _, condition := range req.Conditions {
+			if _, err := resolver.condition(condition.Name, condition.Description); err != nil {
+				return err
+			}
+			for _, value := range condition.Values {
+				if _, err := resolver.value(condition.Name, value); err != nil {
+					return err
+				}
+			}
+		}
+
+		resp.Created = types.ConditionLibraryCounts{
+			Conditions:      resolver.created.Conditions,
+			ConditionValues: resolver.created.ConditionValues,
+		}
+		resp.Unchanged = types.ConditionLibraryCounts{
+			Conditions:      resolver.reused.Conditions,
+			ConditionValues: resolver.reused.ConditionValues,
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	for _, object := range resolver.added {
+		h.events.Publish(watch.Added, utils.Namespace(r), object)
+	}
+
+	status := http.StatusOK
+	if resp.Created.Conditions > 0 || resp.Created.ConditionValues > 0 {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// setConditionType copies a type definition onto condition and checks it.
+func setConditionType(condition *database.Condition, valueType, unit string, options []string, min, max *float64) error {
+	condition.ValueType = database.ValueType(valueType)
+	condition.Unit = unit
+	condition.Options = options
+	condition.Min = min
+	condition.Max = max
+	return values.Normalize(condition)
+}
+
+// updateConditionType applies the type definition of req to condition. When
+// the definition changes, the existing values are parsed again; the first one
+// that does not fit fails the update. It returns the re-parsed values.
+func updateConditionType(tx *gorm.DB, condition *database.Condition, req *types.UpdateConditionRequest) ([]database.ConditionValue, error) {
+	before := *condition
+	if err := setConditionType(condition, req.ValueType, req.Unit, req.Options, req.Min, req.Max); err != nil {
+		return nil, err
+	}
+	if sameConditionType(&before, condition) {
+		return nil, nil
+	}
+
+	conditionValues := []database.ConditionValue{}
+	if err := tx.Where("condition_id = ?", condition.ID).Order("id").Find(&conditionValues).Error; err != nil {
+		return nil, err
+	}
+	for i := range conditionValues {
+		if err := values.Apply(&conditionValues[i], condition); err != nil {
+			return nil, &values.Error{Detail: fmt.Sprintf("Value %q does not fit the new type: %v", conditionValues[i].Value, err)}
+		}
+		for _, other := range conditionValues[:i] {
+			if values.SameTyped(&other, &conditionValues[i]) {
+				return nil, &conflictError{detail: fmt.Sprintf("Values %q and %q of condition %q would be the same", other.Value, conditionValues[i].Value, condition.Name)}
+			}
+		}
+		err := tx.Model(&conditionValues[i]).Updates(map[string]interface{}{
+			"number":     conditionValues[i].Number,
+			"number_max": conditionValues[i].NumberMax,
+			"boolean":    conditionValues[i].Boolean,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return conditionValues, nil
+}
+
+func sameConditionType(a, b *database.Condition) bool {
+	sameBound := func(x, y *float64) bool {
+		return x == nil && y == nil || x != nil && y != nil && *x == *y
+	}
+	return a.ValueType == b.ValueType && a.Unit == b.Unit && slices.Equal(a.Options, b.Options) &&
+		sameBound(a.Min, b.Min) && sameBound(a.Max, b.Max)
+}
+
+func conditionConflict(condition *database.Condition) string {
+	return fmt.Sprintf("Condition %q already exists in namespace %q", condition.Name, condition.Namespace)
 }
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database"
+	"errors"
+	"fmt"
+	"types"
+
+	"config/values"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// conditionResolver looks up conditions and condition values by name inside a
+// transaction, creating the missing ones. It remembers every entity it has
+// seen so repeated references are resolved once and counted once. Conditions
+// are looked up and created in a single namespace.
+type conditionResolver struct {
+	tx         *gorm.DB
+	namespace  string
+	conditions map[string]uint
+	values     map[uint]map[string]uint
+	created    types.WorkflowEntityCounts
+	reused     types.WorkflowEntityCounts
+	// added holds the created rows, to be published once the transaction
+	// has committed.
+	added []interface{}
+}
+
+func newConditionResolver(tx *gorm.DB, namespace string) *conditionResolver {
+	return &conditionResolver{
+		tx:         tx,
+		namespace:  namespace,
+		conditions: map[string]uint{},
+		values:     map[uint]map[string]uint{},
+	}
+}
+
+// liveRowsConflict targets the partial unique indexes that only cover rows
+// which have not been soft-deleted.
+func liveRowsConflict(columns ...string) clause.OnConflict {
+	onConflict := clause.OnConflict{
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+		DoNothing:   true,
+	}
+	for _, column := range columns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	return onConflict
+}
+
+// findOrCreate loads the first row matching the query into dest and inserts
+// fresh when there is none. It reports whether the row was created.
+func findOrCreate[T any](tx *gorm.DB, dest *T, fresh T, conflictColumns []string, query string, args ...interface{}) (bool, error) {
+	err := tx.Where(query, args...).First(dest).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	*dest = fresh
+	result := tx.Clauses(liveRowsConflict(conflictColumns...)).Create(dest)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// Inserted concurrently by another transaction.
+	return false, tx.Where(query, args...).First(dest).Error
+}
+
+// condition returns the ID of the condition with the given name. A condition
+// it creates gets the given description; an existing one is left as is.
+func (c *conditionResolver) condition(name, description string) (uint, error) {
+	if id, ok := c.conditions[name]; ok {
+		return id, nil
+	}
+
+	condition := database.Condition{}
+	fresh := database.Condition{Metadata: database.Metadata{Description: description}, Namespace: c.namespace, Name: name}
+	created, err := findOrCreate(c.tx, &condition, fresh, []string{"namespace", "name"}, "namespace = ? AND name = ?", c.namespace, name)
+	if err != nil {
+		return 0, err
+	}
+	if created {
+		c.created.Conditions++
+		c.added = append(c.added, condition)
+	} else {
+		c.reused.Conditions++
+	}
+
+	c.conditions[name] = condition.ID
+	return condition.ID, nil
+}
+
+// value returns the ID of the given value of the named condition.
+func (c *conditionResolver) value(conditionName, value string) (uint, error) {
+	conditionID, err := c.condition(conditionName, "")
+	if err != nil {
+		return 0, err
+	}
+
+	if c.values[conditionID] == nil {
+		c.values[conditionID] = map[string]uint{}
+	}
+	if id, ok := c.values[conditionID][value]; ok {
+		return id, nil
+	}
+
+	condition := database.Condition{}
+	if err := c.tx.First(&condition, conditionID).Error; err != nil {
+		return 0, err
+	}
+	fresh := database.ConditionValue{ConditionID: conditionID, Value: value}
+	if err := values.Apply(&fresh, &condition); err != nil {
+		return 0, &values.Error{Detail: fmt.Sprintf("Value %q of condition %q: %v", value, conditionName, err)}
+	}
+
+	// A typed value written differently, e.g. "25 °C" for "25C", reuses
+	// the existing one.
+	conditionValue := database.ConditionValue{}
+	existing, err := findTypedValue(c.tx, &fresh)
+	if err != nil {
+		return 0, err
+	}
+	created := false
+	if existing != nil {
+		conditionValue = *existing
+	} else {
+		created, err = findOrCreate(c.tx, &conditionValue, fresh, []string{"condition_id", "value"}, "condition_id = ? AND value = ?", conditionID, value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if created {
+		c.created.ConditionValues++
+		c.added = append(c.added, conditionValue)
+	} else {
+		c.reused.ConditionValues++
+	}
+
+	c.values[conditionID][value] = conditionValue.ID
+	return conditionValue.ID, nil
+}
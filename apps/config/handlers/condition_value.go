@@ -3,22 +3,34 @@ package handlers
 import (
 	"database"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"types"
 
 	"config/utils"
+	"config/values"
+	"config/watch"
+
+	apierrors "github.com/neuro-lab/errors"
 
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
+var conditionValueListSpec = utils.ListSpec{
+	NameColumn: "value",
+	SortFields: []string{"value", "updated_at"},
+}
+
 type ConditionValueHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *watch.Broker
 }
 
-func NewConditionValueHandler(db *gorm.DB) *ConditionValueHandler {
-	return &ConditionValueHandler{db: db}
+func NewConditionValueHandler(db *gorm.DB, events *watch.Broker) *ConditionValueHandler {
+	return &ConditionValueHandler{db: db, events: events}
 }
 
 func (h *ConditionValueHandler) CreateConditionValue(w http.ResponseWriter, r *http.Request) {
@@ -36,7 +48,7 @@ func (h *ConditionValueHandler) CreateConditionValue(w http.ResponseWriter, r *h
 
 	// Validate that the condition exists
 	condition := database.Condition{}
-	if result := h.db.First(&condition, req.ConditionID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&condition, req.ConditionID); result.Error != nil {
 		http.Error(w, "Condition does not exist", http.StatusBadRequest)
 		return
 	}
@@ -46,9 +58,14 @@ func (h *ConditionValueHandler) CreateConditionValue(w http.ResponseWriter, r *h
 		ConditionID: req.ConditionID,
 	}
 
-	result := h.db.Create(&conditionValue)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkConditionValue(tx, &conditionValue, &condition); err != nil {
+			return err
+		}
+		return conflictOn(tx.Create(&conditionValue).Error, conditionValueConflict(&conditionValue))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
@@ -57,6 +74,7 @@ func (h *ConditionValueHandler) CreateConditionValue(w http.ResponseWriter, r *h
 		http.Error(w, "Error loading condition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.events.Publish(watch.Added, utils.Namespace(r), conditionValue)
 
 	w.WriteHeader(http.StatusCreated)
 
@@ -87,20 +105,25 @@ func (h *ConditionValueHandler) UpdateConditionValue(w http.ResponseWriter, r *h
 
 	// Validate that the condition exists
 	condition := database.Condition{}
-	if result := h.db.First(&condition, req.ConditionID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&condition, req.ConditionID); result.Error != nil {
 		http.Error(w, "Condition does not exist", http.StatusBadRequest)
 		return
 	}
 
-	conditionValue := database.ConditionValue{
-		Value:       req.Value,
-		ConditionID: req.ConditionID,
-	}
-	conditionValue.ID = id
-
-	result := h.db.Save(&conditionValue)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	conditionValue := database.ConditionValue{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(viaParent(tx, r, "condition_id", &database.Condition{}), r, &conditionValue, &conditionValue.Model, id); err != nil {
+			return err
+		}
+		conditionValue.Value = req.Value
+		conditionValue.ConditionID = req.ConditionID
+		if err := checkConditionValue(tx, &conditionValue, &condition); err != nil {
+			return err
+		}
+		return conflictOn(tx.Save(&conditionValue).Error, conditionValueConflict(&conditionValue))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
@@ -109,13 +132,68 @@ func (h *ConditionValueHandler) UpdateConditionValue(w http.ResponseWriter, r *h
 		http.Error(w, "Error loading condition: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.events.Publish(watch.Modified, utils.Namespace(r), conditionValue)
 
+	utils.SetETag(w, &conditionValue.Model)
 	if err := json.NewEncoder(w).Encode(conditionValue); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
+
+// PatchConditionValue applies a JSON merge patch (RFC 7396) to a condition value. Only the
+// fields present in the patch are changed.
+func (h *ConditionValueHandler) PatchConditionValue(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid condition value ID: "+err.Error(), r.URL.Path))
+		return
+	}
 
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	conditionValue := database.ConditionValue{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(viaParent(tx, r, "condition_id", &database.Condition{}), r, &conditionValue, &conditionValue.Model, id); err != nil {
+			return err
+		}
+		req := types.UpdateConditionValueRequest{ID: id, Value: conditionValue.Value, ConditionID: conditionValue.ConditionID}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		condition := database.Condition{}
+		if err := namespaced(tx, r).First(&condition, req.ConditionID).Error; err != nil {
+			return err
+		}
+		conditionValue.Value = req.Value
+		conditionValue.ConditionID = req.ConditionID
+		if err := checkConditionValue(tx, &conditionValue, &condition); err != nil {
+			return err
+		}
+		return conflictOn(tx.Save(&conditionValue).Error, conditionValueConflict(&conditionValue))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	if err := h.db.Preload("Condition").First(&conditionValue, id).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	h.events.Publish(watch.Modified, utils.Namespace(r), conditionValue)
+
+	utils.SetETag(w, &conditionValue.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(conditionValue)
 }
 
 func (h *ConditionValueHandler) DeleteConditionValue(w http.ResponseWriter, r *http.Request) {
@@ -127,49 +205,81 @@ func (h *ConditionValueHandler) DeleteConditionValue(w http.ResponseWriter, r *h
 	}
 
 	conditionValue := database.ConditionValue{}
-	result := h.db.Delete(&conditionValue, id)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(viaParent(tx, r, "condition_id", &database.Condition{}), r, &conditionValue, &conditionValue.Model, id); err != nil {
+			return err
+		}
+		return tx.Delete(&conditionValue).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Deleted, utils.Namespace(r), conditionValue)
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *ConditionValueHandler) GetConditionValue(w http.ResponseWriter, r *http.Request) {
+// RestoreConditionValue undeletes a soft-deleted condition value.
+func (h *ConditionValueHandler) RestoreConditionValue(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
-		http.Error(w, "Invalid condition value ID: "+err.Error(), http.StatusBadRequest)
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid condition value ID: "+err.Error(), r.URL.Path))
 		return
 	}
 
 	conditionValue := database.ConditionValue{}
-	result := h.db.Preload("Condition").First(&conditionValue, id)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := restoreRow(viaParent(tx, r, "condition_id", &database.Condition{}), &conditionValue, &conditionValue.Model, id, "Condition value"); err != nil {
+			return conflictOn(err, conditionValueConflict(&conditionValue))
+		}
+		if err := requireLive(tx, &database.Condition{}, conditionValue.ConditionID, "Condition"); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(conditionValue); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.db.Preload("Condition").First(&conditionValue, id).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 		return
 	}
+	h.events.Publish(watch.Added, utils.Namespace(r), conditionValue)
+
+	utils.SetETag(w, &conditionValue.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(conditionValue)
 }
 
-func (h *ConditionValueHandler) GetConditionValues(w http.ResponseWriter, r *http.Request) {
-	conditionValues := []database.ConditionValue{}
-	result := h.db.Preload("Condition").Find(&conditionValues)
+func (h *ConditionValueHandler) GetConditionValue(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		http.Error(w, "Invalid condition value ID: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conditionValue := database.ConditionValue{}
+	result := viaParent(h.db, r, "condition_id", &database.Condition{}).Preload("Condition").First(&conditionValue, id)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(conditionValues); err != nil {
+	utils.SetETag(w, &conditionValue.Model)
+	if err := json.NewEncoder(w).Encode(conditionValue); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
+func (h *ConditionValueHandler) GetConditionValues(w http.ResponseWriter, r *http.Request) {
+	h.listConditionValues(w, r, viaParent(h.db, r, "condition_id", &database.Condition{}), nil)
+}
+
 func (h *ConditionValueHandler) GetConditionValuesByCondition(w http.ResponseWriter, r *http.Request) {
 	conditionIDStr := chi.URLParam(r, "conditionID")
 	conditionID64, err := strconv.ParseUint(conditionIDStr, 10, 32)
@@ -179,15 +289,78 @@ func (h *ConditionValueHandler) GetConditionValuesByCondition(w http.ResponseWri
 	}
 	conditionID := uint(conditionID64)
 
-	conditionValues := []database.ConditionValue{}
-	result := h.db.Preload("Condition").Where("condition_id = ?", conditionID).Find(&conditionValues)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	watchCondition := func(object interface{}) bool {
+		return object.(database.ConditionValue).ConditionID == conditionID
+	}
+	h.listConditionValues(w, r, viaParent(h.db, r, "condition_id", &database.Condition{}).Where("condition_id = ?", conditionID), watchCondition)
+}
+
+// listConditionValues serves a list of the condition values selected by
+// query, or with ?watch=true the changes to those accepted by match.
+func (h *ConditionValueHandler) listConditionValues(w http.ResponseWriter, r *http.Request, query *gorm.DB, match func(object interface{}) bool) {
+	opts, err := utils.ParseListOptions(r, conditionValueListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(conditionValues); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if serveWatchIfRequested(w, r, h.events, "ConditionValue", utils.Namespace(r), match) {
+		return
+	}
+
+	version := listVersion(h.events)
+	conditionValues, meta, err := utils.Paginate[database.ConditionValue](query.Preload("Condition"), opts, conditionValueListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 		return
 	}
+	meta.ResourceVersion = version
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("ConditionValueList", conditionValues, meta))
+}
+
+// checkConditionValue parses the value according to the type of its
+// condition and rejects a value that is the same as an existing one written
+// differently, such as "298.15K" next to "25C".
+func checkConditionValue(tx *gorm.DB, conditionValue *database.ConditionValue, condition *database.Condition) error {
+	if err := values.Apply(conditionValue, condition); err != nil {
+		return err
+	}
+	existing, err := findTypedValue(tx, conditionValue)
+	if err != nil || existing == nil {
+		return err
+	}
+	return &conflictError{detail: fmt.Sprintf("Value %q is the same as the existing value %q of condition %d", conditionValue.Value, existing.Value, conditionValue.ConditionID)}
+}
+
+// findTypedValue returns another live value of the condition with the same
+// typed value, or nil. Untyped values are only matched by their string.
+func findTypedValue(tx *gorm.DB, conditionValue *database.ConditionValue) (*database.ConditionValue, error) {
+	query := tx.Where("condition_id = ? AND id <> ?", conditionValue.ConditionID, conditionValue.ID)
+	switch {
+	case conditionValue.Boolean != nil:
+		query = query.Where("boolean = ?", *conditionValue.Boolean)
+	case conditionValue.Number != nil && conditionValue.NumberMax != nil:
+		query = query.Where("number = ? AND number_max = ?", *conditionValue.Number, *conditionValue.NumberMax)
+	case conditionValue.Number != nil:
+		query = query.Where("number = ? AND number_max IS NULL", *conditionValue.Number)
+	default:
+		return nil, nil
+	}
+
+	existing := database.ConditionValue{}
+	err := query.First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func conditionValueConflict(conditionValue *database.ConditionValue) string {
+	return fmt.Sprintf("Value %q already exists for condition %d", conditionValue.Value, conditionValue.ConditionID)
 }
@@ -3,25 +3,36 @@ package handlers
 import (
 	"database"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"types"
 
 	"config/utils"
+	"config/watch"
 
 	apierrors "github.com/neuro-lab/errors"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 )
 
 var validate = validator.New()
 
+var deviceListSpec = utils.ListSpec{
+	NameColumn: "name",
+	SortFields: []string{"name", "updated_at"},
+	Labels:     true,
+}
+
 type DeviceHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *watch.Broker
 }
 
-func NewDeviceHandler(db *gorm.DB) *DeviceHandler {
-	return &DeviceHandler{db: db}
+func NewDeviceHandler(db *gorm.DB, events *watch.Broker) *DeviceHandler {
+	return &DeviceHandler{db: db, events: events}
 }
 
 func (h *DeviceHandler) CreateDevice(w http.ResponseWriter, r *http.Request) {
@@ -36,15 +47,24 @@ func (h *DeviceHandler) CreateDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
 	device := database.Device{
-		Name: req.Name,
+		Namespace: utils.Namespace(r),
+		Name:      req.Name,
+		Metadata:  metadata,
 	}
 
 	result := h.db.Create(&device)
 	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		writeDatabaseError(w, r, conflictOn(result.Error, deviceConflict(&device)))
 		return
 	}
+	h.events.Publish(watch.Added, device.Namespace, device)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
@@ -70,47 +90,130 @@ func (h *DeviceHandler) UpdateDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	device := database.Device{
-		Name: req.Name,
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
 	}
-	device.ID = id
 
-	result := h.db.Save(&device)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	device := database.Device{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &device, &device.Model, id); err != nil {
+			return err
+		}
+		device.Name = req.Name
+		device.Metadata = metadata
+		return conflictOn(tx.Save(&device).Error, deviceConflict(&device))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Modified, device.Namespace, device)
 
+	utils.SetETag(w, &device.Model)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(device)
 }
 
-func (h *DeviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+// PatchDevice applies a JSON merge patch (RFC 7396) to a device. Only the
+// fields present in the patch are changed.
+func (h *DeviceHandler) PatchDevice(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid device ID: "+err.Error(), r.URL.Path))
 		return
 	}
 
-	// First check if the device exists
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
 	device := database.Device{}
-	result := h.db.First(&device, id)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &device, &device.Model, id); err != nil {
+			return err
+		}
+		req := types.UpdateDeviceRequest{ID: id, Name: device.Name, Metadata: requestMetadata(device.Metadata)}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		metadata, err := metadataOf(req.Metadata)
+		if err != nil {
+			return err
+		}
+		device.Name = req.Name
+		device.Metadata = metadata
+		return conflictOn(tx.Save(&device).Error, deviceConflict(&device))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Modified, device.Namespace, device)
 
-	// Delete the device
-	result = h.db.Delete(&device, id)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	utils.SetETag(w, &device.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(device)
+}
+
+func (h *DeviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid device ID: "+err.Error(), r.URL.Path))
 		return
 	}
 
+	device := database.Device{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &device, &device.Model, id); err != nil {
+			return err
+		}
+		return tx.Delete(&device).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Deleted, device.Namespace, device)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreDevice undeletes a soft-deleted device.
+func (h *DeviceHandler) RestoreDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid device ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	device := database.Device{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := restoreRow(namespaced(tx, r), &device, &device.Model, id, "Device"); err != nil {
+			return conflictOn(err, deviceConflict(&device))
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Added, device.Namespace, device)
+
+	utils.SetETag(w, &device.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(device)
+}
+
 func (h *DeviceHandler) GetDevice(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
@@ -119,26 +222,63 @@ func (h *DeviceHandler) GetDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	device := database.Device{}
-	result := h.db.First(&device, id)
+	result := namespaced(h.db, r).First(&device, id)
 	if result.Error != nil {
 		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
 		return
 	}
 
+	utils.SetETag(w, &device.Model)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(device)
 }
 
-func (h *DeviceHandler) GetDevices(w http.ResponseWriter, r *http.Request) {
-	devices := []database.Device{}
-	result := h.db.Find(&devices)
+// GetDeviceByName returns the device with the given name.
+func (h *DeviceHandler) GetDeviceByName(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	device := database.Device{}
+	result := namespaced(h.db, r).Where("name = ?", name).First(&device)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		apierrors.WriteError(w, apierrors.NewNotFoundError(fmt.Sprintf("device %q", name), r.URL.Path))
+		return
+	}
 	if result.Error != nil {
 		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
 		return
 	}
 
+	utils.SetETag(w, &device.Model)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(devices)
+	json.NewEncoder(w).Encode(device)
+}
+
+func (h *DeviceHandler) GetDevices(w http.ResponseWriter, r *http.Request) {
+	opts, err := utils.ParseListOptions(r, deviceListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	if serveWatchIfRequested(w, r, h.events, "Device", utils.Namespace(r), selecting(opts, nil)) {
+		return
+	}
+
+	version := listVersion(h.events)
+	devices, meta, err := utils.Paginate[database.Device](namespaced(h.db, r), opts, deviceListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	meta.ResourceVersion = version
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("DeviceList", devices, meta))
+}
+
+func deviceConflict(device *database.Device) string {
+	return fmt.Sprintf("Device %q already exists in namespace %q", device.Name, device.Namespace)
 }
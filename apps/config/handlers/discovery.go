@@ -8,12 +8,15 @@ import (
 	"gorm.io/gorm"
 )
 
+// DiscoveryHandler serves the resource list describing the API. The list is
+// derived from the route registry by the server.
 type DiscoveryHandler struct {
-	db *gorm.DB
+	db        *gorm.DB
+	resources []types.APIResource
 }
 
-func NewDiscoveryHandler(db *gorm.DB) *DiscoveryHandler {
-	return &DiscoveryHandler{db: db}
+func NewDiscoveryHandler(db *gorm.DB, resources []types.APIResource) *DiscoveryHandler {
+	return &DiscoveryHandler{db: db, resources: resources}
 }
 
 func (h *DiscoveryHandler) GetAPIResources(w http.ResponseWriter, r *http.Request) {
@@ -21,52 +24,10 @@ func (h *DiscoveryHandler) GetAPIResources(w http.ResponseWriter, r *http.Reques
 		Kind:         "APIResourceList",
 		APIVersion:   "v1",
 		GroupVersion: "v1",
-		Resources: []types.APIResource{
-			{
-				Name:         "devices",
-				SingularName: "device",
-				Kind:         "Device",
-				Verbs:        []string{"create", "get", "list", "update", "delete"},
-				ShortNames:   []string{},
-			},
-			{
-				Name:         "test-sessions",
-				SingularName: "test-session",
-				Kind:         "TestSession",
-				Verbs:        []string{"create", "get", "list", "update", "delete"},
-				ShortNames:   []string{},
-			},
-			{
-				Name:         "conditions",
-				SingularName: "condition",
-				Kind:         "Condition",
-				Verbs:        []string{"create", "get", "list", "update", "delete"},
-				ShortNames:   []string{},
-			},
-			{
-				Name:         "condition-values",
-				SingularName: "condition-value",
-				Kind:         "ConditionValue",
-				Verbs:        []string{"create", "get", "list", "update", "delete"},
-				ShortNames:   []string{},
-			},
-			{
-				Name:         "scenarios",
-				SingularName: "scenario",
-				Kind:         "Scenario",
-				Verbs:        []string{"create", "get", "list", "update", "delete"},
-				ShortNames:   []string{},
-			},
-			{
-				Name:         "scenario-conditions",
-				SingularName: "scenario-condition",
-				Kind:         "ScenarioCondition",
-				Verbs:        []string{"create", "get", "update", "delete"},
-				ShortNames:   []string{},
-			},
-		},
+		Resources:    h.resources,
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resourceList)
 }
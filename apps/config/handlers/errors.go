@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"config/labels"
+	"config/lifecycle"
+	"config/utils"
+	"config/values"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// conflictError is a unique constraint violation together with a message
+// naming the resource that already exists.
+type conflictError struct {
+	detail string
+	err    error
+}
+
+func (e *conflictError) Error() string {
+	return e.detail
+}
+
+func (e *conflictError) Unwrap() error {
+	return e.err
+}
+
+// conflictOn wraps err in a conflictError with the given detail when it is a
+// unique constraint violation, and returns it unchanged otherwise.
+func conflictOn(err error, detail string) error {
+	if apierrors.IsDuplicateKeyError(err) {
+		return &conflictError{detail: detail, err: err}
+	}
+	return err
+}
+
+// writeDatabaseError writes err as a 409 when it was wrapped by conflictOn,
+// as a 412 when an If-Match precondition failed, as a 400 for invalid patches
+// and failed validation, as a 422 for condition values that do not fit their
+// type and for invalid labels, as a 409 when a move would leave a device with
+// two active scenarios, and as the matching database error otherwise.
+func writeDatabaseError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, utils.ErrInvalidPatch) {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+	var invalidValue *values.Error
+	if errors.As(err, &invalidValue) {
+		apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(invalidValue.Detail, r.URL.Path))
+		return
+	}
+	var invalidLabel *labels.Error
+	if errors.As(err, &invalidLabel) {
+		apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(invalidLabel.Detail, r.URL.Path))
+		return
+	}
+	var conflict *conflictError
+	if errors.As(err, &conflict) {
+		apierrors.WriteError(w, apierrors.NewConflictError(conflict.detail, r.URL.Path))
+		return
+	}
+	var active *lifecycle.ActiveScenarioError
+	if errors.As(err, &active) {
+		writeTransitionError(w, r, err)
+		return
+	}
+	var precondition *preconditionError
+	if errors.As(err, &precondition) {
+		errResp := apierrors.NewPreconditionFailedError("The resource was modified since it was last read", r.URL.Path)
+		errResp.Meta = map[string]interface{}{"etag": precondition.etag}
+		apierrors.WriteError(w, errResp)
+		return
+	}
+	apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+}
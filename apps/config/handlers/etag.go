@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"config/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// preconditionError is returned when the If-Match header of a request does
+// not match the current ETag of the row it modifies.
+type preconditionError struct {
+	etag string
+}
+
+func (e *preconditionError) Error() string {
+	return fmt.Sprintf("resource has been modified, current ETag is %s", e.etag)
+}
+
+// lockForWrite loads the row with the given id into dest, locking it until the
+// transaction ends, and checks the request's If-Match header against it.
+// model must point at the gorm.Model embedded in dest.
+func lockForWrite(tx *gorm.DB, r *http.Request, dest interface{}, model *gorm.Model, id uint) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(dest, id).Error; err != nil {
+		return err
+	}
+	if etag := utils.ETag(model); !utils.IfMatch(r, etag) {
+		return &preconditionError{etag: etag}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"config/utils"
+	"config/webhook"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -25,13 +26,21 @@ type NotificationMessage struct {
 	ScenarioID uint `json:"scenario_id"`
 }
 
+// ExportFinished is the data of the export.finished webhook event.
+type ExportFinished struct {
+	ScenarioID uint            `json:"scenario_id"`
+	Path       string          `json:"path"`
+	Labels     database.Labels `json:"labels,omitempty"`
+}
+
 type ExportHandler struct {
 	db    *gorm.DB
 	kafka *kafka.Conn
+	hooks *webhook.Dispatcher
 }
 
-func NewExportHandler(kafka *kafka.Conn, db *gorm.DB) *ExportHandler {
-	return &ExportHandler{kafka: kafka, db: db}
+func NewExportHandler(kafka *kafka.Conn, db *gorm.DB, hooks *webhook.Dispatcher) *ExportHandler {
+	return &ExportHandler{kafka: kafka, db: db, hooks: hooks}
 }
 
 func (h *ExportHandler) sendToKafka(notification NotificationMessage) error {
@@ -65,18 +74,34 @@ func (h *ExportHandler) ExportData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.exportDataToCSV(data, 1, scenarioID)
+	labels, err := database.ScenarioLabels(h.db, scenarioID)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	path, err := h.exportDataToCSV(data, labels, 1, scenarioID)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NewInternalError(r.URL.Path))
 		return
 	}
 
+	// The export route is not namespaced; the event goes to the webhooks of
+	// the namespace the scenario belongs to.
+	scenario := database.Scenario{}
+	if err := h.db.First(&scenario, scenarioID).Error; err == nil {
+		h.hooks.Notify(scenario.Namespace, webhook.ExportFinished, ExportFinished{ScenarioID: scenarioID, Path: path, Labels: labels})
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *ExportHandler) exportDataToCSV(data []database.ProcessedChannel, deviceID, scenarioID uint) error {
+// exportDataToCSV writes the samples of a scenario to a CSV file and returns
+// its path. Every label becomes a "label.<key>" column, so that the files of
+// several scenarios can be concatenated and grouped.
+func (h *ExportHandler) exportDataToCSV(data []database.ProcessedChannel, labels database.Labels, deviceID, scenarioID uint) (string, error) {
 	if len(data) == 0 {
-		return fmt.Errorf("no samples to export")
+		return "", fmt.Errorf("no samples to export")
 	}
 
 	outputDir := "./exports"
@@ -87,7 +112,7 @@ func (h *ExportHandler) exportDataToCSV(data []database.ProcessedChannel, device
 	)
 
 	if err := os.MkdirAll(partitionPath, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return "", fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Group data by FrameID
@@ -103,7 +128,7 @@ func (h *ExportHandler) exportDataToCSV(data []database.ProcessedChannel, device
 	outputPath := filepath.Join(partitionPath, "data.csv")
 	file, err := os.Create(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return "", fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer file.Close()
 
@@ -111,9 +136,17 @@ func (h *ExportHandler) exportDataToCSV(data []database.ProcessedChannel, device
 	defer writer.Flush()
 
 	// Write header
+	labelKeys := make([]string, 0, len(labels))
+	for key := range labels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
 	header := append([]string{"frame_id"}, channelNames...)
+	for _, key := range labelKeys {
+		header = append(header, "label."+key)
+	}
 	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
+		return "", fmt.Errorf("failed to write header: %w", err)
 	}
 
 	// Sort frame IDs to ensure consistent ordering
@@ -146,11 +179,14 @@ func (h *ExportHandler) exportDataToCSV(data []database.ProcessedChannel, device
 					row = append(row, "")
 				}
 			}
+			for _, key := range labelKeys {
+				row = append(row, labels[key])
+			}
 			if err := writer.Write(row); err != nil {
-				return fmt.Errorf("failed to write row: %w", err)
+				return "", fmt.Errorf("failed to write row: %w", err)
 			}
 		}
 	}
 
-	return nil
+	return outputPath, nil
 }
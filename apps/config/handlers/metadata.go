@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database"
+	"types"
+
+	"config/labels"
+	"config/utils"
+)
+
+// metadataOf checks the labels and annotations of a request and returns the
+// metadata to store. Invalid ones yield a *labels.Error.
+func metadataOf(req types.Metadata) (database.Metadata, error) {
+	if err := labels.Validate(req.Labels, req.Annotations); err != nil {
+		return database.Metadata{}, err
+	}
+	return database.Metadata{
+		Description: req.Description,
+		Labels:      req.Labels,
+		Annotations: req.Annotations,
+	}, nil
+}
+
+// requestMetadata returns stored metadata as it appears in a request, to
+// apply merge patches to.
+func requestMetadata(metadata database.Metadata) types.Metadata {
+	return types.Metadata{
+		Description: metadata.Description,
+		Labels:      metadata.Labels,
+		Annotations: metadata.Annotations,
+	}
+}
+
+// selecting narrows match to the objects whose labels pass the label
+// selector of opts; match may be nil.
+func selecting(opts utils.ListOptions, match func(object interface{}) bool) func(object interface{}) bool {
+	if len(opts.LabelSelector) == 0 {
+		return match
+	}
+	return func(object interface{}) bool {
+		labelled, ok := object.(interface{ GetLabels() database.Labels })
+		if !ok || !opts.LabelSelector.Matches(labelled.GetLabels()) {
+			return false
+		}
+		return match == nil || match(object)
+	}
+}
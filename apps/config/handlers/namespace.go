@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"types"
+
+	"config/utils"
+	"config/watch"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var namespaceListSpec = utils.ListSpec{
+	NameColumn: "name",
+	SortFields: []string{"name", "updated_at"},
+}
+
+type NamespaceHandler struct {
+	db     *gorm.DB
+	events *watch.Broker
+}
+
+func NewNamespaceHandler(db *gorm.DB, events *watch.Broker) *NamespaceHandler {
+	return &NamespaceHandler{db: db, events: events}
+}
+
+// RequireNamespace rejects requests to a namespace that does not exist.
+func (h *NamespaceHandler) RequireNamespace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := chi.URLParam(r, "ns")
+		result := h.db.Where("name = ?", ns).First(&database.Namespace{})
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			apierrors.WriteError(w, apierrors.NewNotFoundError(fmt.Sprintf("namespace %q", ns), r.URL.Path))
+			return
+		}
+		if result.Error != nil {
+			apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *NamespaceHandler) CreateNamespace(w http.ResponseWriter, r *http.Request) {
+	var req types.CreateNamespaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	namespace := database.Namespace{
+		Name: req.Name,
+	}
+
+	result := h.db.Create(&namespace)
+	if result.Error != nil {
+		writeDatabaseError(w, r, conflictOn(result.Error, fmt.Sprintf("Namespace %q already exists", namespace.Name)))
+		return
+	}
+	h.events.Publish(watch.Added, "", namespace)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(namespace)
+}
+
+func (h *NamespaceHandler) GetNamespace(w http.ResponseWriter, r *http.Request) {
+	namespace := database.Namespace{}
+	result := h.db.Where("name = ?", chi.URLParam(r, "ns")).First(&namespace)
+	if result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	utils.SetETag(w, &namespace.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(namespace)
+}
+
+func (h *NamespaceHandler) GetNamespaces(w http.ResponseWriter, r *http.Request) {
+	opts, err := utils.ParseListOptions(r, namespaceListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	if serveWatchIfRequested(w, r, h.events, "Namespace", "", nil) {
+		return
+	}
+
+	version := listVersion(h.events)
+	namespaces, meta, err := utils.Paginate[database.Namespace](h.db, opts, namespaceListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	meta.ResourceVersion = version
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("NamespaceList", namespaces, meta))
+}
+
+// DeleteNamespace deletes an empty namespace. The default namespace and
+// namespaces that still hold devices or conditions are kept.
+func (h *NamespaceHandler) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "ns")
+	if name == database.DefaultNamespace {
+		apierrors.WriteError(w, apierrors.NewConflictError("The default namespace cannot be deleted", r.URL.Path))
+		return
+	}
+
+	namespace := database.Namespace{}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&namespace).Error
+		if err != nil {
+			return err
+		}
+		if etag := utils.ETag(&namespace.Model); !utils.IfMatch(r, etag) {
+			return &preconditionError{etag: etag}
+		}
+
+		for _, model := range []interface{}{&database.Device{}, &database.Condition{}} {
+			var count int64
+			if err := tx.Model(model).Where("namespace = ?", name).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return &conflictError{detail: fmt.Sprintf("Namespace %q is not empty, delete its devices and conditions first", name)}
+			}
+		}
+		return tx.Delete(&namespace).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Deleted, "", namespace)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// namespaced restricts db to the rows of a namespaced model that belong to the
+// namespace of the request. The result can be reused for several queries.
+func namespaced(db *gorm.DB, r *http.Request) *gorm.DB {
+	return db.Scopes(utils.InNamespace(r)).Session(&gorm.Session{})
+}
+
+// viaParent restricts db to the rows of a model without a namespace of its
+// own whose parent, referenced by column, belongs to the namespace of the
+// request. The result can be reused for several queries.
+func viaParent(db *gorm.DB, r *http.Request, column string, parent interface{}) *gorm.DB {
+	parents := db.Session(&gorm.Session{NewDB: true}).Unscoped().
+		Model(parent).
+		Select("id").
+		Where("namespace = ?", utils.Namespace(r))
+	ref := clause.Column{Table: clause.CurrentTable, Name: column}
+	return db.Where(clause.Expr{SQL: "? IN (?)", Vars: []interface{}{ref, parents}}).Session(&gorm.Session{})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	apierrors "github.com/neuro-lab/errors"
+)
+
+// readMergePatch reads the body of a PATCH request. Merge patches
+// (application/merge-patch+json) and plain JSON bodies are accepted.
+func readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			apierrors.WriteError(w, apierrors.NewErrorResponse(
+				http.StatusUnsupportedMediaType,
+				apierrors.TypeBadRequest,
+				"Unsupported Media Type",
+				"PATCH requests must be sent as application/merge-patch+json",
+				r.URL.Path,
+			))
+			return nil, false
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return nil, false
+	}
+	return patch, true
+}
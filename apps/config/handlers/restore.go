@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// restoreRow undeletes the soft-deleted row with the given id and keeps it
+// locked until the transaction ends. model must point at the gorm.Model
+// embedded in dest. It returns the time the row had been deleted at.
+func restoreRow(tx *gorm.DB, dest interface{}, model *gorm.Model, id uint, label string) (time.Time, error) {
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(dest, id).Error; err != nil {
+		return time.Time{}, err
+	}
+	if !model.DeletedAt.Valid {
+		return time.Time{}, &conflictError{detail: fmt.Sprintf("%s %d is not deleted", label, id)}
+	}
+	deletedAt := model.DeletedAt.Time
+	if err := tx.Unscoped().Model(dest).Update("deleted_at", nil).Error; err != nil {
+		return time.Time{}, err
+	}
+	model.DeletedAt = gorm.DeletedAt{}
+	return deletedAt, nil
+}
+
+// requireLive checks that the row a restored resource belongs to has not been
+// deleted itself.
+func requireLive(tx *gorm.DB, parent interface{}, id uint, label string) error {
+	err := tx.First(parent, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &conflictError{detail: fmt.Sprintf("%s %d is deleted, restore it first", label, id)}
+	}
+	return err
+}
+
+// boolParam reads an optional boolean query parameter.
+func boolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+	return parsed, nil
+}
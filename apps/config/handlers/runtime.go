@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"types"
+
+	"config/lifecycle"
+	"config/utils"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+type RuntimeHandler struct {
+	db *gorm.DB
+}
+
+func NewRuntimeHandler(db *gorm.DB) *RuntimeHandler {
+	return &RuntimeHandler{db: db}
+}
+
+// GetTestSessionRuntime returns the runtime state of a test session: every
+// scenario with its condition values, the conditions involved and the
+// session progress.
+func (h *RuntimeHandler) GetTestSessionRuntime(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	testSession := database.TestSession{}
+	if result := namespaced(h.db, r).First(&testSession, id); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	runtime, err := h.buildTestSessionRuntime(&testSession)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(runtime)
+}
+
+// GetDeviceRuntime returns the simplified runtime configuration of a device.
+// The session can be chosen with ?test_session_id=; otherwise the session
+// holding the device's active scenario is used, falling back to the most
+// recently created one.
+func (h *RuntimeHandler) GetDeviceRuntime(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid device ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	device := database.Device{}
+	if result := namespaced(h.db, r).First(&device, id); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	testSession := database.TestSession{}
+	if value := r.URL.Query().Get("test_session_id"); value != "" {
+		testSessionID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+			return
+		}
+		err = h.db.Where("device_id = ?", device.ID).First(&testSession, uint(testSessionID)).Error
+		if err != nil {
+			apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+			return
+		}
+	} else if err := h.currentTestSession(device.ID, &testSession); err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	runtime, err := h.buildTestSessionRuntime(&testSession)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	conditionNames := map[uint]string{}
+	for _, condition := range runtime.Spec.Conditions {
+		conditionNames[condition.ID] = condition.Name
+	}
+
+	config := types.DeviceRuntimeConfig{
+		SessionID:  testSession.ID,
+		DeviceID:   device.ID,
+		DeviceName: device.Name,
+		Scenarios:  make([]types.DeviceRuntimeScenario, 0, len(runtime.Spec.Scenarios)),
+	}
+	for _, scenario := range runtime.Spec.Scenarios {
+		conditions := map[string]types.RuntimeValue{}
+		for _, conditionValue := range scenario.ConditionValues {
+			conditions[conditionNames[conditionValue.ConditionID]] = types.RuntimeValue{
+				ID:    conditionValue.ValueID,
+				Value: conditionValue.Value,
+			}
+		}
+		config.Scenarios = append(config.Scenarios, types.DeviceRuntimeScenario{
+			ID:         scenario.ID,
+			Name:       scenario.Name,
+			Position:   scenario.Position,
+			Status:     scenario.Status,
+			Conditions: conditions,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(config)
+}
+
+// currentTestSession loads the test session holding the device's active
+// scenario, or the most recently created session of the device.
+func (h *RuntimeHandler) currentTestSession(deviceID uint, testSession *database.TestSession) error {
+	err := h.db.
+		Joins("JOIN scenarios ON scenarios.test_session_id = test_sessions.id AND scenarios.deleted_at IS NULL").
+		Where("test_sessions.device_id = ? AND scenarios.status = ?", deviceID, database.StatusActive).
+		First(testSession).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return h.db.Where("device_id = ?", deviceID).Order("created_at DESC, id DESC").First(testSession).Error
+}
+
+func (h *RuntimeHandler) buildTestSessionRuntime(testSession *database.TestSession) (*types.TestSessionRuntime, error) {
+	scenarios := []database.Scenario{}
+	err := h.db.Preload("ScenarioConditions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("ScenarioConditions.ConditionValue").
+		Where("test_session_id = ?", testSession.ID).
+		Order("position, id").
+		Find(&scenarios).Error
+	if err != nil {
+		return nil, err
+	}
+
+	runtime := &types.TestSessionRuntime{
+		APIVersion: "neurolab.io/v1",
+		Kind:       "TestSession",
+		Metadata: types.TestSessionRuntimeMetadata{
+			ID:       testSession.ID,
+			Name:     testSession.Name,
+			Created:  testSession.CreatedAt,
+			DeviceID: testSession.DeviceID,
+		},
+		Spec: types.TestSessionRuntimeSpec{
+			Conditions: []types.RuntimeCondition{},
+			Scenarios:  make([]types.RuntimeScenario, 0, len(scenarios)),
+		},
+		Status: types.TestSessionRuntimeStatus{
+			Phase:              types.RuntimePhaseReady,
+			CompletedScenarios: []uint{},
+		},
+	}
+
+	conditionIDs := []uint{}
+	seenConditions := map[uint]bool{}
+	scenarioIDs := make([]uint, 0, len(scenarios))
+	terminal := 0
+	for _, scenario := range scenarios {
+		scenarioIDs = append(scenarioIDs, scenario.ID)
+
+		runtimeScenario := types.RuntimeScenario{
+			ID:              scenario.ID,
+			Name:            scenario.Name,
+			Position:        scenario.Position,
+			Status:          string(lifecycle.CurrentStatus(&scenario)),
+			ConditionValues: make([]types.RuntimeConditionValue, 0, len(scenario.ScenarioConditions)),
+		}
+		for _, scenarioCondition := range scenario.ScenarioConditions {
+			conditionValue := scenarioCondition.ConditionValue
+			if conditionValue == nil {
+				continue
+			}
+			runtimeScenario.ConditionValues = append(runtimeScenario.ConditionValues, types.RuntimeConditionValue{
+				ConditionID: conditionValue.ConditionID,
+				ValueID:     conditionValue.ID,
+				Value:       conditionValue.Value,
+			})
+			if !seenConditions[conditionValue.ConditionID] {
+				seenConditions[conditionValue.ConditionID] = true
+				conditionIDs = append(conditionIDs, conditionValue.ConditionID)
+			}
+		}
+		runtime.Spec.Scenarios = append(runtime.Spec.Scenarios, runtimeScenario)
+
+		switch scenario.Status {
+		case database.StatusActive:
+			activeID := scenario.ID
+			runtime.Status.ActiveScenario = &activeID
+		case database.StatusCompleted:
+			runtime.Status.CompletedScenarios = append(runtime.Status.CompletedScenarios, scenario.ID)
+			terminal++
+		case database.StatusAborted:
+			terminal++
+		}
+	}
+
+	switch {
+	case runtime.Status.ActiveScenario != nil:
+		runtime.Status.Phase = types.RuntimePhaseRunning
+	case len(scenarios) > 0 && terminal == len(scenarios):
+		runtime.Status.Phase = types.RuntimePhaseCompleted
+	}
+
+	if len(scenarioIDs) > 0 {
+		started := database.ScenarioTransition{}
+		err := h.db.Where("scenario_id IN ? AND to_status = ?", scenarioIDs, database.StatusActive).
+			Order("occurred_at ASC").
+			First(&started).Error
+		if err == nil {
+			runtime.Status.StartTime = &started.OccurredAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if len(conditionIDs) > 0 {
+		conditions := []database.Condition{}
+		if err := h.db.Where("id IN ?", conditionIDs).Order("id").Find(&conditions).Error; err != nil {
+			return nil, err
+		}
+		conditionValues := []database.ConditionValue{}
+		if err := h.db.Where("condition_id IN ?", conditionIDs).Order("id").Find(&conditionValues).Error; err != nil {
+			return nil, err
+		}
+
+		valuesByCondition := map[uint][]types.RuntimeValue{}
+		for _, conditionValue := range conditionValues {
+			valuesByCondition[conditionValue.ConditionID] = append(valuesByCondition[conditionValue.ConditionID], types.RuntimeValue{
+				ID:    conditionValue.ID,
+				Value: conditionValue.Value,
+			})
+		}
+		for _, condition := range conditions {
+			values := valuesByCondition[condition.ID]
+			if values == nil {
+				values = []types.RuntimeValue{}
+			}
+			runtime.Spec.Conditions = append(runtime.Spec.Conditions, types.RuntimeCondition{
+				ID:     condition.ID,
+				Name:   condition.Name,
+				Values: values,
+			})
+		}
+	}
+
+	return runtime, nil
+}
@@ -3,11 +3,20 @@ package handlers
 import (
 	"database"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"types"
 
+	"config/acquisition"
+	"config/lifecycle"
 	"config/utils"
+	"config/values"
+	"config/watch"
+	"config/webhook"
 	"math/rand"
 
 	"github.com/go-chi/chi/v5"
@@ -35,12 +44,30 @@ func init() {
 	println("Scenario counter initialized")
 }
 
+var scenarioListSpec = utils.ListSpec{
+	NameColumn: "name",
+	SortFields: []string{"name", "status", "position", "updated_at"},
+	Status:     true,
+	Labels:     true,
+}
+
 type ScenarioHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *watch.Broker
+	hooks  *webhook.Dispatcher
+}
+
+func NewScenarioHandler(db *gorm.DB, events *watch.Broker, hooks *webhook.Dispatcher) *ScenarioHandler {
+	return &ScenarioHandler{db: db, events: events, hooks: hooks}
 }
 
-func NewScenarioHandler(db *gorm.DB) *ScenarioHandler {
-	return &ScenarioHandler{db: db}
+// transitionEvents maps the status a scenario moves to onto the webhook event
+// announcing it.
+var transitionEvents = map[database.Status]string{
+	database.StatusActive:    webhook.ScenarioActivated,
+	database.StatusInactive:  webhook.ScenarioDeactivated,
+	database.StatusCompleted: webhook.ScenarioCompleted,
+	database.StatusAborted:   webhook.ScenarioAborted,
 }
 
 func (h *ScenarioHandler) CreateScenario(w http.ResponseWriter, r *http.Request) {
@@ -56,21 +83,32 @@ func (h *ScenarioHandler) CreateScenario(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
 	// Validate that the test session exists
 	testSession := database.TestSession{}
-	if result := h.db.First(&testSession, req.TestSessionID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&testSession, req.TestSessionID); result.Error != nil {
 		http.Error(w, "TestSession does not exist", http.StatusBadRequest)
 		return
 	}
 
 	scenario := database.Scenario{
+		Namespace:     testSession.Namespace,
 		Name:          req.Name,
 		TestSessionID: req.TestSessionID,
+		Target:        targetOf(req.Target),
+		Metadata:      metadata,
 	}
 
-	result := h.db.Create(&scenario)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		return createScenarioWithConditionValues(tx, &scenario, nil)
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
@@ -79,8 +117,10 @@ func (h *ScenarioHandler) CreateScenario(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Error loading test session: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.events.Publish(watch.Added, scenario.Namespace, scenario)
 
 	scenarioCnt.Add(r.Context(), int64(rand.Intn(6)), metric.WithAttributes(attribute.String("test_session_id", strconv.FormatUint(uint64(req.TestSessionID), 10)), attribute.String("scenario_id", strconv.FormatUint(uint64(scenario.ID), 10))))
+
 	w.WriteHeader(http.StatusCreated)
 
 	if err := json.NewEncoder(w).Encode(scenario); err != nil {
@@ -102,51 +142,47 @@ func (h *ScenarioHandler) CreateScenarioWithConditionValues(w http.ResponseWrite
 		return
 	}
 
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
 	// Validate that the test session exists
 	testSession := database.TestSession{}
-	if result := h.db.First(&testSession, req.TestSessionID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&testSession, req.TestSessionID); result.Error != nil {
 		http.Error(w, "TestSession does not exist", http.StatusBadRequest)
 		return
 	}
 
-	// Validate that the condition values exist
+	// Validate that the condition values exist in the same namespace
+	conditionValueIDs := slices.Clone(req.ConditionValueIDs)
+	slices.Sort(conditionValueIDs)
+	conditionValueIDs = slices.Compact(conditionValueIDs)
 	conditionValues := []database.ConditionValue{}
-	if result := h.db.First(&conditionValues, req.ConditionValueIDs); result.Error != nil {
+	result := viaParent(h.db, r, "condition_id", &database.Condition{}).Find(&conditionValues, conditionValueIDs)
+	if result.Error != nil || len(conditionValues) != len(conditionValueIDs) {
 		http.Error(w, "ConditionValues do not exist", http.StatusBadRequest)
 		return
 	}
 
 	scenario := database.Scenario{
+		Namespace:     testSession.Namespace,
 		Name:          req.Name,
 		TestSessionID: req.TestSessionID,
+		Target:        targetOf(req.Target),
+		Metadata:      metadata,
 	}
 
 	transactionResult := h.db.Transaction(func(tx *gorm.DB) error {
-		result := tx.Create(&scenario)
-		if result.Error != nil {
-			return result.Error
-		}
-
-		scenarioConditions := []database.ScenarioCondition{}
-		for _, conditionValueID := range req.ConditionValueIDs {
-			scenarioConditions = append(scenarioConditions, database.ScenarioCondition{
-				ScenarioID:       scenario.ID,
-				ConditionValueID: conditionValueID,
-			})
-		}
-
-		result = tx.Create(&scenarioConditions)
-		if result.Error != nil {
-			return result.Error
-		}
-
-		return nil
+		return createScenarioWithConditionValues(tx, &scenario, req.ConditionValueIDs)
 	})
 
 	if transactionResult != nil {
-		http.Error(w, transactionResult.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, r, transactionResult)
 		return
 	}
+	h.events.Publish(watch.Added, scenario.Namespace, scenario)
 
 	w.WriteHeader(http.StatusCreated)
 
@@ -156,6 +192,111 @@ func (h *ScenarioHandler) CreateScenarioWithConditionValues(w http.ResponseWrite
 	}
 }
 
+// createScenarioWithConditionValues creates the scenario last in its test
+// session and links it to the given condition values. It must be called
+// inside a transaction.
+func createScenarioWithConditionValues(tx *gorm.DB, scenario *database.Scenario, conditionValueIDs []uint) error {
+	position, err := nextPosition(tx, scenario.TestSessionID)
+	if err != nil {
+		return err
+	}
+	scenario.Position = position
+	if err := tx.Create(scenario).Error; err != nil {
+		return conflictOn(err, scenarioConflict(scenario))
+	}
+
+	if len(conditionValueIDs) == 0 {
+		return nil
+	}
+
+	scenarioConditions := []database.ScenarioCondition{}
+	for _, conditionValueID := range conditionValueIDs {
+		scenarioConditions = append(scenarioConditions, database.ScenarioCondition{
+			ScenarioID:       scenario.ID,
+			ConditionValueID: conditionValueID,
+		})
+	}
+
+	return tx.Create(&scenarioConditions).Error
+}
+
+// nextPosition returns the position that puts a scenario last in the test
+// session.
+func nextPosition(tx *gorm.DB, testSessionID uint) (int, error) {
+	var last int
+	err := tx.Model(&database.Scenario{}).
+		Where("test_session_id = ?", testSessionID).
+		Select("COALESCE(MAX(position), 0)").
+		Scan(&last).Error
+	return last + 1, err
+}
+
+// moveScenario puts a scenario in another test session, last in its order.
+// An active scenario is only moved to a device without an active scenario.
+func moveScenario(tx *gorm.DB, scenario *database.Scenario, testSessionID uint) error {
+	if scenario.TestSessionID == testSessionID {
+		return nil
+	}
+	testSession := database.TestSession{}
+	if err := tx.First(&testSession, testSessionID).Error; err != nil {
+		return err
+	}
+	if err := lifecycle.Join(tx, testSession.DeviceID, []database.Scenario{*scenario}); err != nil {
+		return err
+	}
+	position, err := nextPosition(tx, testSessionID)
+	if err != nil {
+		return err
+	}
+	scenario.TestSessionID = testSessionID
+	scenario.Position = position
+	return nil
+}
+
+func scenarioConflict(scenario *database.Scenario) string {
+	return fmt.Sprintf("Scenario %q already exists in test session %d", scenario.Name, scenario.TestSessionID)
+}
+
+// targetOf converts the target of a request. The action defaults to
+// completing the scenario.
+func targetOf(req types.ScenarioTarget) database.Target {
+	target := database.Target{
+		DurationSeconds: req.DurationSeconds,
+		Frames:          req.Frames,
+		Samples:         req.Samples,
+		Action:          database.TargetAction(req.Action),
+	}
+	if target.Action == "" && target.IsSet() {
+		target.Action = database.TargetActionComplete
+	}
+	return target
+}
+
+// requestTarget returns a stored target as it appears in a request, to apply
+// merge patches to.
+func requestTarget(target database.Target) types.ScenarioTarget {
+	return types.ScenarioTarget{
+		DurationSeconds: target.DurationSeconds,
+		Frames:          target.Frames,
+		Samples:         target.Samples,
+		Action:          string(target.Action),
+	}
+}
+
+// setTarget changes the target of a scenario. A new target clears the
+// overdue flag, so that the scheduler checks the scenario against it.
+func setTarget(scenario *database.Scenario, target database.Target) {
+	same := func(a, b *int64) bool {
+		return a == nil && b == nil || a != nil && b != nil && *a == *b
+	}
+	old := scenario.Target
+	if same(old.DurationSeconds, target.DurationSeconds) && same(old.Frames, target.Frames) && same(old.Samples, target.Samples) && old.Action == target.Action {
+		return
+	}
+	scenario.Target = target
+	scenario.OverdueAt = nil
+}
+
 func (h *ScenarioHandler) UpdateScenario(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
@@ -175,22 +316,34 @@ func (h *ScenarioHandler) UpdateScenario(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
 	// Validate that the test session exists
 	testSession := database.TestSession{}
-	if result := h.db.First(&testSession, req.TestSessionID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&testSession, req.TestSessionID); result.Error != nil {
 		http.Error(w, "TestSession does not exist", http.StatusBadRequest)
 		return
 	}
 
-	scenario := database.Scenario{
-		Name:          req.Name,
-		TestSessionID: req.TestSessionID,
-	}
-	scenario.ID = id
-
-	result := h.db.Save(&scenario)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	scenario := database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &scenario, &scenario.Model, id); err != nil {
+			return err
+		}
+		if err := moveScenario(tx, &scenario, req.TestSessionID); err != nil {
+			return err
+		}
+		scenario.Name = req.Name
+		scenario.Metadata = metadata
+		setTarget(&scenario, targetOf(req.Target))
+		return conflictOn(tx.Save(&scenario).Error, scenarioConflict(&scenario))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
@@ -199,13 +352,72 @@ func (h *ScenarioHandler) UpdateScenario(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Error loading test session: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.events.Publish(watch.Modified, scenario.Namespace, scenario)
 
+	utils.SetETag(w, &scenario.Model)
 	if err := json.NewEncoder(w).Encode(scenario); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
+
+// PatchScenario applies a JSON merge patch (RFC 7396) to a scenario. Only the
+// fields present in the patch are changed.
+func (h *ScenarioHandler) PatchScenario(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	scenario := database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &scenario, &scenario.Model, id); err != nil {
+			return err
+		}
+		req := types.UpdateScenarioRequest{ID: id, Name: scenario.Name, TestSessionID: scenario.TestSessionID, Target: requestTarget(scenario.Target), Metadata: requestMetadata(scenario.Metadata)}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		metadata, err := metadataOf(req.Metadata)
+		if err != nil {
+			return err
+		}
+		if err := namespaced(tx, r).First(&database.TestSession{}, req.TestSessionID).Error; err != nil {
+			return err
+		}
+		if err := moveScenario(tx, &scenario, req.TestSessionID); err != nil {
+			return err
+		}
+		scenario.Name = req.Name
+		scenario.Metadata = metadata
+		setTarget(&scenario, targetOf(req.Target))
+		return conflictOn(tx.Save(&scenario).Error, scenarioConflict(&scenario))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	if err := h.db.Preload("TestSession").First(&scenario, id).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	h.events.Publish(watch.Modified, scenario.Namespace, scenario)
 
+	utils.SetETag(w, &scenario.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scenario)
 }
 
 func (h *ScenarioHandler) DeleteScenario(w http.ResponseWriter, r *http.Request) {
@@ -217,13 +429,115 @@ func (h *ScenarioHandler) DeleteScenario(w http.ResponseWriter, r *http.Request)
 	}
 
 	scenario := database.Scenario{}
-	result := h.db.Delete(&scenario, id)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &scenario, &scenario.Model, id); err != nil {
+			return err
+		}
+		if lifecycle.CurrentStatus(&scenario) == database.StatusActive {
+			return &conflictError{detail: fmt.Sprintf("Scenario %d is active, deactivate it before deleting it", scenario.ID)}
+		}
+		return tx.Delete(&scenario).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Deleted, scenario.Namespace, scenario)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RestoreScenario undeletes a soft-deleted scenario. An active scenario is
+// only restored on a device without another active scenario.
+func (h *ScenarioHandler) RestoreScenario(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	scenario := database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := restoreRow(namespaced(tx, r), &scenario, &scenario.Model, id, "Scenario"); err != nil {
+			return conflictOn(err, scenarioConflict(&scenario))
+		}
+		testSession := database.TestSession{}
+		if err := requireLive(tx, &testSession, scenario.TestSessionID, "Test session"); err != nil {
+			return err
+		}
+		return lifecycle.Join(tx, testSession.DeviceID, []database.Scenario{scenario})
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
+	if err := h.db.Preload("TestSession").First(&scenario, id).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	h.events.Publish(watch.Added, scenario.Namespace, scenario)
+
+	utils.SetETag(w, &scenario.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scenario)
+}
+
+// CloneScenario copies a scenario with its condition values in one
+// transaction. The copy starts out inactive; the body may rename it and move
+// it to another test session.
+func (h *ScenarioHandler) CloneScenario(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	req, ok := decodeCloneRequest[types.CloneScenarioRequest](w, r)
+	if !ok {
+		return
+	}
+
+	if req.TestSessionID != 0 {
+		if result := namespaced(h.db, r).First(&database.TestSession{}, req.TestSessionID); result.Error != nil {
+			apierrors.WriteError(w, apierrors.NewBadRequestError("TestSession does not exist", r.URL.Path))
+			return
+		}
+	}
+
+	scenario := database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		source := database.Scenario{}
+		if err := namespaced(tx, r).First(&source, id).Error; err != nil {
+			return err
+		}
+		testSessionID := source.TestSessionID
+		if req.TestSessionID != 0 {
+			testSessionID = req.TestSessionID
+		}
+		testSession := database.TestSession{}
+		if err := tx.First(&testSession, testSessionID).Error; err != nil {
+			return err
+		}
+
+		scenario, _, err = cloneScenario(tx, &source, &testSession, cloneName(req.Name, source.Name, testSessionID == source.TestSessionID))
+		return err
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	if err := h.db.Preload("TestSession").Preload("ScenarioConditions").First(&scenario, scenario.ID).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	h.events.Publish(watch.Added, scenario.Namespace, scenario)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scenario)
 }
 
 func (h *ScenarioHandler) GetScenario(w http.ResponseWriter, r *http.Request) {
@@ -234,21 +548,51 @@ func (h *ScenarioHandler) GetScenario(w http.ResponseWriter, r *http.Request) {
 	}
 
 	scenario := database.Scenario{}
-	result := h.db.Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").First(&scenario, id)
+	result := namespaced(h.db, r).Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").First(&scenario, id)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	utils.SetETag(w, &scenario.Model)
 	if err := json.NewEncoder(w).Encode(scenario); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
+// GetScenarioByName returns the scenario with the given name inside a test
+// session.
+func (h *ScenarioHandler) GetScenarioByName(w http.ResponseWriter, r *http.Request) {
+	testSessionID, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	scenario := database.Scenario{}
+	result := namespaced(h.db, r).Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").
+		Where("test_session_id = ? AND name = ?", testSessionID, name).
+		First(&scenario)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		apierrors.WriteError(w, apierrors.NewNotFoundError(fmt.Sprintf("scenario %q in test session %d", name, testSessionID), r.URL.Path))
+		return
+	}
+	if result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	utils.SetETag(w, &scenario.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scenario)
+}
+
 func (h *ScenarioHandler) GetScenarios(w http.ResponseWriter, r *http.Request) {
 	scenarios := []database.Scenario{}
-	result := h.db.Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").Find(&scenarios)
+	result := namespaced(h.db, r).Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").Find(&scenarios)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
@@ -269,19 +613,80 @@ func (h *ScenarioHandler) GetScenariosByTestSession(w http.ResponseWriter, r *ht
 	}
 	testSessionID := uint(testSessionID64)
 
-	scenarios := []database.Scenario{}
-	result := h.db.Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").Where("test_session_id = ?", testSessionID).Find(&scenarios)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	opts, err := utils.ParseListOptions(r, scenarioListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(scenarios); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	conditionFilters := r.URL.Query()["condition"]
+	if len(conditionFilters) > 0 && r.URL.Query().Get("watch") != "" {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("condition filter is not supported with watch", r.URL.Path))
 		return
 	}
+
+	watchTestSession := func(object interface{}) bool {
+		scenario := object.(database.Scenario)
+		if scenario.TestSessionID != testSessionID {
+			return false
+		}
+		return len(opts.Statuses) == 0 || slices.Contains(opts.Statuses, string(scenario.Status))
+	}
+	if serveWatchIfRequested(w, r, h.events, "Scenario", utils.Namespace(r), selecting(opts, watchTestSession)) {
+		return
+	}
+
+	version := listVersion(h.events)
+	query := namespaced(h.db, r).Preload("TestSession").Preload("ScenarioConditions").Preload("ScenarioConditions.ConditionValue").Where("test_session_id = ?", testSessionID)
+	for _, expr := range conditionFilters {
+		query, err = h.filterByCondition(r, query, expr)
+		if err != nil {
+			apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+			return
+		}
+	}
+	scenarios, meta, err := utils.Paginate[database.Scenario](query, opts, scenarioListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+	meta.ResourceVersion = version
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("ScenarioList", scenarios, meta))
 }
 
+// filterByCondition restricts query to the scenarios with a value of the
+// condition named in expr that passes the comparison, e.g. temperature>=30C.
+func (h *ScenarioHandler) filterByCondition(r *http.Request, query *gorm.DB, expr string) (*gorm.DB, error) {
+	filter, err := values.ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	condition := database.Condition{}
+	if err := namespaced(h.db, r).Where("name = ?", filter.Condition).First(&condition).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("condition %q does not exist", filter.Condition)
+		}
+		return nil, err
+	}
+	where, args, err := filter.Where(&condition)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := h.db.Model(&database.ScenarioCondition{}).
+		Select("scenario_conditions.scenario_id").
+		Joins("JOIN condition_values ON condition_values.id = scenario_conditions.condition_value_id AND condition_values.deleted_at IS NULL").
+		Where("condition_values.condition_id = ?", condition.ID).
+		Where(where, args...)
+	return query.Where("id IN (?)", matching), nil
+}
+
+// ActivateScenario activates a scenario unless another scenario is already
+// active on the same device. With ?preempt=true the other scenario is
+// deactivated in the same transaction instead.
 func (h *ScenarioHandler) ActivateScenario(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
@@ -289,28 +694,56 @@ func (h *ScenarioHandler) ActivateScenario(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	scenario := database.Scenario{}
-	if result := h.db.First(&scenario, id); result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
-		return
+	preempt := false
+	if value := r.URL.Query().Get("preempt"); value != "" {
+		preempt, err = strconv.ParseBool(value)
+		if err != nil {
+			apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid preempt flag: "+err.Error(), r.URL.Path))
+			return
+		}
 	}
 
-	if scenario.Status == database.StatusActive {
-		apierrors.WriteError(w, apierrors.NewBadRequestError("Scenario is already active", r.URL.Path))
+	req, ok := decodeTransitionRequest(w, r)
+	if !ok {
 		return
 	}
 
-	scenario.Status = database.StatusActive
-	result := h.db.Save(&scenario)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	var scenario *database.Scenario
+	var preempted []database.Scenario
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := namespaced(tx, r).First(&database.Scenario{}, id).Error; err != nil {
+			return err
+		}
+		scenario, preempted, err = lifecycle.Activate(tx, id, req.Reason, preempt)
+		return err
+	})
+	if err != nil {
+		writeTransitionError(w, r, err)
 		return
 	}
+	for _, other := range preempted {
+		h.events.Publish(watch.Modified, other.Namespace, other)
+		h.hooks.Notify(other.Namespace, webhook.ScenarioDeactivated, other)
+	}
+	h.events.Publish(watch.Modified, scenario.Namespace, *scenario)
+	h.hooks.Notify(scenario.Namespace, webhook.ScenarioActivated, *scenario)
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *ScenarioHandler) DeactivateScenario(w http.ResponseWriter, r *http.Request) {
+	h.transitionScenario(w, r, database.StatusInactive)
+}
+
+func (h *ScenarioHandler) CompleteScenario(w http.ResponseWriter, r *http.Request) {
+	h.transitionScenario(w, r, database.StatusCompleted)
+}
+
+func (h *ScenarioHandler) AbortScenario(w http.ResponseWriter, r *http.Request) {
+	h.transitionScenario(w, r, database.StatusAborted)
+}
+
+func (h *ScenarioHandler) GetScenarioHistory(w http.ResponseWriter, r *http.Request) {
 	id, err := utils.ParseID(r)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario ID: "+err.Error(), r.URL.Path))
@@ -318,50 +751,124 @@ func (h *ScenarioHandler) DeactivateScenario(w http.ResponseWriter, r *http.Requ
 	}
 
 	scenario := database.Scenario{}
-	if result := h.db.First(&scenario, id); result.Error != nil {
+	if result := namespaced(h.db, r).First(&scenario, id); result.Error != nil {
 		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
 		return
 	}
 
-	if scenario.Status == database.StatusInactive {
-		apierrors.WriteError(w, apierrors.NewBadRequestError("Scenario is already deactivated", r.URL.Path))
+	history, err := lifecycle.History(h.db, id)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 		return
 	}
 
-	scenario.Status = database.StatusInactive
-	result := h.db.Save(&scenario)
-	if result.Error != nil {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetScenarioStats describes the data recorded for a scenario: its frames,
+// the time they span, the effective sample rate and per-channel statistics,
+// so that a run can be checked before it is completed.
+func (h *ScenarioHandler) GetScenarioStats(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	scenario := database.Scenario{}
+	if result := namespaced(h.db, r).First(&scenario, id); result.Error != nil {
 		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
 		return
 	}
 
+	db := h.db.WithContext(r.Context())
+	stats, err := acquisition.Stats(db, acquisition.AvailableSources(db), id)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
 }
 
-func (h *ScenarioHandler) CompleteScenario(w http.ResponseWriter, r *http.Request) {
+// transitionScenario moves the scenario addressed by the request to the given
+// status. An optional JSON body may carry the reason recorded in the history.
+func (h *ScenarioHandler) transitionScenario(w http.ResponseWriter, r *http.Request, to database.Status) {
 	id, err := utils.ParseID(r)
 	if err != nil {
 		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario ID: "+err.Error(), r.URL.Path))
 		return
 	}
 
-	scenario := database.Scenario{}
-	if result := h.db.First(&scenario, id); result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	req, ok := decodeTransitionRequest(w, r)
+	if !ok {
 		return
 	}
 
-	if scenario.Status == database.StatusCompleted {
-		apierrors.WriteError(w, apierrors.NewBadRequestError("Scenario is already completed", r.URL.Path))
+	var scenario *database.Scenario
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := namespaced(tx, r).First(&database.Scenario{}, id).Error; err != nil {
+			return err
+		}
+		scenario, err = lifecycle.Transition(tx, id, to, req.Reason)
+		return err
+	})
+	if err != nil {
+		writeTransitionError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Modified, scenario.Namespace, *scenario)
+	h.hooks.Notify(scenario.Namespace, transitionEvents[to], *scenario)
 
-	scenario.Status = database.StatusCompleted
-	result := h.db.Save(&scenario)
-	if result.Error != nil {
-		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+	w.WriteHeader(http.StatusOK)
+}
+
+// decodeTransitionRequest reads the optional transition body. It writes the
+// error response itself and reports whether the handler may continue.
+func decodeTransitionRequest(w http.ResponseWriter, r *http.Request) (types.ScenarioTransitionRequest, bool) {
+	var req types.ScenarioTransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return req, false
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return req, false
+	}
+
+	return req, true
+}
+
+// writeTransitionError reports a rejected lifecycle transition as a conflict
+// and falls back to the database error mapping for everything else.
+func writeTransitionError(w http.ResponseWriter, r *http.Request, err error) {
+	var transitionErr *lifecycle.TransitionError
+	if errors.As(err, &transitionErr) {
+		errResp := apierrors.NewConflictError(transitionErr.Error(), r.URL.Path)
+		errResp.Meta = map[string]interface{}{
+			"from": transitionErr.From,
+			"to":   transitionErr.To,
+		}
+		apierrors.WriteError(w, errResp)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	var activeErr *lifecycle.ActiveScenarioError
+	if errors.As(err, &activeErr) {
+		errResp := apierrors.NewConflictError(activeErr.Error(), r.URL.Path)
+		errResp.Meta = map[string]interface{}{
+			"device_id":            activeErr.DeviceID,
+			"active_scenario_id":   activeErr.Active.ID,
+			"active_scenario_name": activeErr.Active.Name,
+		}
+		apierrors.WriteError(w, errResp)
+		return
+	}
+
+	apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 }
@@ -8,6 +8,8 @@ import (
 
 	"config/utils"
 
+	apierrors "github.com/neuro-lab/errors"
+
 	"gorm.io/gorm"
 )
 
@@ -34,14 +36,14 @@ func (h *ScenarioConditionHandler) CreateScenarioCondition(w http.ResponseWriter
 
 	// Validate that the scenario exists
 	scenario := database.Scenario{}
-	if result := h.db.First(&scenario, req.ScenarioID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&scenario, req.ScenarioID); result.Error != nil {
 		http.Error(w, "Scenario does not exist", http.StatusBadRequest)
 		return
 	}
 
 	// Validate that the condition value exists
 	conditionValue := database.ConditionValue{}
-	if result := h.db.First(&conditionValue, req.ConditionValueID); result.Error != nil {
+	if result := viaParent(h.db, r, "condition_id", &database.Condition{}).First(&conditionValue, req.ConditionValueID); result.Error != nil {
 		http.Error(w, "ConditionValue does not exist", http.StatusBadRequest)
 		return
 	}
@@ -92,27 +94,29 @@ func (h *ScenarioConditionHandler) UpdateScenarioCondition(w http.ResponseWriter
 
 	// Validate that the scenario exists
 	scenario := database.Scenario{}
-	if result := h.db.First(&scenario, req.ScenarioID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&scenario, req.ScenarioID); result.Error != nil {
 		http.Error(w, "Scenario does not exist", http.StatusBadRequest)
 		return
 	}
 
 	// Validate that the condition value exists
 	conditionValue := database.ConditionValue{}
-	if result := h.db.First(&conditionValue, req.ConditionValueID); result.Error != nil {
+	if result := viaParent(h.db, r, "condition_id", &database.Condition{}).First(&conditionValue, req.ConditionValueID); result.Error != nil {
 		http.Error(w, "ConditionValue does not exist", http.StatusBadRequest)
 		return
 	}
 
-	scenarioCondition := database.ScenarioCondition{
-		ScenarioID:       req.ScenarioID,
-		ConditionValueID: req.ConditionValueID,
-	}
-	scenarioCondition.ID = id
-
-	result := h.db.Save(&scenarioCondition)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	scenarioCondition := database.ScenarioCondition{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(viaParent(tx, r, "scenario_id", &database.Scenario{}), r, &scenarioCondition, &scenarioCondition.Model, id); err != nil {
+			return err
+		}
+		scenarioCondition.ScenarioID = req.ScenarioID
+		scenarioCondition.ConditionValueID = req.ConditionValueID
+		return tx.Save(&scenarioCondition).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
@@ -122,12 +126,64 @@ func (h *ScenarioConditionHandler) UpdateScenarioCondition(w http.ResponseWriter
 		return
 	}
 
+	utils.SetETag(w, &scenarioCondition.Model)
 	if err := json.NewEncoder(w).Encode(scenarioCondition); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
+
+// PatchScenarioCondition applies a JSON merge patch (RFC 7396) to a scenario condition. Only the
+// fields present in the patch are changed.
+func (h *ScenarioConditionHandler) PatchScenarioCondition(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario condition ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	scenarioCondition := database.ScenarioCondition{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(viaParent(tx, r, "scenario_id", &database.Scenario{}), r, &scenarioCondition, &scenarioCondition.Model, id); err != nil {
+			return err
+		}
+		req := types.UpdateScenarioConditionRequest{ID: id, ScenarioID: scenarioCondition.ScenarioID, ConditionValueID: scenarioCondition.ConditionValueID}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		if err := namespaced(tx, r).First(&database.Scenario{}, req.ScenarioID).Error; err != nil {
+			return err
+		}
+		if err := viaParent(tx, r, "condition_id", &database.Condition{}).First(&database.ConditionValue{}, req.ConditionValueID).Error; err != nil {
+			return err
+		}
+		scenarioCondition.ScenarioID = req.ScenarioID
+		scenarioCondition.ConditionValueID = req.ConditionValueID
+		return tx.Save(&scenarioCondition).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	if err := h.db.Preload("Scenario").Preload("ConditionValue").First(&scenarioCondition, id).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
 
+	utils.SetETag(w, &scenarioCondition.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scenarioCondition)
 }
 
 func (h *ScenarioConditionHandler) DeleteScenarioCondition(w http.ResponseWriter, r *http.Request) {
@@ -139,13 +195,55 @@ func (h *ScenarioConditionHandler) DeleteScenarioCondition(w http.ResponseWriter
 	}
 
 	scenarioCondition := database.ScenarioCondition{}
-	result := h.db.Delete(&scenarioCondition, id)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(viaParent(tx, r, "scenario_id", &database.Scenario{}), r, &scenarioCondition, &scenarioCondition.Model, id); err != nil {
+			return err
+		}
+		return tx.Delete(&scenarioCondition).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RestoreScenarioCondition undeletes a soft-deleted scenario condition.
+func (h *ScenarioConditionHandler) RestoreScenarioCondition(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid scenario condition ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	scenarioCondition := database.ScenarioCondition{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := restoreRow(viaParent(tx, r, "scenario_id", &database.Scenario{}), &scenarioCondition, &scenarioCondition.Model, id, "Scenario condition"); err != nil {
+			return err
+		}
+		if err := requireLive(tx, &database.Scenario{}, scenarioCondition.ScenarioID, "Scenario"); err != nil {
+			return err
+		}
+		if err := requireLive(tx, &database.ConditionValue{}, scenarioCondition.ConditionValueID, "Condition value"); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	if err := h.db.Preload("Scenario").Preload("ConditionValue").First(&scenarioCondition, id).Error; err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 		return
 	}
 
+	utils.SetETag(w, &scenarioCondition.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scenarioCondition)
 }
 
 func (h *ScenarioConditionHandler) GetScenarioCondition(w http.ResponseWriter, r *http.Request) {
@@ -156,12 +254,13 @@ func (h *ScenarioConditionHandler) GetScenarioCondition(w http.ResponseWriter, r
 	}
 
 	scenarioCondition := database.ScenarioCondition{}
-	result := h.db.Preload("Scenario").Preload("ConditionValue").First(&scenarioCondition, id)
+	result := viaParent(h.db, r, "scenario_id", &database.Scenario{}).Preload("Scenario").Preload("ConditionValue").First(&scenarioCondition, id)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	utils.SetETag(w, &scenarioCondition.Model)
 	if err := json.NewEncoder(w).Encode(scenarioCondition); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
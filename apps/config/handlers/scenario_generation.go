@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"types"
+
+	"config/design"
+	"config/utils"
+	"config/watch"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+// maxGeneratedScenarios caps the scenarios a single generation may create.
+const maxGeneratedScenarios = 1000
+
+// GenerateScenarios creates the scenarios of a test session from
+// combinations of condition values, as a full factorial or a pairwise
+// covering array, skipping the excluded combinations. All scenarios are
+// created in one transaction. With ?dryRun=true nothing is created and the
+// scenarios that would be are returned instead.
+func (h *ScenarioHandler) GenerateScenarios(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	dryRun, err := boolParam(r, "dryRun")
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	var req types.GenerateScenariosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	testSession := database.TestSession{}
+	if result := namespaced(h.db, r).First(&testSession, id); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	factors, valueIDs, err := h.generationFactors(r, req.Conditions)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(err.Error(), r.URL.Path))
+		return
+	}
+
+	rules := make([]design.Rule, 0, len(req.Exclude))
+	for _, exclude := range req.Exclude {
+		rules = append(rules, design.Rule(exclude))
+	}
+
+	var runs []design.Run
+	if req.Strategy == "factorial" {
+		if size := design.Size(factors); size > maxGeneratedScenarios {
+			combinations := strconv.Itoa(size)
+			if size == math.MaxInt {
+				combinations = "too many"
+			}
+			apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(fmt.Sprintf("The full factorial has %s combinations, more than the %d scenarios a generation may create", combinations, maxGeneratedScenarios), r.URL.Path))
+			return
+		}
+		runs, err = design.Factorial(factors, rules)
+	} else {
+		runs, err = design.Pairwise(factors, rules)
+	}
+	if err == nil && len(runs) > maxGeneratedScenarios {
+		err = fmt.Errorf("the generation yields %d scenarios, more than the %d it may create", len(runs), maxGeneratedScenarios)
+	}
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(err.Error(), r.URL.Path))
+		return
+	}
+
+	resp := types.GenerateScenariosResponse{
+		DryRun:        dryRun,
+		TestSessionID: id,
+		Strategy:      req.Strategy,
+		Combinations:  design.Size(factors),
+		Scenarios:     make([]types.GeneratedScenario, 0, len(runs)),
+	}
+	names := map[string]bool{}
+	for n, run := range runs {
+		generated := types.GeneratedScenario{
+			Conditions:        map[string]string{},
+			ConditionValueIDs: make([]uint, 0, len(run)),
+		}
+		for i, level := range run {
+			generated.Conditions[factors[i].Name] = factors[i].Levels[level]
+			generated.ConditionValueIDs = append(generated.ConditionValueIDs, valueIDs[i][level])
+		}
+		generated.Name = generatedName(req.NameTemplate, n+1, factors, run)
+		if names[generated.Name] {
+			apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(fmt.Sprintf("The name template yields %q more than once; include {index} or every condition", generated.Name), r.URL.Path))
+			return
+		}
+		names[generated.Name] = true
+		resp.Scenarios = append(resp.Scenarios, generated)
+	}
+
+	if dryRun {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	scenarios := make([]database.Scenario, 0, len(resp.Scenarios))
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for i := range resp.Scenarios {
+			scenario := database.Scenario{
+				Namespace:     testSession.Namespace,
+				Name:          resp.Scenarios[i].Name,
+				TestSessionID: testSession.ID,
+			}
+			if err := createScenarioWithConditionValues(tx, &scenario, resp.Scenarios[i].ConditionValueIDs); err != nil {
+				return err
+			}
+			resp.Scenarios[i].ID = scenario.ID
+			scenarios = append(scenarios, scenario)
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	for _, scenario := range scenarios {
+		h.events.Publish(watch.Added, scenario.Namespace, scenario)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// generationFactors looks up the requested conditions of the namespace and
+// the values to combine. It returns the factors together with the condition
+// value IDs of their levels.
+func (h *ScenarioHandler) generationFactors(r *http.Request, conditions []types.GenerateCondition) ([]design.Factor, [][]uint, error) {
+	factors := make([]design.Factor, 0, len(conditions))
+	valueIDs := make([][]uint, 0, len(conditions))
+	for _, spec := range conditions {
+		if slices.ContainsFunc(factors, func(f design.Factor) bool { return f.Name == spec.Name }) {
+			return nil, nil, fmt.Errorf("condition %q is listed more than once", spec.Name)
+		}
+
+		condition := database.Condition{}
+		if err := namespaced(h.db, r).Where("name = ?", spec.Name).First(&condition).Error; err != nil {
+			return nil, nil, fmt.Errorf("condition %q does not exist", spec.Name)
+		}
+		values := []database.ConditionValue{}
+		if err := h.db.Where("condition_id = ?", condition.ID).Order("id").Find(&values).Error; err != nil {
+			return nil, nil, err
+		}
+
+		factor := design.Factor{Name: spec.Name}
+		ids := []uint{}
+		if len(spec.Values) == 0 {
+			for _, value := range values {
+				factor.Levels = append(factor.Levels, value.Value)
+				ids = append(ids, value.ID)
+			}
+		}
+		for _, name := range spec.Values {
+			i := slices.IndexFunc(values, func(v database.ConditionValue) bool { return v.Value == name })
+			if i < 0 {
+				return nil, nil, fmt.Errorf("condition %q has no value %q", spec.Name, name)
+			}
+			if slices.Contains(factor.Levels, name) {
+				return nil, nil, fmt.Errorf("value %q of condition %q is listed more than once", name, spec.Name)
+			}
+			factor.Levels = append(factor.Levels, name)
+			ids = append(ids, values[i].ID)
+		}
+		if len(factor.Levels) == 0 {
+			return nil, nil, fmt.Errorf("condition %q has no values", spec.Name)
+		}
+
+		factors = append(factors, factor)
+		valueIDs = append(valueIDs, ids)
+	}
+	return factors, valueIDs, nil
+}
+
+// generatedName fills in the name template for a run; without a template the
+// values are joined with "-".
+func generatedName(template string, index int, factors []design.Factor, run design.Run) string {
+	if template == "" {
+		values := make([]string, 0, len(run))
+		for i, level := range run {
+			values = append(values, factors[i].Levels[level])
+		}
+		return strings.Join(values, "-")
+	}
+
+	replacements := []string{"{index}", strconv.Itoa(index)}
+	for i, level := range run {
+		replacements = append(replacements, "{"+factors[i].Name+"}", factors[i].Levels[level])
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
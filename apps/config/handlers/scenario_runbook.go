@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"types"
+
+	"config/lifecycle"
+	"config/utils"
+	"config/watch"
+	"config/webhook"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// orderError is a reorder request that does not list the scenarios of the
+// test session.
+type orderError struct {
+	detail string
+}
+
+func (e *orderError) Error() string {
+	return e.detail
+}
+
+// ReorderScenarios sets the order in which the scenarios of a test session
+// are run. The request lists every scenario of the session once; they are
+// numbered from 1 in that order.
+func (h *ScenarioHandler) ReorderScenarios(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	var req types.ReorderScenariosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	scenarios := []database.Scenario{}
+	moved := []database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		testSession := database.TestSession{}
+		if err := namespaced(tx, r).Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&testSession, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("test_session_id = ?", id).Find(&scenarios).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if scenarios, moved, err = reorder(id, scenarios, req.ScenarioIDs); err != nil {
+			return err
+		}
+		for _, scenario := range moved {
+			if err := tx.Model(&scenario).Update("position", scenario.Position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var invalidOrder *orderError
+	if errors.As(err, &invalidOrder) {
+		apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(invalidOrder.detail, r.URL.Path))
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	for _, scenario := range moved {
+		h.events.Publish(watch.Modified, scenario.Namespace, scenario)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scenarios)
+}
+
+// reorder numbers the scenarios of test session id from 1 in the order of
+// scenarioIDs, which must list each of them once. It returns the scenarios in
+// that order and the ones whose position changed.
+func reorder(id uint, scenarios []database.Scenario, scenarioIDs []uint) ([]database.Scenario, []database.Scenario, error) {
+	if len(scenarioIDs) != len(scenarios) {
+		return nil, nil, &orderError{detail: fmt.Sprintf("test session %d has %d scenarios, %d were listed", id, len(scenarios), len(scenarioIDs))}
+	}
+	byID := make(map[uint]database.Scenario, len(scenarios))
+	for _, scenario := range scenarios {
+		byID[scenario.ID] = scenario
+	}
+
+	ordered := make([]database.Scenario, 0, len(scenarios))
+	moved := []database.Scenario{}
+	for i, scenarioID := range scenarioIDs {
+		scenario, ok := byID[scenarioID]
+		if !ok {
+			return nil, nil, &orderError{detail: fmt.Sprintf("scenario %d is not part of test session %d", scenarioID, id)}
+		}
+		if i > 0 && slices.Contains(scenarioIDs[:i], scenarioID) {
+			return nil, nil, &orderError{detail: fmt.Sprintf("scenario %d is listed more than once", scenarioID)}
+		}
+		if scenario.Position != i+1 {
+			scenario.Position = i + 1
+			moved = append(moved, scenario)
+		}
+		ordered = append(ordered, scenario)
+	}
+	return ordered, moved, nil
+}
+
+// AdvanceTestSession runs the next step of a test session: in one
+// transaction it completes the active scenario of the session and activates
+// the first pending one in the session order. The response carries the
+// condition values to set up for the activated scenario, so that a session
+// can be driven by calling the endpoint until it reports done. An optional
+// JSON body may carry the reason recorded in the history of both scenarios.
+func (h *ScenarioHandler) AdvanceTestSession(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	req, ok := decodeTransitionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	resp := types.TestSessionAdvanceResponse{TestSessionID: id}
+	var completed, activated *database.Scenario
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the session, then its device like lifecycle.Activate does, so
+		// that concurrent steps and activations are serialized.
+		testSession := database.TestSession{}
+		if err := namespaced(tx, r).Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&testSession, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&database.Device{}, testSession.DeviceID).Error; err != nil {
+			return err
+		}
+
+		active := database.Scenario{}
+		err := tx.Where("test_session_id = ? AND status = ?", id, database.StatusActive).First(&active).Error
+		switch {
+		case err == nil:
+			reason := req.Reason
+			if reason == "" {
+				reason = "test session advanced"
+			}
+			if completed, err = lifecycle.Transition(tx, active.ID, database.StatusCompleted, reason); err != nil {
+				return err
+			}
+			resp.Completed = &completed.ID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		pending := []database.Scenario{}
+		err = tx.Where("test_session_id = ?", id).
+			Where("status IN ? OR status IS NULL", []database.Status{database.StatusInactive, ""}).
+			Order("position, id").
+			Find(&pending).Error
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			resp.Done = true
+			return nil
+		}
+
+		reason := req.Reason
+		if reason == "" {
+			reason = "test session advanced"
+			if completed != nil {
+				reason = fmt.Sprintf("test session advanced from scenario %d", completed.ID)
+			}
+		}
+		if activated, _, err = lifecycle.Activate(tx, pending[0].ID, reason, false); err != nil {
+			return err
+		}
+		resp.Remaining = len(pending) - 1
+		resp.Active, err = runbookScenario(tx, activated)
+		return err
+	})
+	if err != nil {
+		writeTransitionError(w, r, err)
+		return
+	}
+	if completed != nil {
+		h.events.Publish(watch.Modified, completed.Namespace, *completed)
+		h.hooks.Notify(completed.Namespace, webhook.ScenarioCompleted, *completed)
+	}
+	if activated != nil {
+		h.events.Publish(watch.Modified, activated.Namespace, *activated)
+		h.hooks.Notify(activated.Namespace, webhook.ScenarioActivated, *activated)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// runbookScenario returns a scenario with the values of its conditions.
+func runbookScenario(tx *gorm.DB, scenario *database.Scenario) (*types.RunbookScenario, error) {
+	scenarioConditions := []database.ScenarioCondition{}
+	err := tx.Preload("ConditionValue").Preload("ConditionValue.Condition").
+		Where("scenario_id = ?", scenario.ID).
+		Order("id").
+		Find(&scenarioConditions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	runbook := &types.RunbookScenario{
+		ID:                scenario.ID,
+		Name:              scenario.Name,
+		Position:          scenario.Position,
+		Conditions:        map[string]string{},
+		ConditionValueIDs: []uint{},
+	}
+	for _, scenarioCondition := range scenarioConditions {
+		conditionValue := scenarioCondition.ConditionValue
+		if conditionValue == nil || conditionValue.Condition == nil {
+			continue
+		}
+		runbook.Conditions[conditionValue.Condition.Name] = conditionValue.Value
+		runbook.ConditionValueIDs = append(runbook.ConditionValueIDs, conditionValue.ID)
+	}
+	return runbook, nil
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"database"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestReorder(t *testing.T) {
+	scenario := func(id uint, position int) database.Scenario {
+		return database.Scenario{Model: gorm.Model{ID: id}, Position: position}
+	}
+	session := []database.Scenario{scenario(4, 1), scenario(7, 2), scenario(9, 3)}
+
+	tests := []struct {
+		name        string
+		scenarioIDs []uint
+		wantOrder   []uint
+		wantMoved   []uint
+		wantErr     string
+	}{
+		{name: "unchanged", scenarioIDs: []uint{4, 7, 9}, wantOrder: []uint{4, 7, 9}, wantMoved: []uint{}},
+		{name: "reversed", scenarioIDs: []uint{9, 7, 4}, wantOrder: []uint{9, 7, 4}, wantMoved: []uint{9, 4}},
+		{name: "rotated", scenarioIDs: []uint{7, 9, 4}, wantOrder: []uint{7, 9, 4}, wantMoved: []uint{7, 9, 4}},
+		{name: "missing scenario", scenarioIDs: []uint{4, 7}, wantErr: "test session 1 has 3 scenarios, 2 were listed"},
+		{name: "extra scenario", scenarioIDs: []uint{4, 7, 9, 11}, wantErr: "test session 1 has 3 scenarios, 4 were listed"},
+		{name: "foreign scenario", scenarioIDs: []uint{4, 7, 11}, wantErr: "scenario 11 is not part of test session 1"},
+		{name: "duplicate scenario", scenarioIDs: []uint{4, 7, 4}, wantErr: "scenario 4 is listed more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenarios := append([]database.Scenario{}, session...)
+			ordered, moved, err := reorder(1, scenarios, tt.scenarioIDs)
+			if tt.wantErr != "" {
+				var invalid *orderError
+				if !errors.As(err, &invalid) || !strings.Contains(invalid.detail, tt.wantErr) {
+					t.Fatalf("reorder() error = %v, want an orderError containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(ordered) != len(tt.wantOrder) {
+				t.Fatalf("reorder() returned %d scenarios, want %d", len(ordered), len(tt.wantOrder))
+			}
+			for i, scenario := range ordered {
+				if scenario.ID != tt.wantOrder[i] || scenario.Position != i+1 {
+					t.Errorf("scenario %d = ID %d at position %d, want ID %d at position %d", i, scenario.ID, scenario.Position, tt.wantOrder[i], i+1)
+				}
+			}
+			movedIDs := []uint{}
+			for _, scenario := range moved {
+				movedIDs = append(movedIDs, scenario.ID)
+			}
+			if !slices.Equal(movedIDs, tt.wantMoved) {
+				t.Errorf("moved = %v, want %v", movedIDs, tt.wantMoved)
+			}
+			if session[0].Position != 1 || session[2].Position != 3 {
+				t.Error("reorder() changed the positions of the scenarios passed in")
+			}
+		})
+	}
+}
@@ -3,9 +3,13 @@ package handlers
 import (
 	"database"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"types"
 
+	"config/auth"
+	"config/lifecycle"
+
 	apierrors "github.com/neuro-lab/errors"
 
 	"gorm.io/gorm"
@@ -19,6 +23,9 @@ func NewScenarioValidationHandler(db *gorm.DB) *ScenarioValidationHandler {
 	return &ScenarioValidationHandler{db: db}
 }
 
+// ValidateScenario checks that data sent by a device may be recorded for a
+// scenario: the scenario must exist, belong to a test session of that device
+// and be ACTIVE.
 func (h *ScenarioValidationHandler) ValidateScenario(w http.ResponseWriter, r *http.Request) {
 	var req types.ValidationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -32,13 +39,39 @@ func (h *ScenarioValidationHandler) ValidateScenario(w http.ResponseWriter, r *h
 		return
 	}
 
-	// Check if scenario exists in database
+	// Soft-deleted scenarios and test sessions are not found.
 	scenario := database.Scenario{}
-	result := h.db.First(&scenario, req.ScenarioID)
+	result := h.db.Preload("TestSession").First(&scenario, req.ScenarioID)
 	if result.Error != nil {
 		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
 		return
 	}
+	if scenario.TestSession == nil {
+		apierrors.WriteError(w, apierrors.NewNotFoundError(fmt.Sprintf("test session of scenario %d", scenario.ID), r.URL.Path))
+		return
+	}
+
+	// A device token may only validate data of its own device.
+	if principal := auth.FromContext(r.Context()); principal != nil && principal.DeviceID != nil && *principal.DeviceID != uint(req.DeviceID) {
+		errResp := apierrors.NewDeviceMismatchError(fmt.Sprintf("The token belongs to device %d, not device %d", *principal.DeviceID, req.DeviceID), r.URL.Path)
+		errResp.Meta = map[string]interface{}{"device_id": req.DeviceID, "token_device_id": *principal.DeviceID}
+		apierrors.WriteError(w, errResp)
+		return
+	}
+
+	if scenario.TestSession.DeviceID != uint(req.DeviceID) {
+		errResp := apierrors.NewDeviceMismatchError(fmt.Sprintf("Scenario %d belongs to device %d, not device %d", scenario.ID, scenario.TestSession.DeviceID, req.DeviceID), r.URL.Path)
+		errResp.Meta = map[string]interface{}{"device_id": req.DeviceID, "scenario_device_id": scenario.TestSession.DeviceID}
+		apierrors.WriteError(w, errResp)
+		return
+	}
+
+	if status := lifecycle.CurrentStatus(&scenario); status != database.StatusActive {
+		errResp := apierrors.NewScenarioNotActiveError(fmt.Sprintf("Scenario %d is %s, data is only accepted while it is %s", scenario.ID, status, database.StatusActive), r.URL.Path)
+		errResp.Meta = map[string]interface{}{"status": status}
+		apierrors.WriteError(w, errResp)
+		return
+	}
 
 	// Return success response with scenario data
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -3,22 +3,37 @@ package handlers
 import (
 	"database"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"types"
 
+	"config/lifecycle"
 	"config/utils"
+	"config/watch"
+	"config/webhook"
+
+	apierrors "github.com/neuro-lab/errors"
 
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var testSessionListSpec = utils.ListSpec{
+	NameColumn: "name",
+	SortFields: []string{"name", "updated_at"},
+	Labels:     true,
+}
+
 type TestSessionHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	events *watch.Broker
+	hooks  *webhook.Dispatcher
 }
 
-func NewTestSessionHandler(db *gorm.DB) *TestSessionHandler {
-	return &TestSessionHandler{db: db}
+func NewTestSessionHandler(db *gorm.DB, events *watch.Broker, hooks *webhook.Dispatcher) *TestSessionHandler {
+	return &TestSessionHandler{db: db, events: events, hooks: hooks}
 }
 
 func (h *TestSessionHandler) CreateTestSession(w http.ResponseWriter, r *http.Request) {
@@ -34,22 +49,32 @@ func (h *TestSessionHandler) CreateTestSession(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
 	device := database.Device{}
-	if result := h.db.First(&device, req.DeviceID); result.Error != nil {
+	if result := namespaced(h.db, r).First(&device, req.DeviceID); result.Error != nil {
 		http.Error(w, "Device with given ID does not exist", http.StatusBadRequest)
 		return
 	}
 
 	testSession := database.TestSession{
-		Name:     req.Name,
-		DeviceID: req.DeviceID,
+		Namespace: device.Namespace,
+		Name:      req.Name,
+		DeviceID:  req.DeviceID,
+		Metadata:  metadata,
 	}
 
 	result := h.db.Create(&testSession)
 	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, r, conflictOn(result.Error, testSessionConflict(&testSession)))
 		return
 	}
+	h.events.Publish(watch.Added, testSession.Namespace, testSession)
+	h.hooks.Notify(testSession.Namespace, webhook.TestSessionCreated, testSession)
 
 	w.WriteHeader(http.StatusCreated)
 
@@ -78,33 +103,101 @@ func (h *TestSessionHandler) UpdateTestSession(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Validate that the device exists
-	device := database.Device{}
-	if result := h.db.First(&device, req.DeviceID); result.Error != nil {
-		http.Error(w, "Device with ID "+string(rune(req.DeviceID))+" does not exist", http.StatusBadRequest)
+	metadata, err := metadataOf(req.Metadata)
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
 
-	testSession := database.TestSession{
-		Name:     req.Name,
-		DeviceID: req.DeviceID,
+	// Validate that the device exists
+	device := database.Device{}
+	if result := namespaced(h.db, r).First(&device, req.DeviceID); result.Error != nil {
+		http.Error(w, fmt.Sprintf("Device with ID %d does not exist", req.DeviceID), http.StatusBadRequest)
+		return
 	}
-	testSession.ID = id
 
-	result := h.db.Save(&testSession)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	testSession := database.TestSession{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &testSession, &testSession.Model, id); err != nil {
+			return err
+		}
+		if err := retarget(tx, &testSession, req.DeviceID); err != nil {
+			return err
+		}
+		testSession.Name = req.Name
+		testSession.Metadata = metadata
+		return conflictOn(tx.Save(&testSession).Error, testSessionConflict(&testSession))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
 		return
 	}
+	h.events.Publish(watch.Modified, testSession.Namespace, testSession)
 
+	utils.SetETag(w, &testSession.Model)
 	if err := json.NewEncoder(w).Encode(testSession); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
+
+// PatchTestSession applies a JSON merge patch (RFC 7396) to a test session. Only the
+// fields present in the patch are changed.
+func (h *TestSessionHandler) PatchTestSession(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
 
+	testSession := database.TestSession{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &testSession, &testSession.Model, id); err != nil {
+			return err
+		}
+		req := types.UpdateTestSessionRequest{ID: id, Name: testSession.Name, DeviceID: testSession.DeviceID, Metadata: requestMetadata(testSession.Metadata)}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		metadata, err := metadataOf(req.Metadata)
+		if err != nil {
+			return err
+		}
+		if err := namespaced(tx, r).First(&database.Device{}, req.DeviceID).Error; err != nil {
+			return err
+		}
+		if err := retarget(tx, &testSession, req.DeviceID); err != nil {
+			return err
+		}
+		testSession.Name = req.Name
+		testSession.Metadata = metadata
+		return conflictOn(tx.Save(&testSession).Error, testSessionConflict(&testSession))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Modified, testSession.Namespace, testSession)
+
+	utils.SetETag(w, &testSession.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(testSession)
 }
 
+// DeleteTestSession soft-deletes a test session. A session that still has
+// scenarios is only deleted with ?cascade=true, which removes the scenarios
+// and their scenario conditions in the same transaction. With ?dryRun=true
+// nothing is deleted and the affected rows are returned instead.
 func (h *TestSessionHandler) DeleteTestSession(w http.ResponseWriter, r *http.Request) {
 
 	id, err := utils.ParseID(r)
@@ -113,14 +206,247 @@ func (h *TestSessionHandler) DeleteTestSession(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	cascade, err := boolParam(r, "cascade")
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+	dryRun, err := boolParam(r, "dryRun")
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	resp := types.TestSessionDeleteResponse{
+		DryRun:             dryRun,
+		TestSessionID:      id,
+		Scenarios:          []uint{},
+		ScenarioConditions: []uint{},
+	}
 	testSession := database.TestSession{}
-	result := h.db.Delete(&testSession, id)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	scenarios := []database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &testSession, &testSession.Model, id); err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("test_session_id = ?", id).
+			Order("id").
+			Find(&scenarios).Error
+		if err != nil {
+			return err
+		}
+		if len(scenarios) > 0 && !cascade {
+			return &conflictError{detail: fmt.Sprintf("Test session %d still has %d scenario(s), delete them first or use ?cascade=true", id, len(scenarios))}
+		}
+		for _, scenario := range scenarios {
+			if scenario.Status == database.StatusActive {
+				return &conflictError{detail: fmt.Sprintf("Scenario %d is active, deactivate it before deleting the test session", scenario.ID)}
+			}
+			resp.Scenarios = append(resp.Scenarios, scenario.ID)
+		}
+
+		if len(resp.Scenarios) > 0 {
+			err := tx.Model(&database.ScenarioCondition{}).
+				Where("scenario_id IN ?", resp.Scenarios).
+				Order("id").
+				Pluck("id", &resp.ScenarioConditions).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		// All rows share one deletion timestamp so a cascading restore can
+		// tell them apart from rows deleted earlier.
+		deletedAt := tx.NowFunc()
+		if len(resp.ScenarioConditions) > 0 {
+			err := tx.Model(&database.ScenarioCondition{}).Where("id IN ?", resp.ScenarioConditions).Update("deleted_at", deletedAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(resp.Scenarios) > 0 {
+			err := tx.Model(&database.Scenario{}).Where("id IN ?", resp.Scenarios).Update("deleted_at", deletedAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		for i := range scenarios {
+			scenarios[i].DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+		}
+		return tx.Model(&testSession).Update("deleted_at", deletedAt).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	if !dryRun {
+		for _, scenario := range scenarios {
+			h.events.Publish(watch.Deleted, scenario.Namespace, scenario)
+		}
+		h.events.Publish(watch.Deleted, testSession.Namespace, testSession)
+	}
+
+	if !cascade && !dryRun {
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RestoreTestSession undeletes a soft-deleted test session. With
+// ?cascade=true the scenarios and scenario conditions removed by the same
+// cascading delete are restored as well, unless one of them is active while
+// another scenario is active on the device.
+func (h *TestSessionHandler) RestoreTestSession(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	cascade, err := boolParam(r, "cascade")
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	testSession := database.TestSession{}
+	scenarios := []database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		deletedAt, err := restoreRow(namespaced(tx, r), &testSession, &testSession.Model, id, "Test session")
+		if err != nil {
+			return conflictOn(err, testSessionConflict(&testSession))
+		}
+		if err := requireLive(tx, &database.Device{}, testSession.DeviceID, "Device"); err != nil {
+			return err
+		}
+		if !cascade {
+			return nil
+		}
+
+		scenarioIDs := []uint{}
+		err = tx.Unscoped().Model(&database.Scenario{}).
+			Where("test_session_id = ? AND deleted_at = ?", id, deletedAt).
+			Pluck("id", &scenarioIDs).Error
+		if err != nil || len(scenarioIDs) == 0 {
+			return err
+		}
+		err = tx.Unscoped().Model(&database.Scenario{}).Where("id IN ?", scenarioIDs).Update("deleted_at", nil).Error
+		if err != nil {
+			return conflictOn(err, fmt.Sprintf("A restored scenario of test session %d clashes with a newer scenario of the same name", id))
+		}
+		if err := tx.Where("id IN ?", scenarioIDs).Order("id").Find(&scenarios).Error; err != nil {
+			return err
+		}
+		if err := lifecycle.Join(tx, testSession.DeviceID, scenarios); err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&database.ScenarioCondition{}).
+			Where("scenario_id IN ? AND deleted_at = ?", scenarioIDs, deletedAt).
+			Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Added, testSession.Namespace, testSession)
+	for _, scenario := range scenarios {
+		h.events.Publish(watch.Added, scenario.Namespace, scenario)
+	}
+
+	utils.SetETag(w, &testSession.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(testSession)
+}
+
+// CloneTestSession copies a test session with all of its scenarios and their
+// condition values in one transaction. The copies start out inactive; the
+// body may rename the copy and retarget it to another device.
+func (h *TestSessionHandler) CloneTestSession(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid test session ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	req, ok := decodeCloneRequest[types.CloneTestSessionRequest](w, r)
+	if !ok {
+		return
+	}
+
+	if req.DeviceID != 0 {
+		if result := namespaced(h.db, r).First(&database.Device{}, req.DeviceID); result.Error != nil {
+			apierrors.WriteError(w, apierrors.NewBadRequestError(fmt.Sprintf("Device with ID %d does not exist", req.DeviceID), r.URL.Path))
+			return
+		}
+	}
+
+	resp := types.TestSessionCloneResponse{
+		SourceID:           id,
+		Scenarios:          []uint{},
+		ScenarioConditions: []uint{},
+	}
+	testSession := database.TestSession{}
+	scenarios := []database.Scenario{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		source := database.TestSession{}
+		if err := namespaced(tx, r).First(&source, id).Error; err != nil {
+			return err
+		}
+		deviceID := source.DeviceID
+		if req.DeviceID != 0 {
+			deviceID = req.DeviceID
+		}
+
+		testSession = database.TestSession{
+			Namespace: source.Namespace,
+			Name:      cloneName(req.Name, source.Name, deviceID == source.DeviceID),
+			DeviceID:  deviceID,
+			Metadata:  source.Metadata,
+		}
+		if err := tx.Create(&testSession).Error; err != nil {
+			return conflictOn(err, testSessionConflict(&testSession))
+		}
+		resp.TestSessionID = testSession.ID
+
+		sources := []database.Scenario{}
+		if err := tx.Where("test_session_id = ?", id).Order("position, id").Find(&sources).Error; err != nil {
+			return err
+		}
+		for i := range sources {
+			scenario, scenarioConditionIDs, err := cloneScenario(tx, &sources[i], &testSession, sources[i].Name)
+			if err != nil {
+				return err
+			}
+			scenarios = append(scenarios, scenario)
+			resp.Scenarios = append(resp.Scenarios, scenario.ID)
+			resp.ScenarioConditions = append(resp.ScenarioConditions, scenarioConditionIDs...)
+		}
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	h.events.Publish(watch.Added, testSession.Namespace, testSession)
+	for _, scenario := range scenarios {
+		h.events.Publish(watch.Added, scenario.Namespace, scenario)
+	}
+	h.hooks.Notify(testSession.Namespace, webhook.TestSessionCreated, testSession)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *TestSessionHandler) GetTestSession(w http.ResponseWriter, r *http.Request) {
@@ -131,12 +457,13 @@ func (h *TestSessionHandler) GetTestSession(w http.ResponseWriter, r *http.Reque
 	}
 
 	testSession := database.TestSession{}
-	result := h.db.First(&testSession, id)
+	result := namespaced(h.db, r).First(&testSession, id)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	utils.SetETag(w, &testSession.Model)
 	if err := json.NewEncoder(w).Encode(testSession); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -145,7 +472,7 @@ func (h *TestSessionHandler) GetTestSession(w http.ResponseWriter, r *http.Reque
 
 func (h *TestSessionHandler) GetTestSessions(w http.ResponseWriter, r *http.Request) {
 	testSessions := []database.TestSession{}
-	result := h.db.Find(&testSessions)
+	result := namespaced(h.db, r).Find(&testSessions)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
@@ -161,20 +488,58 @@ func (h *TestSessionHandler) GetTestSessionsByDevice(w http.ResponseWriter, r *h
 	deviceIDStr := chi.URLParam(r, "deviceID")
 	deviceID64, err := strconv.ParseUint(deviceIDStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid device ID: "+err.Error(), http.StatusBadRequest)
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid device ID: "+err.Error(), r.URL.Path))
 		return
 	}
 	deviceID := uint(deviceID64)
 
-	testSessions := []database.TestSession{}
-	result := h.db.Where("device_id = ?", deviceID).Find(&testSessions)
-	if result.Error != nil {
-		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+	opts, err := utils.ParseListOptions(r, testSessionListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(testSessions); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	watchDevice := func(object interface{}) bool {
+		return object.(database.TestSession).DeviceID == deviceID
+	}
+	if serveWatchIfRequested(w, r, h.events, "TestSession", utils.Namespace(r), selecting(opts, watchDevice)) {
+		return
+	}
+
+	version := listVersion(h.events)
+	testSessions, meta, err := utils.Paginate[database.TestSession](namespaced(h.db, r).Where("device_id = ?", deviceID), opts, testSessionListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
 		return
 	}
+	meta.ResourceVersion = version
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("TestSessionList", testSessions, meta))
+}
+
+// retarget moves a test session to another device. An active scenario of
+// the session moves along, so the move is refused when the device already
+// has an active scenario.
+func retarget(tx *gorm.DB, testSession *database.TestSession, deviceID uint) error {
+	if testSession.DeviceID == deviceID {
+		return nil
+	}
+	active := []database.Scenario{}
+	err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("test_session_id = ? AND status = ?", testSession.ID, database.StatusActive).
+		Find(&active).Error
+	if err != nil {
+		return err
+	}
+	if err := lifecycle.Join(tx, deviceID, active); err != nil {
+		return err
+	}
+	testSession.DeviceID = deviceID
+	return nil
+}
+
+func testSessionConflict(testSession *database.TestSession) string {
+	return fmt.Sprintf("Test session %q already exists on device %d", testSession.Name, testSession.DeviceID)
 }
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"net/http"
+	"types"
+
+	"config/auth"
+	"config/utils"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	db *gorm.DB
+}
+
+func NewTokenHandler(db *gorm.DB) *TokenHandler {
+	return &TokenHandler{db: db}
+}
+
+// CreateToken issues a new API token. The secret is returned once and only
+// its hash is stored.
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req types.CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewInternalError(r.URL.Path))
+		return
+	}
+
+	apiToken := database.APIToken{
+		Name:      req.Name,
+		TokenHash: auth.HashToken(token),
+		Role:      database.Role(req.Role),
+		DeviceID:  req.DeviceID,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if result := h.db.Create(&apiToken); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	resp := tokenResponse(&apiToken)
+	resp.Token = token
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *TokenHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	apiTokens := []database.APIToken{}
+	if result := h.db.Order("id").Find(&apiTokens); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	resp := make([]types.APITokenResponse, 0, len(apiTokens))
+	for i := range apiTokens {
+		resp = append(resp, tokenResponse(&apiTokens[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DeleteToken revokes an API token.
+func (h *TokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid token ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	apiToken := database.APIToken{}
+	if result := h.db.First(&apiToken, id); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+	if result := h.db.Delete(&apiToken); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WhoAmI returns the token the request was authenticated with.
+func (h *TokenHandler) WhoAmI(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		apierrors.WriteError(w, apierrors.NewUnauthorizedError("The request is not authenticated", r.URL.Path))
+		return
+	}
+
+	apiToken := database.APIToken{}
+	if result := h.db.First(&apiToken, principal.TokenID); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokenResponse(&apiToken))
+}
+
+func tokenResponse(apiToken *database.APIToken) types.APITokenResponse {
+	return types.APITokenResponse{
+		ID:        apiToken.ID,
+		Name:      apiToken.Name,
+		Role:      string(apiToken.Role),
+		DeviceID:  apiToken.DeviceID,
+		ExpiresAt: apiToken.ExpiresAt,
+		CreatedAt: apiToken.CreatedAt,
+	}
+}
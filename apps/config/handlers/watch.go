@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"config/watch"
+
+	apierrors "github.com/neuro-lab/errors"
+)
+
+// watchHeartbeat is how often an idle watch stream sends a comment so that
+// proxies do not close it.
+const watchHeartbeat = 30 * time.Second
+
+// serveWatchIfRequested serves a list request with ?watch=true as a stream of
+// the changes to the objects of kind in namespace that match accepts; match
+// may be nil. It reports whether the request was handled, in which case the
+// list handler must not write anything else.
+func serveWatchIfRequested(w http.ResponseWriter, r *http.Request, events *watch.Broker, kind, namespace string, match func(object interface{}) bool) bool {
+	watching, err := boolParam(r, "watch")
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return true
+	}
+	if !watching {
+		return false
+	}
+
+	serveWatch(w, r, events, func(event watch.Event) bool {
+		return event.Kind == kind && event.Namespace == namespace && (match == nil || match(event.Object))
+	})
+	return true
+}
+
+// serveWatch streams the events accepted by match as Server-Sent Events
+// until the client goes away. The stream resumes after the version given as
+// ?resourceVersion= or in the Last-Event-ID header a reconnecting client
+// sends; without either it starts with the changes made after the request.
+func serveWatch(w http.ResponseWriter, r *http.Request, events *watch.Broker, match func(watch.Event) bool) {
+	since := events.Version()
+	value := r.URL.Query().Get("resourceVersion")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		value = id
+	}
+	if value != "" {
+		version, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			apierrors.WriteError(w, apierrors.NewBadRequestError("resourceVersion must be a resource version returned by a list", r.URL.Path))
+			return
+		}
+		since = version
+	}
+
+	watcher, err := events.Watch(since, match)
+	if errors.Is(err, watch.ErrExpired) {
+		apierrors.WriteError(w, apierrors.NewGoneError(err.Error(), r.URL.Path))
+		return
+	}
+	defer watcher.Stop()
+
+	// The server write timeout is meant for regular requests; a watch
+	// lasts as long as the client keeps it open.
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-watcher.Events():
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				// with the last id it received.
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ResourceVersion, event.Type, data)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// listVersion returns the resource version to report with a list. It must be
+// read before the list is queried, so that a watch resumed from it replays
+// any change the list may have missed.
+func listVersion(events *watch.Broker) string {
+	return strconv.FormatUint(events.Version(), 10)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"types"
+
+	"config/utils"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+var webhookListSpec = utils.ListSpec{
+	NameColumn: "name",
+	SortFields: []string{"name", "updated_at"},
+}
+
+var webhookDeliveryListSpec = utils.ListSpec{
+	NameColumn: "event",
+	SortFields: []string{"event", "status", "updated_at"},
+	Status:     true,
+}
+
+type WebhookHandler struct {
+	db *gorm.DB
+}
+
+func NewWebhookHandler(db *gorm.DB) *WebhookHandler {
+	return &WebhookHandler{db: db}
+}
+
+// CreateWebhook registers a webhook for the lab events of the namespace.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req types.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	webhook := database.Webhook{
+		Namespace: utils.Namespace(r),
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    req.Events,
+		Active:    true,
+	}
+
+	result := h.db.Create(&webhook)
+	if result.Error != nil {
+		writeDatabaseError(w, r, conflictOn(result.Error, webhookConflict(&webhook)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid webhook ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	var req types.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+	req.ID = id
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	webhook := database.Webhook{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &webhook, &webhook.Model, id); err != nil {
+			return err
+		}
+		applyWebhookUpdate(&webhook, &req)
+		return conflictOn(tx.Save(&webhook).Error, webhookConflict(&webhook))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	utils.SetETag(w, &webhook.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// PatchWebhook applies a JSON merge patch (RFC 7396) to a webhook. Only the
+// fields present in the patch are changed.
+func (h *WebhookHandler) PatchWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid webhook ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	webhook := database.Webhook{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &webhook, &webhook.Model, id); err != nil {
+			return err
+		}
+		active := webhook.Active
+		req := types.UpdateWebhookRequest{ID: id, Name: webhook.Name, URL: webhook.URL, Events: webhook.Events, Active: &active}
+		if err := utils.MergePatch(&req, patch); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := validate.Struct(req); err != nil {
+			return err
+		}
+		applyWebhookUpdate(&webhook, &req)
+		return conflictOn(tx.Save(&webhook).Error, webhookConflict(&webhook))
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	utils.SetETag(w, &webhook.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhook removes a webhook. Pending deliveries are not sent anymore,
+// the delivery log is kept.
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid webhook ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	webhook := database.Webhook{}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockForWrite(namespaced(tx, r), r, &webhook, &webhook.Model, id); err != nil {
+			return err
+		}
+		return tx.Delete(&webhook).Error
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid webhook ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	webhook := database.Webhook{}
+	result := namespaced(h.db, r).First(&webhook, id)
+	if result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	utils.SetETag(w, &webhook.Model)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	opts, err := utils.ParseListOptions(r, webhookListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	webhooks, meta, err := utils.Paginate[database.Webhook](namespaced(h.db, r), opts, webhookListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("WebhookList", webhooks, meta))
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, oldest first
+// unless sorted otherwise. ?status= filters by delivery status.
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseID(r)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid webhook ID: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	opts, err := utils.ParseListOptions(r, webhookDeliveryListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError(err.Error(), r.URL.Path))
+		return
+	}
+
+	if result := namespaced(h.db, r).Unscoped().First(&database.Webhook{}, id); result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	deliveries, meta, err := utils.Paginate[database.WebhookDelivery](h.db.Where("webhook_id = ?", id), opts, webhookDeliveryListSpec)
+	if err != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types.NewList("WebhookDeliveryList", deliveries, meta))
+}
+
+func applyWebhookUpdate(webhook *database.Webhook, req *types.UpdateWebhookRequest) {
+	webhook.Name = req.Name
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+}
+
+func webhookConflict(webhook *database.Webhook) string {
+	return fmt.Sprintf("Webhook %q already exists in namespace %q", webhook.Name, webhook.Namespace)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"database"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"types"
+
+	"config/watch"
+	"config/webhook"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+type WorkflowHandler struct {
+	db     *gorm.DB
+	events *watch.Broker
+	hooks  *webhook.Dispatcher
+}
+
+func NewWorkflowHandler(db *gorm.DB, events *watch.Broker, hooks *webhook.Dispatcher) *WorkflowHandler {
+	return &WorkflowHandler{db: db, events: events, hooks: hooks}
+}
+
+// CreateWorkflow applies a TestSessionWorkflow document: conditions and values
+// are resolved by name (and created when missing), then the test session and
+// all of its scenarios are created. Everything happens in one transaction so a
+// failure never leaves a half-built session behind.
+func (h *WorkflowHandler) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
+	var req types.TestSessionWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.WriteError(w, apierrors.NewBadRequestError("Invalid request body: "+err.Error(), r.URL.Path))
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		apierrors.WriteError(w, apierrors.NewValidationError(err, r.URL.Path))
+		return
+	}
+
+	device := database.Device{}
+	var result *gorm.DB
+	missing := fmt.Sprintf("Device %q does not exist", req.Metadata.Device)
+	if req.Metadata.DeviceID != 0 {
+		result = namespaced(h.db, r).First(&device, req.Metadata.DeviceID)
+		missing = fmt.Sprintf("Device with ID %d does not exist", req.Metadata.DeviceID)
+	} else {
+		result = namespaced(h.db, r).Where("name = ?", req.Metadata.Device).First(&device)
+	}
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		apierrors.WriteError(w, apierrors.NewUnprocessableEntityError(missing, r.URL.Path))
+		return
+	}
+	if result.Error != nil {
+		apierrors.WriteError(w, apierrors.NewDatabaseError(result.Error, r.URL.Path))
+		return
+	}
+
+	resp := types.TestSessionWorkflowResponse{
+		DeviceID:  device.ID,
+		Scenarios: []types.WorkflowScenarioRef{},
+	}
+
+	var resolver *conditionResolver
+	testSession := database.TestSession{}
+	scenarios := []database.Scenario{}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		resolver = newConditionResolver(tx, device.Namespace)
+		for _, condition := range req.Spec.Conditions {
+			if _, err := resolver.condition(condition.Name, condition.Description); err != nil {
+				return err
+			}
+			for _, value := range condition.Values {
+				if _, err := resolver.value(condition.Name, value); err != nil {
+					return err
+				}
+			}
+		}
+
+		metadata, err := metadataOf(types.Metadata{Description: req.Spec.TestSession.Description, Labels: req.Spec.TestSession.Labels})
+		if err != nil {
+			return err
+		}
+		testSession = database.TestSession{
+			Namespace: device.Namespace,
+			Name:      req.Spec.TestSession.Name,
+			DeviceID:  device.ID,
+			Metadata:  metadata,
+		}
+		if err := tx.Create(&testSession).Error; err != nil {
+			return conflictOn(err, testSessionConflict(&testSession))
+		}
+		resp.TestSessionID = testSession.ID
+		resp.TestSessionName = testSession.Name
+
+		for _, spec := range req.Spec.Scenarios {
+			conditionNames := make([]string, 0, len(spec.Conditions))
+			for name := range spec.Conditions {
+				conditionNames = append(conditionNames, name)
+			}
+			sort.Strings(conditionNames)
+
+			conditionValueIDs := make([]uint, 0, len(conditionNames))
+			for _, name := range conditionNames {
+				id, err := resolver.value(name, spec.Conditions[name])
+				if err != nil {
+					return err
+				}
+				conditionValueIDs = append(conditionValueIDs, id)
+			}
+
+			metadata, err := metadataOf(types.Metadata{Description: spec.Description, Labels: spec.Labels})
+			if err != nil {
+				return err
+			}
+			scenario := database.Scenario{
+				Namespace:     device.Namespace,
+				Name:          spec.Name,
+				TestSessionID: testSession.ID,
+				Metadata:      metadata,
+			}
+			if err := createScenarioWithConditionValues(tx, &scenario, conditionValueIDs); err != nil {
+				return err
+			}
+
+			scenarios = append(scenarios, scenario)
+			resp.Scenarios = append(resp.Scenarios, types.WorkflowScenarioRef{ID: scenario.ID, Name: scenario.Name})
+			resp.Created.ScenarioConditions += len(conditionValueIDs)
+		}
+		resp.Created.Scenarios = len(resp.Scenarios)
+		resp.Created.Conditions = resolver.created.Conditions
+		resp.Created.ConditionValues = resolver.created.ConditionValues
+		resp.Reused = resolver.reused
+
+		return nil
+	})
+	if err != nil {
+		writeDatabaseError(w, r, err)
+		return
+	}
+	for _, object := range resolver.added {
+		h.events.Publish(watch.Added, device.Namespace, object)
+	}
+	h.events.Publish(watch.Added, testSession.Namespace, testSession)
+	h.hooks.Notify(testSession.Namespace, webhook.TestSessionCreated, testSession)
+	for _, scenario := range scenarios {
+		h.events.Publish(watch.Added, scenario.Namespace, scenario)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,274 @@
+// Package labels validates the labels and annotations of resources and
+// parses label selectors such as "campaign=Q3,firmware!=1.3".
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxNameLength bounds label names and values.
+	maxNameLength = 63
+	// maxPrefixLength bounds the DNS subdomain prefix of a key.
+	maxPrefixLength = 253
+	// maxAnnotationsSize bounds the total size of the annotations of a
+	// resource.
+	maxAnnotationsSize = 64 * 1024
+)
+
+var (
+	namePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	prefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// Error is a label or annotation a resource cannot have.
+type Error struct {
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// ValidateKey checks a label or annotation key: a name of at most 63
+// alphanumerics, '-', '_' and '.', optionally prefixed with a DNS subdomain
+// and '/', as in "lab.example.com/campaign".
+func ValidateKey(key string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if len(prefix) > maxPrefixLength || !prefixPattern.MatchString(prefix) {
+			return fmt.Errorf("key %q must have a DNS subdomain as prefix", key)
+		}
+		name = rest
+	}
+	if len(name) > maxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("key %q must be at most 63 alphanumerics, '-', '_' or '.', starting and ending with an alphanumeric", key)
+	}
+	return nil
+}
+
+// ValidateValue checks a label value: empty or at most 63 alphanumerics, '-',
+// '_' and '.', starting and ending with an alphanumeric.
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxNameLength || !namePattern.MatchString(value) {
+		return fmt.Errorf("label value %q must be at most 63 alphanumerics, '-', '_' or '.', starting and ending with an alphanumeric", value)
+	}
+	return nil
+}
+
+// Validate checks the labels and annotations of a resource and returns an
+// *Error for the first problem. Annotation values are free-form but limited
+// in total size.
+func Validate(labels, annotations map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if err := ValidateKey(key); err != nil {
+			return &Error{Detail: "label " + err.Error()}
+		}
+		if err := ValidateValue(labels[key]); err != nil {
+			return &Error{Detail: err.Error()}
+		}
+	}
+
+	size := 0
+	for _, key := range sortedKeys(annotations) {
+		if err := ValidateKey(key); err != nil {
+			return &Error{Detail: "annotation " + err.Error()}
+		}
+		size += len(key) + len(annotations[key])
+	}
+	if size > maxAnnotationsSize {
+		return &Error{Detail: fmt.Sprintf("annotations must not exceed %d bytes in total", maxAnnotationsSize)}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Operator is how a requirement compares a label.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is one comma-separated term of a selector.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector selects the resources whose labels meet all of its requirements.
+// The zero Selector selects everything.
+type Selector []Requirement
+
+// Parse reads a selector in the syntax of Kubernetes equality- and set-based
+// selectors: "key=value" (or "=="), "key!=value", "key in (a,b)",
+// "key notin (a,b)", "key" and "!key", separated by commas. A resource
+// without the key passes "!=" and "notin".
+func Parse(selector string) (Selector, error) {
+	terms, err := splitTerms(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := Selector{}
+	for _, term := range terms {
+		requirement, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, requirement)
+	}
+	return parsed, nil
+}
+
+// splitTerms splits at the commas outside of parentheses.
+func splitTerms(selector string) ([]string, error) {
+	terms := []string{}
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("label selector %q has an unmatched ')'", selector)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("label selector %q has an unmatched '('", selector)
+	}
+	terms = append(terms, selector[start:])
+
+	for i := range terms {
+		terms[i] = strings.TrimSpace(terms[i])
+		if terms[i] == "" {
+			return nil, fmt.Errorf("label selector %q has an empty term", selector)
+		}
+	}
+	return terms, nil
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	if key, ok := strings.CutPrefix(term, "!"); ok {
+		return requirement(strings.TrimSpace(key), DoesNotExist, nil)
+	}
+
+	if i := strings.IndexAny(term, "!="); i >= 0 {
+		key, value := term[:i], term[i:]
+		operator := Equals
+		switch {
+		case strings.HasPrefix(value, "!="):
+			operator, value = NotEquals, value[2:]
+		case strings.HasPrefix(value, "=="):
+			value = value[2:]
+		case strings.HasPrefix(value, "="):
+			value = value[1:]
+		default:
+			return Requirement{}, fmt.Errorf("label selector term %q has an invalid operator", term)
+		}
+		return requirement(strings.TrimSpace(key), operator, []string{strings.TrimSpace(value)})
+	}
+
+	if open := strings.Index(term, "("); open >= 0 {
+		if !strings.HasSuffix(term, ")") {
+			return Requirement{}, fmt.Errorf("label selector term %q must end with ')'", term)
+		}
+		fields := strings.Fields(term[:open])
+		if len(fields) != 2 || (fields[1] != string(In) && fields[1] != string(NotIn)) {
+			return Requirement{}, fmt.Errorf("label selector term %q must look like key in (a,b) or key notin (a,b)", term)
+		}
+		values := strings.Split(term[open+1:len(term)-1], ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		return requirement(fields[0], Operator(fields[1]), values)
+	}
+
+	return requirement(term, Exists, nil)
+}
+
+func requirement(key string, operator Operator, values []string) (Requirement, error) {
+	if err := ValidateKey(key); err != nil {
+		return Requirement{}, fmt.Errorf("label selector %w", err)
+	}
+	for _, value := range values {
+		if err := ValidateValue(value); err != nil {
+			return Requirement{}, fmt.Errorf("label selector: %w", err)
+		}
+	}
+	return Requirement{Key: key, Operator: operator, Values: values}, nil
+}
+
+// Matches reports whether labels meet every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		value, ok := labels[requirement.Key]
+		switch requirement.Operator {
+		case Equals, In:
+			if !ok || !slices.Contains(requirement.Values, value) {
+				return false
+			}
+		case NotEquals, NotIn:
+			if ok && slices.Contains(requirement.Values, value) {
+				return false
+			}
+		case Exists:
+			if !ok {
+				return false
+			}
+		case DoesNotExist:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Where returns the SQL condition selecting the rows whose JSONB column meets
+// the requirement.
+func (r Requirement) Where(column string) (string, []interface{}) {
+	value := column + " ->> ?"
+	switch r.Operator {
+	case Equals:
+		return value + " = ?", []interface{}{r.Key, r.Values[0]}
+	case NotEquals:
+		return value + " IS DISTINCT FROM ?", []interface{}{r.Key, r.Values[0]}
+	case In:
+		return value + " IN ?", []interface{}{r.Key, r.Values}
+	case NotIn:
+		return "(" + value + " IS NULL OR " + value + " NOT IN ?)", []interface{}{r.Key, r.Key, r.Values}
+	case Exists:
+		return value + " IS NOT NULL", []interface{}{r.Key}
+	default:
+		return value + " IS NULL", []interface{}{r.Key}
+	}
+}
@@ -0,0 +1,166 @@
+package labels
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+		wantErr  string
+	}{
+		{selector: "campaign=Q3", want: Selector{{Key: "campaign", Operator: Equals, Values: []string{"Q3"}}}},
+		{selector: "campaign==Q3", want: Selector{{Key: "campaign", Operator: Equals, Values: []string{"Q3"}}}},
+		{selector: "firmware != 1.3", want: Selector{{Key: "firmware", Operator: NotEquals, Values: []string{"1.3"}}}},
+		{selector: "tier in (a, b)", want: Selector{{Key: "tier", Operator: In, Values: []string{"a", "b"}}}},
+		{selector: "tier notin (a,b)", want: Selector{{Key: "tier", Operator: NotIn, Values: []string{"a", "b"}}}},
+		{selector: "calibrated", want: Selector{{Key: "calibrated", Operator: Exists}}},
+		{selector: "!retired", want: Selector{{Key: "retired", Operator: DoesNotExist}}},
+		{selector: "lab.example.com/campaign=Q3", want: Selector{{Key: "lab.example.com/campaign", Operator: Equals, Values: []string{"Q3"}}}},
+		{selector: "owner=", want: Selector{{Key: "owner", Operator: Equals, Values: []string{""}}}},
+		{
+			selector: "campaign=Q3,tier in (a,b), !retired",
+			want: Selector{
+				{Key: "campaign", Operator: Equals, Values: []string{"Q3"}},
+				{Key: "tier", Operator: In, Values: []string{"a", "b"}},
+				{Key: "retired", Operator: DoesNotExist},
+			},
+		},
+		{selector: "", wantErr: "empty term"},
+		{selector: "campaign=Q3,", wantErr: "empty term"},
+		{selector: "tier in (a,b", wantErr: "unmatched '('"},
+		{selector: "tier in a,b)", wantErr: "unmatched ')'"},
+		{selector: "tier in (a,b) x", wantErr: "must end with ')'"},
+		{selector: "tier within (a,b)", wantErr: "key in (a,b) or key notin (a,b)"},
+		{selector: "campaign!Q3", wantErr: "invalid operator"},
+		{selector: "-campaign=Q3", wantErr: `key "-campaign"`},
+		{selector: "Lab.Example/campaign=Q3", wantErr: "DNS subdomain"},
+		{selector: "campaign=Q 3", wantErr: `label value "Q 3"`},
+		{selector: "tier in (a,-b)", wantErr: `label value "-b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := Parse(tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want one containing %q", tt.selector, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.selector, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"campaign": "Q3", "firmware": "1.4", "tier": "b"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "campaign=Q3", want: true},
+		{selector: "campaign=Q4", want: false},
+		{selector: "owner=lab", want: false},
+		{selector: "firmware!=1.3", want: true},
+		{selector: "firmware!=1.4", want: false},
+		{selector: "owner!=lab", want: true},
+		{selector: "tier in (a,b)", want: true},
+		{selector: "tier in (a,c)", want: false},
+		{selector: "owner in (lab)", want: false},
+		{selector: "tier notin (a,c)", want: true},
+		{selector: "tier notin (b)", want: false},
+		{selector: "owner notin (lab)", want: true},
+		{selector: "campaign", want: true},
+		{selector: "owner", want: false},
+		{selector: "!owner", want: true},
+		{selector: "!campaign", want: false},
+		{selector: "campaign=Q3,firmware!=1.3,tier in (b)", want: true},
+		{selector: "campaign=Q3,firmware!=1.4", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := selector.Matches(labels); got != tt.want {
+				t.Errorf("%q matches %v = %v, want %v", tt.selector, labels, got, tt.want)
+			}
+		})
+	}
+
+	if !(Selector{}).Matches(nil) {
+		t.Error("the empty selector does not match a resource without labels")
+	}
+	if selector, _ := Parse("!retired"); !selector.Matches(nil) {
+		t.Error("!retired does not match a resource without labels")
+	}
+}
+
+func TestRequirementWhere(t *testing.T) {
+	tests := []struct {
+		selector  string
+		wantSQL   string
+		wantValue []interface{}
+	}{
+		{selector: "campaign=Q3", wantSQL: "labels ->> ? = ?", wantValue: []interface{}{"campaign", "Q3"}},
+		{selector: "campaign!=Q3", wantSQL: "labels ->> ? IS DISTINCT FROM ?", wantValue: []interface{}{"campaign", "Q3"}},
+		{selector: "tier in (a,b)", wantSQL: "labels ->> ? IN ?", wantValue: []interface{}{"tier", []string{"a", "b"}}},
+		{selector: "tier notin (a,b)", wantSQL: "(labels ->> ? IS NULL OR labels ->> ? NOT IN ?)", wantValue: []interface{}{"tier", "tier", []string{"a", "b"}}},
+		{selector: "campaign", wantSQL: "labels ->> ? IS NOT NULL", wantValue: []interface{}{"campaign"}},
+		{selector: "!campaign", wantSQL: "labels ->> ? IS NULL", wantValue: []interface{}{"campaign"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := Parse(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, values := selector[0].Where("labels")
+			if sql != tt.wantSQL || !reflect.DeepEqual(values, tt.wantValue) {
+				t.Errorf("Where() = %q %v, want %q %v", sql, values, tt.wantSQL, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		wantErr     string
+	}{
+		{name: "valid", labels: map[string]string{"lab.example.com/campaign": "Q3", "tier": ""}, annotations: map[string]string{"note": "free text, with spaces"}},
+		{name: "label key", labels: map[string]string{"bad key": "x"}, wantErr: `label key "bad key"`},
+		{name: "label value", labels: map[string]string{"tier": "a/b"}, wantErr: `label value "a/b"`},
+		{name: "long label value", labels: map[string]string{"tier": strings.Repeat("a", 64)}, wantErr: "label value"},
+		{name: "annotation key", annotations: map[string]string{"/note": "x"}, wantErr: `annotation key "/note"`},
+		{name: "annotations size", annotations: map[string]string{"note": strings.Repeat("x", maxAnnotationsSize)}, wantErr: "must not exceed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.labels, tt.annotations)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			var invalid *Error
+			if !errors.As(err, &invalid) || !strings.Contains(invalid.Detail, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want an *Error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package lifecycle
+
+import (
+	"database"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// transitions lists, for every scenario status, the statuses it may move to.
+// COMPLETE and ABORTED are terminal.
+var transitions = map[database.Status][]database.Status{
+	database.StatusInactive: {database.StatusActive, database.StatusAborted},
+	database.StatusActive:   {database.StatusInactive, database.StatusCompleted, database.StatusAborted},
+}
+
+// TransitionError is returned when a scenario cannot move between two statuses.
+type TransitionError struct {
+	From database.Status
+	To   database.Status
+}
+
+func (e *TransitionError) Error() string {
+	if e.From == e.To {
+		return fmt.Sprintf("scenario is already %s", e.To)
+	}
+	return fmt.Sprintf("scenario cannot transition from %s to %s", e.From, e.To)
+}
+
+// ActiveScenarioError is returned when another scenario is already active on
+// the device a scenario is being activated for.
+type ActiveScenarioError struct {
+	DeviceID uint
+	Active   database.Scenario
+}
+
+func (e *ActiveScenarioError) Error() string {
+	return fmt.Sprintf("scenario %d (%s) is already active on device %d", e.Active.ID, e.Active.Name, e.DeviceID)
+}
+
+// CurrentStatus returns the effective status of a scenario. Rows created
+// before the status column had a default are treated as INACTIVE.
+func CurrentStatus(scenario *database.Scenario) database.Status {
+	if scenario.Status == "" {
+		return database.StatusInactive
+	}
+	return scenario.Status
+}
+
+// CanTransition reports whether a scenario may move from one status to another.
+func CanTransition(from, to database.Status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition locks the scenario row, moves it to the given status and records
+// the change in the scenario history. It must be called inside a transaction.
+func Transition(tx *gorm.DB, id uint, to database.Status, reason string) (*database.Scenario, error) {
+	scenario := database.Scenario{}
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&scenario, id).Error; err != nil {
+		return nil, err
+	}
+
+	if err := apply(tx, &scenario, to, reason); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// Activate moves the scenario with the given ID to ACTIVE while keeping at
+// most one active scenario per device. The device row is locked first so that
+// concurrent activations for the same device are serialized. When preempt is
+// set, a scenario already active on the device is moved back to INACTIVE in
+// the same transaction; otherwise an ActiveScenarioError is returned. The
+// preempted scenarios are returned along with the activated one.
+func Activate(tx *gorm.DB, id uint, reason string, preempt bool) (*database.Scenario, []database.Scenario, error) {
+	scenario := database.Scenario{}
+	if err := tx.Preload("TestSession").First(&scenario, id).Error; err != nil {
+		return nil, nil, err
+	}
+	if scenario.TestSession == nil {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	deviceID := scenario.TestSession.DeviceID
+
+	device := database.Device{}
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&device, deviceID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&scenario, id).Error; err != nil {
+		return nil, nil, err
+	}
+	if from := CurrentStatus(&scenario); !CanTransition(from, database.StatusActive) {
+		return nil, nil, &TransitionError{From: from, To: database.StatusActive}
+	}
+
+	active, err := activeOn(tx, deviceID, []uint{id})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range active {
+		if !preempt {
+			return nil, nil, &ActiveScenarioError{DeviceID: deviceID, Active: active[i]}
+		}
+		if err := apply(tx, &active[i], database.StatusInactive, fmt.Sprintf("preempted by scenario %d", id)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := apply(tx, &scenario, database.StatusActive, reason); err != nil {
+		return nil, nil, err
+	}
+	return &scenario, active, nil
+}
+
+// Join checks that scenarios can join the device with the given ID, as when
+// they are moved to one of its test sessions or when their session is moved
+// to it, without the device ending up with two active scenarios. When one of
+// scenarios is active, the device row is locked as in Activate and an
+// ActiveScenarioError is returned if another scenario is already active on
+// it. It must be called inside a transaction.
+func Join(tx *gorm.DB, deviceID uint, scenarios []database.Scenario) error {
+	joining := []uint{}
+	for _, scenario := range scenarios {
+		if CurrentStatus(&scenario) == database.StatusActive {
+			joining = append(joining, scenario.ID)
+		}
+	}
+	if len(joining) == 0 {
+		return nil
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&database.Device{}, deviceID).Error; err != nil {
+		return err
+	}
+	active, err := activeOn(tx, deviceID, joining)
+	if err != nil {
+		return err
+	}
+	if len(active) > 0 {
+		return &ActiveScenarioError{DeviceID: deviceID, Active: active[0]}
+	}
+	return nil
+}
+
+// activeOn locks and returns the active scenarios of the device with the
+// given ID, leaving out those whose IDs are in except.
+func activeOn(tx *gorm.DB, deviceID uint, except []uint) ([]database.Scenario, error) {
+	active := []database.Scenario{}
+	err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Table: clause.Table{Name: clause.CurrentTable}}).
+		Joins("JOIN test_sessions ON test_sessions.id = scenarios.test_session_id AND test_sessions.deleted_at IS NULL").
+		Where("test_sessions.device_id = ? AND scenarios.status = ? AND scenarios.id NOT IN ?", deviceID, database.StatusActive, except).
+		Find(&active).Error
+	return active, err
+}
+
+// apply performs the transition on an already locked scenario.
+func apply(tx *gorm.DB, scenario *database.Scenario, to database.Status, reason string) error {
+	from := CurrentStatus(scenario)
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+
+	if err := tx.Model(scenario).Update("status", to).Error; err != nil {
+		return err
+	}
+
+	transition := database.ScenarioTransition{
+		ScenarioID: scenario.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		OccurredAt: time.Now().UTC(),
+	}
+	return tx.Create(&transition).Error
+}
+
+// Note records an event that does not change the status of a scenario in
+// its history. It must be called inside a transaction.
+func Note(tx *gorm.DB, scenario *database.Scenario, reason string) error {
+	status := CurrentStatus(scenario)
+	transition := database.ScenarioTransition{
+		ScenarioID: scenario.ID,
+		FromStatus: status,
+		ToStatus:   status,
+		Reason:     reason,
+		OccurredAt: time.Now().UTC(),
+	}
+	return tx.Create(&transition).Error
+}
+
+// History returns the recorded transitions of a scenario in the order they happened.
+func History(db *gorm.DB, scenarioID uint) ([]database.ScenarioTransition, error) {
+	history := []database.ScenarioTransition{}
+	err := db.Where("scenario_id = ?", scenarioID).Order("occurred_at ASC, id ASC").Find(&history).Error
+	return history, err
+}
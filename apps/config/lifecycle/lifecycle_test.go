@@ -0,0 +1,60 @@
+package lifecycle
+
+import (
+	"database"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	statuses := []database.Status{database.StatusInactive, database.StatusActive, database.StatusCompleted, database.StatusAborted}
+	allowed := map[[2]database.Status]bool{
+		{database.StatusInactive, database.StatusActive}:  true,
+		{database.StatusInactive, database.StatusAborted}: true,
+		{database.StatusActive, database.StatusInactive}:  true,
+		{database.StatusActive, database.StatusCompleted}: true,
+		{database.StatusActive, database.StatusAborted}:   true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]database.Status{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	if CanTransition("", database.StatusActive) {
+		t.Error("CanTransition allowed a transition from an empty status; callers pass CurrentStatus")
+	}
+}
+
+func TestCurrentStatus(t *testing.T) {
+	tests := []struct {
+		status database.Status
+		want   database.Status
+	}{
+		{status: "", want: database.StatusInactive},
+		{status: database.StatusActive, want: database.StatusActive},
+		{status: database.StatusCompleted, want: database.StatusCompleted},
+	}
+	for _, tt := range tests {
+		if got := CurrentStatus(&database.Scenario{Status: tt.status}); got != tt.want {
+			t.Errorf("CurrentStatus(%q) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  TransitionError
+		want string
+	}{
+		{err: TransitionError{From: database.StatusActive, To: database.StatusActive}, want: "scenario is already ACTIVE"},
+		{err: TransitionError{From: database.StatusCompleted, To: database.StatusActive}, want: "scenario cannot transition from COMPLETE to ACTIVE"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
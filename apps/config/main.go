@@ -3,11 +3,16 @@ package main
 import (
 	"net/http"
 
+	"config/acquisition"
+	"config/auth"
 	"config/server"
+	"config/watch"
+	"config/webhook"
 	"database"
 
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"os/signal"
@@ -46,9 +51,43 @@ func run() error {
 	// Set up router, database and app server.
 	r := chi.NewRouter()
 	db := database.Connect()
-	db.AutoMigrate(&database.Device{}, &database.TestSession{}, &database.Scenario{}, &database.ScenarioCondition{}, &database.ConditionValue{})
+	if err := checkUniqueNames(db); err != nil {
+		return err
+	}
+	err = db.AutoMigrate(&database.Device{}, &database.TestSession{}, &database.Condition{}, &database.Scenario{}, &database.ScenarioCondition{}, &database.ConditionValue{}, &database.ScenarioTransition{}, &database.APIToken{}, &database.Namespace{}, &database.Webhook{}, &database.WebhookDelivery{})
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if err := migrateNamespaces(db); err != nil {
+		return err
+	}
+
+	// CONFIG_BOOTSTRAP_TOKEN seeds an admin token used to issue the real ones.
+	if token := os.Getenv("CONFIG_BOOTSTRAP_TOKEN"); token != "" {
+		if err := auth.EnsureBootstrapToken(db, token); err != nil {
+			return err
+		}
+	}
+
+	hooks := webhook.NewDispatcher(db)
+	go hooks.Run(ctx)
+
+	// events carries the changes written by the handlers and the scheduler
+	// to the clients watching a list.
+	events := watch.NewBroker()
+
+	// CONFIG_ACQUISITION_INTERVAL overrides how often scenario targets are
+	// checked, e.g. "2s".
+	interval := acquisition.DefaultInterval
+	if value := os.Getenv("CONFIG_ACQUISITION_INTERVAL"); value != "" {
+		if interval, err = time.ParseDuration(value); err != nil {
+			return fmt.Errorf("CONFIG_ACQUISITION_INTERVAL: %w", err)
+		}
+	}
+	scheduler := acquisition.NewScheduler(db, events, hooks, interval)
+	go scheduler.Run(ctx)
 
-	appSrv := server.NewServer(db, r)
+	appSrv := server.NewServer(db, r, events, hooks)
 	appSrv.Start()
 
 	// Start HTTP server.
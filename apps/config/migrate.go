@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// uniqueName is a unique index on the live rows of a table that older
+// databases were created without.
+type uniqueName struct {
+	model   interface{}
+	index   string
+	columns []string
+}
+
+var uniqueNames = []uniqueName{
+	{&database.Device{}, "idx_devices_namespace_name", []string{"namespace", "name"}},
+	{&database.Condition{}, "idx_conditions_namespace_name", []string{"namespace", "name"}},
+	{&database.TestSession{}, "idx_test_sessions_device_name", []string{"device_id", "name"}},
+	{&database.Scenario{}, "idx_scenarios_test_session_name", []string{"test_session_id", "name"}},
+	{&database.ConditionValue{}, "idx_condition_values_condition_value", []string{"condition_id", "value"}},
+}
+
+// checkUniqueNames fails with the conflicting names when live rows would
+// break a unique index AutoMigrate is about to create. Creating the index
+// would fail halfway through the migration instead, leaving the tables after
+// it unmigrated. A column the table does not have yet is left out: the
+// namespace of devices and conditions, for instance, is filled in with the
+// default for every existing row.
+func checkUniqueNames(db *gorm.DB) error {
+	migrator := db.Migrator()
+	conflicts := []string{}
+	for _, unique := range uniqueNames {
+		if !migrator.HasTable(unique.model) || migrator.HasIndex(unique.model, unique.index) {
+			continue
+		}
+		columns := []string{}
+		for _, column := range unique.columns {
+			if migrator.HasColumn(unique.model, column) {
+				columns = append(columns, column)
+			}
+		}
+
+		rows := []map[string]interface{}{}
+		err := db.Model(unique.model).
+			Select(strings.Join(columns, ", ") + ", COUNT(*) AS count").
+			Group(strings.Join(columns, ", ")).
+			Having("COUNT(*) > 1").
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			values := make([]string, len(columns))
+			for i, column := range columns {
+				values[i] = fmt.Sprintf("%s=%v", column, row[column])
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s (%v rows)", unique.index, strings.Join(values, " "), row["count"]))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("rename or delete the duplicate rows before migrating:\n\t%s", strings.Join(conflicts, "\n\t"))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"database"
+
+	"gorm.io/gorm"
+)
+
+// migrateNamespaces creates the default namespace and drops the global name
+// indexes that were replaced by per-namespace ones. Existing rows get the
+// default namespace through the column default.
+func migrateNamespaces(db *gorm.DB) error {
+	namespace := database.Namespace{}
+	err := db.Where(database.Namespace{Name: database.DefaultNamespace}).FirstOrCreate(&namespace).Error
+	if err != nil {
+		return err
+	}
+
+	migrator := db.Migrator()
+	if migrator.HasIndex(&database.Device{}, "idx_devices_name") {
+		if err := migrator.DropIndex(&database.Device{}, "idx_devices_name"); err != nil {
+			return err
+		}
+	}
+	if migrator.HasIndex(&database.Condition{}, "idx_conditions_name") {
+		if err := migrator.DropIndex(&database.Condition{}, "idx_conditions_name"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "github.com/neuro-lab/errors"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// stringParams are the path parameters that are not numeric IDs.
+var stringParams = map[string]bool{"ns": true, "name": true}
+
+// Endpoint documents the bodies and query parameters of a route.
+type Endpoint struct {
+	Summary string
+	// Request is a value of the request body type, nil when the route takes
+	// no body.
+	Request interface{}
+	// Response is a value of the success response body type, nil when the
+	// route answers without a body.
+	Response interface{}
+	// Status is the success status code, 200 when zero.
+	Status int
+	Query  []Parameter
+	// Public routes can be called without a bearer token.
+	Public bool
+}
+
+// Route is a registered route. Endpoint is nil for routes that are not
+// documented; they are still listed, with an untyped response.
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Endpoint    *Endpoint
+}
+
+// QueryParam describes an optional query parameter of the given JSON
+// Schema type.
+func QueryParam(name, typ, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
+
+// Build generates the document for the given routes. Paths are relative to
+// serverURL. Every operation answers errors with apierrors.ErrorResponse.
+func Build(info Info, serverURL string, routes []Route) *Document {
+	schemas := NewSchemas()
+	errorSchema := schemas.For(reflect.TypeOf(apierrors.ErrorResponse{}))
+
+	doc := &Document{
+		OpenAPI:  "3.0.3",
+		Info:     info,
+		Servers:  []Server{{URL: serverURL}},
+		Security: []SecurityRequirement{{"bearerAuth": {}}},
+		Paths:    map[string]PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	for _, route := range routes {
+		endpoint := route.Endpoint
+		if endpoint == nil {
+			endpoint = &Endpoint{Response: map[string]interface{}{}}
+		}
+
+		op := &Operation{
+			OperationID: route.OperationID,
+			Summary:     endpoint.Summary,
+			Tags:        []string{tag(route.Path)},
+			Parameters:  pathParameters(route.Path),
+			Responses: map[string]Response{
+				"default": {
+					Description: "Problem details (RFC 7807)",
+					Content:     map[string]MediaType{"application/json": {Schema: errorSchema}},
+				},
+			},
+		}
+		op.Parameters = append(op.Parameters, endpoint.Query...)
+
+		if endpoint.Request != nil {
+			schema := schemas.For(reflect.TypeOf(endpoint.Request))
+			content := map[string]MediaType{"application/json": {Schema: schema}}
+			if route.Method == http.MethodPatch {
+				content["application/merge-patch+json"] = MediaType{Schema: schema}
+			}
+			op.RequestBody = &RequestBody{Required: true, Content: content}
+		}
+
+		status := endpoint.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := Response{Description: http.StatusText(status)}
+		if endpoint.Response != nil {
+			response.Content = map[string]MediaType{
+				"application/json": {Schema: schemas.For(reflect.TypeOf(endpoint.Response))},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+
+		if endpoint.Public {
+			op.Security = &[]SecurityRequirement{}
+		}
+
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = PathItem{}
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = schemas.Components()
+	return doc
+}
+
+func pathParameters(path string) []Parameter {
+	params := []Parameter{}
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		schema := &Schema{Type: "integer", Minimum: float(0)}
+		if stringParams[match[1]] {
+			schema = &Schema{Type: "string"}
+		}
+		params = append(params, Parameter{Name: match[1], In: "path", Required: true, Schema: schema})
+	}
+	return params
+}
+
+// tag groups operations by the first path segment after any namespace prefix.
+func tag(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 2 && segments[0] == "namespaces" {
+		segments = segments[2:]
+	}
+	if segments[0] == "" {
+		return "discovery"
+	}
+	return segments[0]
+}
@@ -0,0 +1,296 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+)
+
+// dnsLabelPattern is the pattern enforced by the dns_rfc1035_label validator.
+const dnsLabelPattern = "^[a-z]([-a-z0-9]*[a-z0-9])?$"
+
+// Schemas builds schemas for Go types. Named struct types become components
+// and are referenced from the schemas that use them.
+type Schemas struct {
+	components map[string]*Schema
+}
+
+func NewSchemas() *Schemas {
+	return &Schemas{components: map[string]*Schema{}}
+}
+
+// Components returns the named schemas collected so far.
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// For returns the schema of the JSON encoding of values of type t.
+func (s *Schemas) For(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.For(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.For(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.For(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := s.components[name]; !ok {
+			// Registered before the properties are built so recursive
+			// types refer back to it.
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+// object builds the schema of a struct from its exported fields, inlining
+// embedded structs the way encoding/json does.
+func (s *Schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			embedded := s.object(field.Type)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		property := s.For(field.Type)
+		required := applyValidation(property, field.Tag.Get("validate"), t)
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// applyValidation translates the validate tag of a field into schema
+// keywords and reports whether the field is required. Rules after dive apply
+// to the items of a slice or the values of a map.
+func applyValidation(schema *Schema, tag string, parent reflect.Type) bool {
+	required := false
+	target := schema
+	inKeys := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch {
+		case name == "keys":
+			inKeys = true
+			continue
+		case name == "endkeys":
+			inKeys = false
+			continue
+		case inKeys:
+			continue
+		}
+
+		switch name {
+		case "dive":
+			if target.Items != nil {
+				target = target.Items
+			} else if target.AdditionalProperties != nil {
+				target = target.AdditionalProperties
+			}
+		case "required":
+			if target == schema {
+				required = true
+			} else if target.Type == "string" {
+				target.MinLength = count(1)
+			}
+		case "min", "gte":
+			setMinimum(target, param, false)
+		case "gt":
+			setMinimum(target, param, true)
+		case "max", "lte":
+			setMaximum(target, param, false)
+		case "lt":
+			setMaximum(target, param, true)
+		case "len":
+			setMinimum(target, param, false)
+			setMaximum(target, param, false)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				target.Enum = append(target.Enum, value)
+			}
+		case "eq":
+			target.Enum = []interface{}{param}
+		case "dns_rfc1035_label":
+			target.Pattern = dnsLabelPattern
+			target.MaxLength = count(63)
+		case "required_if", "required_without", "excluded_unless":
+			target.Description = joinSentence(target.Description, describeCondition(name, param, parent))
+		}
+	}
+	return required
+}
+
+// setMinimum applies a lower bound: a length for strings, a size for arrays
+// and objects and a value for numbers. Exclusive bounds on sizes are turned
+// into inclusive ones.
+func setMinimum(schema *Schema, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	size := value
+	if exclusive {
+		size++
+	}
+	switch schema.Type {
+	case "string":
+		schema.MinLength = count(size)
+	case "array":
+		schema.MinItems = count(size)
+	case "object":
+		schema.MinProperties = count(size)
+	case "integer", "number":
+		schema.Minimum = float(value)
+		schema.ExclusiveMinimum = exclusive
+	}
+}
+
+// setMaximum is the upper bound counterpart of setMinimum.
+func setMaximum(schema *Schema, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	size := value
+	if exclusive {
+		size--
+	}
+	switch schema.Type {
+	case "string":
+		schema.MaxLength = count(size)
+	case "array":
+		schema.MaxItems = count(size)
+	case "object":
+		schema.MaxProperties = count(size)
+	case "integer", "number":
+		schema.Maximum = float(value)
+		schema.ExclusiveMaximum = exclusive
+	}
+}
+
+// describeCondition spells out a cross-field rule, which JSON Schema in
+// OpenAPI 3.0 cannot express on a single property.
+func describeCondition(rule, param string, parent reflect.Type) string {
+	fields := strings.Fields(param)
+	if len(fields) == 0 {
+		return ""
+	}
+	other := fields[0]
+	if field, ok := parent.FieldByName(other); ok {
+		other, _ = jsonName(field)
+	}
+	switch rule {
+	case "required_if":
+		return fmt.Sprintf("Required when %s is %s.", other, strings.Join(fields[1:], " "))
+	case "required_without":
+		return fmt.Sprintf("Required when %s is not set.", other)
+	default:
+		return fmt.Sprintf("Only allowed when %s is %s.", other, strings.Join(fields[1:], " "))
+	}
+}
+
+// jsonName returns the name encoding/json uses for a field, and whether the
+// field is left out of the encoding.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+// schemaName returns the component name of a named type. Instances of generic
+// types are named after their type argument, so List[database.Device]
+// becomes DeviceList.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	base, args, ok := strings.Cut(name, "[")
+	if !ok {
+		return name
+	}
+	args = strings.TrimSuffix(args, "]")
+	if i := strings.LastIndex(args, "."); i >= 0 {
+		args = args[i+1:]
+	}
+	return args + base
+}
+
+func joinSentence(description, sentence string) string {
+	if description == "" {
+		return sentence
+	}
+	return description + " " + sentence
+}
+
+func count(value float64) *uint64 {
+	n := uint64(value)
+	return &n
+}
+
+func float(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"database"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"config/auth"
+
+	apierrors "github.com/neuro-lab/errors"
+
+	"gorm.io/gorm"
+)
+
+const apiPrefix = "/api/v1"
+
+// authenticate resolves the bearer token of the request to a principal and
+// rejects requests the principal's role is not allowed to make. Public
+// routes pass through without a token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeUnauthorized(w, r, "A bearer token is required")
+			return
+		}
+
+		apiToken := database.APIToken{}
+		err := s.db.Where("token_hash = ?", auth.HashToken(token)).First(&apiToken).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeUnauthorized(w, r, "The bearer token is invalid or has been revoked")
+			return
+		}
+		if err != nil {
+			apierrors.WriteError(w, apierrors.NewDatabaseError(err, r.URL.Path))
+			return
+		}
+		if apiToken.ExpiresAt != nil && time.Now().After(*apiToken.ExpiresAt) {
+			writeUnauthorized(w, r, "The bearer token has expired")
+			return
+		}
+
+		principal := &auth.Principal{
+			TokenID:  apiToken.ID,
+			Name:     apiToken.Name,
+			Role:     apiToken.Role,
+			DeviceID: apiToken.DeviceID,
+		}
+		if !authorize(principal, r) {
+			detail := fmt.Sprintf("Role %q may not %s %s", principal.Role, r.Method, r.URL.Path)
+			apierrors.WriteError(w, apierrors.NewForbiddenError(detail, r.URL.Path))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="neuro-lab"`)
+	apierrors.WriteError(w, apierrors.NewUnauthorizedError(detail, r.URL.Path))
+}
+
+// isPublicRoute reports whether the route can be called without a token.
+func isPublicRoute(r *http.Request) bool {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	return r.Method == http.MethodGet && (path == apiPrefix || path == apiPrefix+"/openapi.json")
+}
+
+// authorize applies the role rules:
+//   - admin may call every route
+//   - operator may call every route except token management
+//   - analyst may only read, and not the token list
+//   - device may only validate scenarios and read its own device runtime
+//
+// Every role may call /whoami.
+func authorize(principal *auth.Principal, r *http.Request) bool {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path == apiPrefix+"/whoami" {
+		return true
+	}
+	tokenRoute := path == apiPrefix+"/tokens" || strings.HasPrefix(path, apiPrefix+"/tokens/")
+	readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	switch principal.Role {
+	case database.RoleAdmin:
+		return true
+	case database.RoleOperator:
+		return !tokenRoute
+	case database.RoleAnalyst:
+		return readOnly && !tokenRoute
+	case database.RoleDevice:
+		if r.Method == http.MethodPost && path == apiPrefix+"/scenario-validation" {
+			return true
+		}
+		return principal.DeviceID != nil && readOnly &&
+			resourcePath(path) == fmt.Sprintf("%s/device/%d/runtime", apiPrefix, *principal.DeviceID)
+	default:
+		return false
+	}
+}
+
+// resourcePath strips the /namespaces/{ns} prefix from path, so a rule on a
+// resource route covers the route in every namespace.
+func resourcePath(path string) string {
+	rest, ok := strings.CutPrefix(path, apiPrefix+"/namespaces/")
+	if !ok {
+		return path
+	}
+	if _, resource, ok := strings.Cut(rest, "/"); ok {
+		return apiPrefix + "/" + resource
+	}
+	return path
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"database"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"types"
+
+	"config/openapi"
+
+	"github.com/go-chi/chi/v5"
+	apierrors "github.com/neuro-lab/errors"
+)
+
+// pageQuery documents the paging and filter parameters of every list.
+var pageQuery = []openapi.Parameter{
+	openapi.QueryParam("limit", "integer", "Page size, at most 1000"),
+	openapi.QueryParam("continue", "string", "Token of the next page returned in metadata.continue"),
+	openapi.QueryParam("name", "string", "Case-insensitive substring filter on the name"),
+	openapi.QueryParam("sort", "string", "Sort field, prefixed with - for descending order"),
+	openapi.QueryParam("created_after", "string", "Only return rows created after this RFC 3339 timestamp"),
+	openapi.QueryParam("includeDeleted", "boolean", "Also return soft-deleted rows"),
+}
+
+// listQuery documents the lists that can also be watched.
+var listQuery = append(slices.Clip(pageQuery), watchQuery, resourceVersionQuery)
+
+var (
+	watchQuery           = openapi.QueryParam("watch", "boolean", "Stream ADDED, MODIFIED and DELETED events as text/event-stream instead of returning a list")
+	resourceVersionQuery = openapi.QueryParam("resourceVersion", "string", "With watch, stream the changes made after this metadata.resourceVersion; 410 when it is too old")
+)
+
+// labeledListQuery documents the lists of the resources that carry labels.
+var labeledListQuery = append([]openapi.Parameter{
+	openapi.QueryParam("labelSelector", "string", "Only return rows whose labels match, e.g. campaign=Q3,firmware!=1.3 or tier in (a,b); also applies to watch. Only the device, test session, scenario and condition lists accept it"),
+}, listQuery...)
+
+var scenarioListQuery = append([]openapi.Parameter{
+	openapi.QueryParam("status", "string", "Comma-separated list of statuses to return"),
+	openapi.QueryParam("condition", "string", "Only return scenarios whose value of a condition passes a comparison such as temperature>=30C; may be repeated"),
+}, labeledListQuery...)
+
+var cascadeQuery = openapi.QueryParam("cascade", "boolean", "Also apply the operation to the scenarios and scenario conditions of the session")
+
+var transitionEndpoint = &openapi.Endpoint{Summary: "Change the status of a scenario", Request: types.ScenarioTransitionRequest{}}
+
+// endpoints documents the routes registered in Start. Keys are the method
+// and the chi pattern below /api/v1 with any /namespaces/{ns} prefix removed.
+var endpoints = map[string]*openapi.Endpoint{
+	"GET /":               {Summary: "List the API resources", Response: types.APIResourceList{}, Public: true},
+	"GET /whoami":         {Summary: "Describe the token of the request", Response: types.APITokenResponse{}},
+	"POST /tokens":        {Summary: "Create an API token", Request: types.CreateAPITokenRequest{}, Response: types.APITokenResponse{}, Status: http.StatusCreated},
+	"GET /tokens":         {Summary: "List the API tokens", Response: []types.APITokenResponse{}},
+	"DELETE /tokens/{id}": {Summary: "Revoke an API token", Status: http.StatusNoContent},
+
+	"POST /scenario-validation": {Summary: "Validate a scenario for data ingestion", Request: types.ValidationRequest{}, Response: database.Scenario{}},
+	"POST /export/{id}":         {Summary: "Export the processed data of a scenario"},
+
+	"POST /namespaces":        {Summary: "Create a namespace", Request: types.CreateNamespaceRequest{}, Response: database.Namespace{}, Status: http.StatusCreated},
+	"GET /namespaces":         {Summary: "List namespaces", Response: types.List[database.Namespace]{}, Query: listQuery},
+	"GET /namespaces/{ns}":    {Summary: "Get a namespace", Response: database.Namespace{}},
+	"DELETE /namespaces/{ns}": {Summary: "Delete an empty namespace", Status: http.StatusNoContent},
+	"GET /openapi.json":       {Summary: "Get this document", Response: map[string]interface{}{}, Public: true},
+	"POST /workflows":         {Summary: "Create a test session with its scenarios", Request: types.TestSessionWorkflowRequest{}, Response: types.TestSessionWorkflowResponse{}, Status: http.StatusCreated},
+	"POST /condition-library": {Summary: "Create or update conditions and their values", Request: types.ConditionLibraryRequest{}, Response: types.ConditionLibraryResponse{}},
+
+	"POST /webhooks":                {Summary: "Register a webhook", Request: types.CreateWebhookRequest{}, Response: database.Webhook{}, Status: http.StatusCreated},
+	"GET /webhooks":                 {Summary: "List webhooks", Response: types.List[database.Webhook]{}, Query: pageQuery},
+	"GET /webhooks/{id}":            {Summary: "Get a webhook", Response: database.Webhook{}},
+	"PUT /webhooks/{id}":            {Summary: "Update a webhook", Request: types.UpdateWebhookRequest{}, Response: database.Webhook{}},
+	"PATCH /webhooks/{id}":          {Summary: "Merge-patch a webhook", Request: types.UpdateWebhookRequest{}, Response: database.Webhook{}},
+	"DELETE /webhooks/{id}":         {Summary: "Delete a webhook", Status: http.StatusNoContent},
+	"GET /webhooks/{id}/deliveries": {Summary: "List the deliveries of a webhook", Response: types.List[database.WebhookDelivery]{}, Query: append([]openapi.Parameter{openapi.QueryParam("status", "string", "Comma-separated list of delivery statuses to return")}, pageQuery...)},
+
+	"POST /device":               {Summary: "Create a device", Request: types.CreateDeviceRequest{}, Response: database.Device{}, Status: http.StatusCreated},
+	"GET /device":                {Summary: "List devices", Response: types.List[database.Device]{}, Query: labeledListQuery},
+	"GET /device/{id}":           {Summary: "Get a device", Response: database.Device{}},
+	"GET /device/by-name/{name}": {Summary: "Get a device by name", Response: database.Device{}},
+	"PUT /device/{id}":           {Summary: "Replace a device", Request: types.UpdateDeviceRequest{}, Response: database.Device{}},
+	"PATCH /device/{id}":         {Summary: "Merge-patch a device", Request: types.UpdateDeviceRequest{}, Response: database.Device{}},
+	"DELETE /device/{id}":        {Summary: "Delete a device", Status: http.StatusNoContent},
+	"POST /device/{id}/restore":  {Summary: "Restore a deleted device", Response: database.Device{}},
+	"GET /device/{id}/runtime":   {Summary: "Get the runtime configuration of a device", Response: types.DeviceRuntimeConfig{}, Query: []openapi.Parameter{openapi.QueryParam("test_session_id", "integer", "Test session to describe instead of the current one")}},
+
+	"POST /test-session":                             {Summary: "Create a test session", Request: types.CreateTestSessionRequest{}, Response: database.TestSession{}, Status: http.StatusCreated},
+	"GET /test-session/{id}":                         {Summary: "Get a test session", Response: database.TestSession{}},
+	"GET /test-session/list/{deviceID}":              {Summary: "List the test sessions of a device", Response: types.List[database.TestSession]{}, Query: labeledListQuery},
+	"PUT /test-session/{id}":                         {Summary: "Replace a test session", Request: types.UpdateTestSessionRequest{}, Response: database.TestSession{}},
+	"PATCH /test-session/{id}":                       {Summary: "Merge-patch a test session", Request: types.UpdateTestSessionRequest{}, Response: database.TestSession{}},
+	"DELETE /test-session/{id}":                      {Summary: "Delete a test session", Response: types.TestSessionDeleteResponse{}, Query: []openapi.Parameter{cascadeQuery, openapi.QueryParam("dryRun", "boolean", "Report what would be deleted without deleting it")}},
+	"POST /test-session/{id}/restore":                {Summary: "Restore a deleted test session", Response: database.TestSession{}, Query: []openapi.Parameter{cascadeQuery}},
+	"POST /test-session/{id}/clone":                  {Summary: "Copy a test session with its scenarios", Request: types.CloneTestSessionRequest{}, Response: types.TestSessionCloneResponse{}, Status: http.StatusCreated},
+	"POST /test-session/{id}/generate":               {Summary: "Generate scenarios from combinations of condition values", Request: types.GenerateScenariosRequest{}, Response: types.GenerateScenariosResponse{}, Status: http.StatusCreated, Query: []openapi.Parameter{openapi.QueryParam("dryRun", "boolean", "Return the scenarios that would be created without creating them")}},
+	"GET /test-session/{id}/runtime":                 {Summary: "Get the runtime state of a test session", Response: types.TestSessionRuntime{}},
+	"PUT /test-session/{id}/order":                   {Summary: "Set the order in which the scenarios of a test session run", Request: types.ReorderScenariosRequest{}, Response: []database.Scenario{}},
+	"POST /test-session/{id}/advance":                {Summary: "Complete the active scenario of a test session and activate the next one", Request: types.ScenarioTransitionRequest{}, Response: types.TestSessionAdvanceResponse{}},
+	"GET /test-session/{id}/scenario/by-name/{name}": {Summary: "Get a scenario of a test session by name", Response: database.Scenario{}},
+
+	"POST /condition":              {Summary: "Create a condition", Request: types.CreateConditionRequest{}, Response: database.Condition{}, Status: http.StatusCreated},
+	"GET /condition":               {Summary: "List conditions", Response: types.List[database.Condition]{}, Query: labeledListQuery},
+	"GET /condition/{id}":          {Summary: "Get a condition", Response: database.Condition{}},
+	"PUT /condition/{id}":          {Summary: "Replace a condition", Request: types.UpdateConditionRequest{}, Response: database.Condition{}},
+	"PATCH /condition/{id}":        {Summary: "Merge-patch a condition", Request: types.UpdateConditionRequest{}, Response: database.Condition{}},
+	"DELETE /condition/{id}":       {Summary: "Delete a condition", Status: http.StatusNoContent},
+	"POST /condition/{id}/restore": {Summary: "Restore a deleted condition", Response: database.Condition{}},
+
+	"POST /condition-value":                   {Summary: "Create a condition value", Request: types.CreateConditionValueRequest{}, Response: database.ConditionValue{}, Status: http.StatusCreated},
+	"GET /condition-value":                    {Summary: "List condition values", Response: types.List[database.ConditionValue]{}, Query: listQuery},
+	"GET /condition-value/list/{conditionID}": {Summary: "List the values of a condition", Response: types.List[database.ConditionValue]{}, Query: listQuery},
+	"GET /condition-value/{id}":               {Summary: "Get a condition value", Response: database.ConditionValue{}},
+	"PUT /condition-value/{id}":               {Summary: "Replace a condition value", Request: types.UpdateConditionValueRequest{}, Response: database.ConditionValue{}},
+	"PATCH /condition-value/{id}":             {Summary: "Merge-patch a condition value", Request: types.UpdateConditionValueRequest{}, Response: database.ConditionValue{}},
+	"DELETE /condition-value/{id}":            {Summary: "Delete a condition value"},
+	"POST /condition-value/{id}/restore":      {Summary: "Restore a deleted condition value", Response: database.ConditionValue{}},
+
+	"POST /scenario":                       {Summary: "Create a scenario", Request: types.CreateScenarioRequest{}, Response: database.Scenario{}, Status: http.StatusCreated},
+	"POST /scenario/with-condition-values": {Summary: "Create a scenario with its condition values", Request: types.CreateScenarioWithConditionValuesRequest{}, Response: database.Scenario{}, Status: http.StatusCreated},
+	"GET /scenario/{id}":                   {Summary: "Get a scenario", Response: database.Scenario{}},
+	"GET /scenario/list/{testSessionID}":   {Summary: "List the scenarios of a test session", Response: types.List[database.Scenario]{}, Query: scenarioListQuery},
+	"PUT /scenario/{id}":                   {Summary: "Replace a scenario", Request: types.UpdateScenarioRequest{}, Response: database.Scenario{}},
+	"PATCH /scenario/{id}":                 {Summary: "Merge-patch a scenario", Request: types.UpdateScenarioRequest{}, Response: database.Scenario{}},
+	"DELETE /scenario/{id}":                {Summary: "Delete a scenario"},
+	"POST /scenario/{id}/restore":          {Summary: "Restore a deleted scenario", Response: database.Scenario{}},
+	"POST /scenario/{id}/clone":            {Summary: "Copy a scenario with its condition values", Request: types.CloneScenarioRequest{}, Response: database.Scenario{}, Status: http.StatusCreated},
+	"POST /scenario/activate/{id}": {Summary: "Activate a scenario", Request: types.ScenarioTransitionRequest{}, Query: []openapi.Parameter{
+		openapi.QueryParam("preempt", "boolean", "Deactivate the scenario currently active on the device"),
+	}},
+	"POST /scenario/deactivate/{id}": transitionEndpoint,
+	"POST /scenario/complete/{id}":   transitionEndpoint,
+	"POST /scenario/abort/{id}":      transitionEndpoint,
+	"GET /scenario/{id}/history":     {Summary: "List the status transitions of a scenario", Response: []database.ScenarioTransition{}},
+	"GET /scenario/{id}/stats":       {Summary: "Summarize the data recorded for a scenario", Response: types.ScenarioStats{}},
+
+	"POST /scenario-condition":              {Summary: "Create a scenario condition", Request: types.CreateScenarioConditionRequest{}, Response: database.ScenarioCondition{}, Status: http.StatusCreated},
+	"GET /scenario-condition/{id}":          {Summary: "Get a scenario condition", Response: database.ScenarioCondition{}},
+	"PUT /scenario-condition/{id}":          {Summary: "Replace a scenario condition", Request: types.UpdateScenarioConditionRequest{}, Response: database.ScenarioCondition{}},
+	"PATCH /scenario-condition/{id}":        {Summary: "Merge-patch a scenario condition", Request: types.UpdateScenarioConditionRequest{}, Response: database.ScenarioCondition{}},
+	"DELETE /scenario-condition/{id}":       {Summary: "Delete a scenario condition"},
+	"POST /scenario-condition/{id}/restore": {Summary: "Restore a deleted scenario condition", Response: database.ScenarioCondition{}},
+}
+
+// GetOpenAPI serves the OpenAPI document of the routes registered on the
+// router. It is generated on the first request, once every route is known.
+func (s *Server) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.openapiOnce.Do(func() {
+		s.openapi, s.openapiErr = s.buildOpenAPI()
+	})
+	if s.openapiErr != nil {
+		apierrors.WriteError(w, apierrors.NewInternalError(r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(s.openapi)
+}
+
+func (s *Server) buildOpenAPI() ([]byte, error) {
+	routes := []openapi.Route{}
+	seen := map[string]bool{}
+	err := chi.Walk(s.router, func(method, pattern string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path, key, ok := endpointKey(method, pattern)
+		if !ok {
+			return nil
+		}
+
+		operationID := handlerName(handler)
+		if operationID != "" && strings.HasPrefix(path, "/namespaces/{ns}/") {
+			operationID += "InNamespace"
+		}
+		if operationID == "" || seen[operationID] {
+			operationID = strings.ToLower(method) + strings.ReplaceAll(path, "/", "_")
+		}
+		seen[operationID] = true
+
+		routes = append(routes, openapi.Route{
+			Method:      method,
+			Path:        path,
+			OperationID: operationID,
+			Endpoint:    endpoints[key],
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := openapi.Info{Title: "neuro-lab config API", Version: "v1"}
+	return json.Marshal(openapi.Build(info, apiPrefix, routes))
+}
+
+// endpointKey returns the path of a route below /api/v1 and its key in
+// endpoints. ok is false for routes outside the API.
+func endpointKey(method, pattern string) (path, key string, ok bool) {
+	path, ok = strings.CutPrefix(pattern, apiPrefix)
+	if !ok {
+		return "", "", false
+	}
+	// Routers registered with Route("/") show up as a /* segment.
+	path = strings.TrimSuffix(strings.ReplaceAll(path, "/*", ""), "/")
+	if path == "" {
+		path = "/"
+	}
+
+	key = path
+	if rest, ok := strings.CutPrefix(path, "/namespaces/{ns}"); ok && rest != "" {
+		key = rest
+	}
+	return path, method + " " + key, true
+}
+
+// checkEndpoints reports the routes registered on the router without an
+// entry in endpoints, which would be documented as untyped operations, and
+// the entries that match no route, such as a mistyped key.
+func (s *Server) checkEndpoints() error {
+	documented := map[string]bool{}
+	missing := []string{}
+	err := chi.Walk(s.router, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		_, key, ok := endpointKey(method, pattern)
+		if !ok {
+			return nil
+		}
+		if endpoints[key] == nil {
+			missing = append(missing, key)
+		}
+		documented[key] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	unused := []string{}
+	for key := range endpoints {
+		if !documented[key] {
+			unused = append(unused, key)
+		}
+	}
+	slices.Sort(missing)
+	missing = slices.Compact(missing)
+	slices.Sort(unused)
+
+	problems := []string{}
+	if len(missing) > 0 {
+		problems = append(problems, "routes without an OpenAPI entry: "+strings.Join(missing, ", "))
+	}
+	if len(unused) > 0 {
+		problems = append(problems, "OpenAPI entries without a route: "+strings.Join(unused, ", "))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// handlerName derives an operation ID from the name of the handler method,
+// e.g. createDevice for DeviceHandler.CreateDevice.
+func handlerName(handler http.Handler) string {
+	if _, ok := handler.(http.HandlerFunc); !ok {
+		return ""
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+	if name == "" {
+		return ""
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
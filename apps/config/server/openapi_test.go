@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestServer registers the routes with nil handlers, which is enough to
+// walk them.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := &Server{router: chi.NewRouter()}
+	s.Start()
+	return s
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.checkEndpoints(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckEndpointsCatchesMistypedKeys(t *testing.T) {
+	s := newTestServer(t)
+	endpoints["GET /devcie/{id}"] = endpoints["GET /device/{id}"]
+	defer delete(endpoints, "GET /devcie/{id}")
+
+	err := s.checkEndpoints()
+	if err == nil || !strings.Contains(err.Error(), "GET /devcie/{id}") {
+		t.Errorf("checkEndpoints() error = %v, want the mistyped key reported", err)
+	}
+}
+
+func TestCheckEndpointsCatchesUndocumentedRoutes(t *testing.T) {
+	s := newTestServer(t)
+	documented := endpoints["GET /device/{id}"]
+	delete(endpoints, "GET /device/{id}")
+	defer func() { endpoints["GET /device/{id}"] = documented }()
+
+	err := s.checkEndpoints()
+	if err == nil || !strings.Contains(err.Error(), "GET /device/{id}") {
+		t.Errorf("checkEndpoints() error = %v, want the route reported", err)
+	}
+}
+
+func TestBuildOpenAPI(t *testing.T) {
+	s := newTestServer(t)
+	spec, err := s.buildOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	if err := json.Unmarshal(spec, &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/device/{id}", "/namespaces/{ns}/device/{id}"} {
+		if _, ok := document.Paths[path]["get"]; !ok {
+			t.Errorf("spec has no GET %s", path)
+		}
+	}
+}
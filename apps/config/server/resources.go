@@ -0,0 +1,394 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"types"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// namespacePrefix is the path of the routes addressing one namespace.
+const namespacePrefix = "/namespaces/{ns}"
+
+// resource describes an API resource. The registry returned by
+// Server.resources drives both route registration and discovery.
+type resource struct {
+	types.APIResource
+	routes       []route
+	subresources []subresource
+}
+
+// subresource is an operation or collection below a resource.
+type subresource struct {
+	name   string
+	kind   string
+	routes []route
+}
+
+// route serves one verb of a resource. Paths are relative to /api/v1;
+// routes of namespaced resources are served below /namespaces/{ns} as well.
+type route struct {
+	verb    string
+	method  string
+	path    string
+	handler http.HandlerFunc
+	// root routes of namespaced resources are not served per namespace.
+	root bool
+}
+
+func (s *Server) resources() []resource {
+	crud := func(path string, create, get, update, patch, del, restore http.HandlerFunc) []route {
+		return []route{
+			{verb: "create", method: http.MethodPost, path: path, handler: create},
+			{verb: "get", method: http.MethodGet, path: path + "/{id}", handler: get},
+			{verb: "update", method: http.MethodPut, path: path + "/{id}", handler: update},
+			{verb: "patch", method: http.MethodPatch, path: path + "/{id}", handler: patch},
+			{verb: "delete", method: http.MethodDelete, path: path + "/{id}", handler: del},
+			{verb: "restore", method: http.MethodPost, path: path + "/{id}/restore", handler: restore},
+		}
+	}
+	transition := func(name string, handler http.HandlerFunc) subresource {
+		return subresource{name: name, routes: []route{
+			{verb: "create", method: http.MethodPost, path: "/scenario/" + name + "/{id}", handler: handler},
+		}}
+	}
+
+	return []resource{
+		{
+			APIResource: types.APIResource{
+				Name:         "namespaces",
+				SingularName: "namespace",
+				Kind:         "Namespace",
+				ShortNames:   []string{"ns"},
+				Categories:   []string{"admin"},
+				NameField:    "name",
+				IDField:      "name",
+			},
+			routes: []route{
+				{verb: "create", method: http.MethodPost, path: "/namespaces", handler: s.namespaceHandler.CreateNamespace},
+				{verb: "list", method: http.MethodGet, path: "/namespaces", handler: s.namespaceHandler.GetNamespaces},
+				{verb: "get", method: http.MethodGet, path: namespacePrefix, handler: s.namespaceHandler.GetNamespace},
+				{verb: "delete", method: http.MethodDelete, path: namespacePrefix, handler: s.namespaceHandler.DeleteNamespace},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "tokens",
+				SingularName: "token",
+				Kind:         "APIToken",
+				Categories:   []string{"admin"},
+				NameField:    "name",
+				IDField:      "id",
+			},
+			routes: []route{
+				{verb: "create", method: http.MethodPost, path: "/tokens", handler: s.tokenHandler.CreateToken},
+				{verb: "list", method: http.MethodGet, path: "/tokens", handler: s.tokenHandler.GetTokens},
+				{verb: "delete", method: http.MethodDelete, path: "/tokens/{id}", handler: s.tokenHandler.DeleteToken},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "devices",
+				SingularName: "device",
+				Namespaced:   true,
+				Kind:         "Device",
+				Categories:   []string{"all"},
+				NameField:    "name",
+				IDField:      "id",
+			},
+			routes: append(crud("/device",
+				s.deviceHandler.CreateDevice, s.deviceHandler.GetDevice, s.deviceHandler.UpdateDevice,
+				s.deviceHandler.PatchDevice, s.deviceHandler.DeleteDevice, s.deviceHandler.RestoreDevice),
+				route{verb: "list", method: http.MethodGet, path: "/device", handler: s.deviceHandler.GetDevices},
+				route{verb: "getByName", method: http.MethodGet, path: "/device/by-name/{name}", handler: s.deviceHandler.GetDeviceByName},
+			),
+			subresources: []subresource{
+				{name: "runtime", kind: "DeviceRuntimeConfig", routes: []route{
+					{verb: "get", method: http.MethodGet, path: "/device/{id}/runtime", handler: s.runtimeHandler.GetDeviceRuntime},
+				}},
+				{name: "test-sessions", kind: "TestSession", routes: []route{
+					{verb: "list", method: http.MethodGet, path: "/test-session/list/{deviceID}", handler: s.testSessionHandler.GetTestSessionsByDevice},
+				}},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "test-sessions",
+				SingularName: "test-session",
+				Namespaced:   true,
+				Kind:         "TestSession",
+				Categories:   []string{"all"},
+				NameField:    "name",
+				IDField:      "id",
+			},
+			routes: crud("/test-session",
+				s.testSessionHandler.CreateTestSession, s.testSessionHandler.GetTestSession, s.testSessionHandler.UpdateTestSession,
+				s.testSessionHandler.PatchTestSession, s.testSessionHandler.DeleteTestSession, s.testSessionHandler.RestoreTestSession),
+			subresources: []subresource{
+				{name: "runtime", kind: "TestSessionRuntime", routes: []route{
+					{verb: "get", method: http.MethodGet, path: "/test-session/{id}/runtime", handler: s.runtimeHandler.GetTestSessionRuntime},
+				}},
+				{name: "clone", kind: "TestSession", routes: []route{
+					{verb: "create", method: http.MethodPost, path: "/test-session/{id}/clone", handler: s.testSessionHandler.CloneTestSession},
+				}},
+				{name: "generate", kind: "Scenario", routes: []route{
+					{verb: "create", method: http.MethodPost, path: "/test-session/{id}/generate", handler: s.scenarioHandler.GenerateScenarios},
+				}},
+				{name: "order", kind: "Scenario", routes: []route{
+					{verb: "update", method: http.MethodPut, path: "/test-session/{id}/order", handler: s.scenarioHandler.ReorderScenarios},
+				}},
+				{name: "advance", kind: "TestSessionAdvance", routes: []route{
+					{verb: "create", method: http.MethodPost, path: "/test-session/{id}/advance", handler: s.scenarioHandler.AdvanceTestSession},
+				}},
+				{name: "scenarios", kind: "Scenario", routes: []route{
+					{verb: "list", method: http.MethodGet, path: "/scenario/list/{testSessionID}", handler: s.scenarioHandler.GetScenariosByTestSession},
+					{verb: "getByName", method: http.MethodGet, path: "/test-session/{id}/scenario/by-name/{name}", handler: s.scenarioHandler.GetScenarioByName},
+				}},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "conditions",
+				SingularName: "condition",
+				Namespaced:   true,
+				Kind:         "Condition",
+				Categories:   []string{"all"},
+				NameField:    "name",
+				IDField:      "id",
+			},
+			routes: append(crud("/condition",
+				s.conditionHandler.CreateCondition, s.conditionHandler.GetCondition, s.conditionHandler.UpdateCondition,
+				s.conditionHandler.PatchCondition, s.conditionHandler.DeleteCondition, s.conditionHandler.RestoreCondition),
+				route{verb: "list", method: http.MethodGet, path: "/condition", handler: s.conditionHandler.GetConditions},
+			),
+			subresources: []subresource{
+				{name: "values", kind: "ConditionValue", routes: []route{
+					{verb: "list", method: http.MethodGet, path: "/condition-value/list/{conditionID}", handler: s.conditionValueHandler.GetConditionValuesByCondition},
+				}},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "condition-values",
+				SingularName: "condition-value",
+				Namespaced:   true,
+				Kind:         "ConditionValue",
+				Categories:   []string{"all"},
+				NameField:    "value",
+				IDField:      "id",
+			},
+			routes: append(crud("/condition-value",
+				s.conditionValueHandler.CreateConditionValue, s.conditionValueHandler.GetConditionValue, s.conditionValueHandler.UpdateConditionValue,
+				s.conditionValueHandler.PatchConditionValue, s.conditionValueHandler.DeleteConditionValue, s.conditionValueHandler.RestoreConditionValue),
+				route{verb: "list", method: http.MethodGet, path: "/condition-value", handler: s.conditionValueHandler.GetConditionValues},
+			),
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "scenarios",
+				SingularName: "scenario",
+				Namespaced:   true,
+				Kind:         "Scenario",
+				Categories:   []string{"all"},
+				NameField:    "name",
+				IDField:      "id",
+			},
+			routes: crud("/scenario",
+				s.scenarioHandler.CreateScenario, s.scenarioHandler.GetScenario, s.scenarioHandler.UpdateScenario,
+				s.scenarioHandler.PatchScenario, s.scenarioHandler.DeleteScenario, s.scenarioHandler.RestoreScenario),
+			subresources: []subresource{
+				{name: "with-condition-values", kind: "Scenario", routes: []route{
+					{verb: "create", method: http.MethodPost, path: "/scenario/with-condition-values", handler: s.scenarioHandler.CreateScenarioWithConditionValues},
+				}},
+				{name: "clone", kind: "Scenario", routes: []route{
+					{verb: "create", method: http.MethodPost, path: "/scenario/{id}/clone", handler: s.scenarioHandler.CloneScenario},
+				}},
+				transition("activate", s.scenarioHandler.ActivateScenario),
+				transition("deactivate", s.scenarioHandler.DeactivateScenario),
+				transition("complete", s.scenarioHandler.CompleteScenario),
+				transition("abort", s.scenarioHandler.AbortScenario),
+				{name: "history", kind: "ScenarioTransition", routes: []route{
+					{verb: "list", method: http.MethodGet, path: "/scenario/{id}/history", handler: s.scenarioHandler.GetScenarioHistory},
+				}},
+				{name: "stats", kind: "ScenarioStats", routes: []route{
+					{verb: "get", method: http.MethodGet, path: "/scenario/{id}/stats", handler: s.scenarioHandler.GetScenarioStats},
+				}},
+				{name: "export", routes: []route{
+					{verb: "create", method: http.MethodPost, path: "/export/{id}", handler: s.exportHandler.ExportData, root: true},
+				}},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "scenario-conditions",
+				SingularName: "scenario-condition",
+				Namespaced:   true,
+				Kind:         "ScenarioCondition",
+				Categories:   []string{"all"},
+				IDField:      "id",
+			},
+			routes: crud("/scenario-condition",
+				s.scenarioConditionHandler.CreateScenarioCondition, s.scenarioConditionHandler.GetScenarioCondition, s.scenarioConditionHandler.UpdateScenarioCondition,
+				s.scenarioConditionHandler.PatchScenarioCondition, s.scenarioConditionHandler.DeleteScenarioCondition, s.scenarioConditionHandler.RestoreScenarioCondition),
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "condition-libraries",
+				SingularName: "condition-library",
+				Namespaced:   true,
+				Kind:         "ConditionLibrary",
+				Categories:   []string{"workflow"},
+			},
+			routes: []route{
+				{verb: "create", method: http.MethodPost, path: "/condition-library", handler: s.conditionHandler.ApplyConditionLibrary},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "webhooks",
+				SingularName: "webhook",
+				Namespaced:   true,
+				Kind:         "Webhook",
+				ShortNames:   []string{"wh"},
+				Categories:   []string{"admin"},
+				NameField:    "name",
+				IDField:      "id",
+			},
+			routes: []route{
+				{verb: "create", method: http.MethodPost, path: "/webhooks", handler: s.webhookHandler.CreateWebhook},
+				{verb: "list", method: http.MethodGet, path: "/webhooks", handler: s.webhookHandler.GetWebhooks},
+				{verb: "get", method: http.MethodGet, path: "/webhooks/{id}", handler: s.webhookHandler.GetWebhook},
+				{verb: "update", method: http.MethodPut, path: "/webhooks/{id}", handler: s.webhookHandler.UpdateWebhook},
+				{verb: "patch", method: http.MethodPatch, path: "/webhooks/{id}", handler: s.webhookHandler.PatchWebhook},
+				{verb: "delete", method: http.MethodDelete, path: "/webhooks/{id}", handler: s.webhookHandler.DeleteWebhook},
+			},
+			subresources: []subresource{
+				{name: "deliveries", kind: "WebhookDelivery", routes: []route{
+					{verb: "list", method: http.MethodGet, path: "/webhooks/{id}/deliveries", handler: s.webhookHandler.GetWebhookDeliveries},
+				}},
+			},
+		},
+		{
+			APIResource: types.APIResource{
+				Name:         "workflows",
+				SingularName: "workflow",
+				Namespaced:   true,
+				Kind:         "TestSessionWorkflow",
+				Categories:   []string{"workflow"},
+			},
+			routes: []route{
+				{verb: "create", method: http.MethodPost, path: "/workflows", handler: s.workflowHandler.CreateWorkflow},
+			},
+		},
+	}
+}
+
+// discovery returns the discovery entries of the registry, with the verbs
+// and endpoint templates derived from the routes.
+func discovery(resources []resource) []types.APIResource {
+	list := make([]types.APIResource, 0, len(resources))
+	for _, res := range resources {
+		apiResource := res.APIResource
+		apiResource.Verbs, apiResource.Endpoints = verbEndpoints(res.Namespaced, res.routes)
+		for _, sub := range res.subresources {
+			verbs, endpoints := verbEndpoints(res.Namespaced, sub.routes)
+			apiResource.Subresources = append(apiResource.Subresources, types.APISubresource{
+				Name:      sub.name,
+				Kind:      sub.kind,
+				Verbs:     verbs,
+				Endpoints: endpoints,
+			})
+		}
+		list = append(list, apiResource)
+	}
+	return list
+}
+
+func verbEndpoints(namespaced bool, routes []route) ([]string, map[string]types.APIEndpoint) {
+	verbs := make([]string, 0, len(routes))
+	templates := make(map[string]types.APIEndpoint, len(routes))
+	for _, rt := range routes {
+		path := rt.path
+		if namespaced && !rt.root {
+			path = namespacePrefix + path
+		}
+		verbs = append(verbs, rt.verb)
+		templates[rt.verb] = types.APIEndpoint{Method: rt.method, Path: path}
+	}
+	return verbs, templates
+}
+
+// registerResources registers the routes of the registry on the /api/v1
+// router. Routes below /namespaces/{ns} require the namespace to exist;
+// namespaced resources are also served at the root for the default
+// namespace.
+func (s *Server) registerResources(r chi.Router, resources []resource) {
+	root := routeGroups{}
+	namespace := routeGroups{}
+	for _, res := range resources {
+		routes := res.routes
+		for _, sub := range res.subresources {
+			routes = append(routes, sub.routes...)
+		}
+		for _, rt := range routes {
+			if rest, ok := strings.CutPrefix(rt.path, namespacePrefix); ok {
+				namespace.add(rest, rt)
+				continue
+			}
+			root.add(rt.path, rt)
+			if res.Namespaced && !rt.root {
+				namespace.add(rt.path, rt)
+			}
+		}
+	}
+
+	r.Route(namespacePrefix, func(r chi.Router) {
+		r.Use(s.namespaceHandler.RequireNamespace)
+		namespace.register(r)
+	})
+	root.register(r)
+}
+
+// routeGroups collects routes by their first path segment, so that each
+// collection is mounted as one sub-router and answers with and without a
+// trailing slash.
+type routeGroups struct {
+	prefixes []string
+	routes   map[string][]route
+}
+
+func (g *routeGroups) add(path string, rt route) {
+	prefix, rest := path, "/"
+	if i := strings.Index(strings.TrimPrefix(path, "/"), "/"); i >= 0 {
+		prefix, rest = path[:i+1], path[i+1:]
+	}
+	if prefix == "" || prefix == "/" {
+		prefix = ""
+	}
+	if g.routes == nil {
+		g.routes = map[string][]route{}
+	}
+	if _, ok := g.routes[prefix]; !ok {
+		g.prefixes = append(g.prefixes, prefix)
+	}
+	rt.path = rest
+	g.routes[prefix] = append(g.routes[prefix], rt)
+}
+
+func (g *routeGroups) register(r chi.Router) {
+	for _, prefix := range g.prefixes {
+		routes := g.routes[prefix]
+		if prefix == "" {
+			for _, rt := range routes {
+				r.Method(rt.method, rt.path, rt.handler)
+			}
+			continue
+		}
+		r.Route(prefix, func(r chi.Router) {
+			for _, rt := range routes {
+				r.Method(rt.method, rt.path, rt.handler)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package server
 
 import (
 	"config/handlers"
+	"config/watch"
+	"config/webhook"
 
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -24,6 +27,15 @@ type Server struct {
 	scenarioValidationHandler *handlers.ScenarioValidationHandler
 	discoveryHandler          *handlers.DiscoveryHandler
 	exportHandler             *handlers.ExportHandler
+	workflowHandler           *handlers.WorkflowHandler
+	runtimeHandler            *handlers.RuntimeHandler
+	tokenHandler              *handlers.TokenHandler
+	namespaceHandler          *handlers.NamespaceHandler
+	webhookHandler            *handlers.WebhookHandler
+
+	openapiOnce sync.Once
+	openapi     []byte
+	openapiErr  error
 }
 
 func connect(topic string, partition int) (*kafka.Conn, error) {
@@ -35,23 +47,30 @@ func connect(topic string, partition int) (*kafka.Conn, error) {
 	return conn, err
 } //end connect
 
-func NewServer(db *gorm.DB, r *chi.Mux) *Server {
+// NewServer wires the handlers. The changes they write are published on
+// events, which carries them to the clients watching a list; lab events are
+// queued on hooks, which the caller runs.
+func NewServer(db *gorm.DB, r *chi.Mux, events *watch.Broker, hooks *webhook.Dispatcher) *Server {
 
 	kafkaConn, err := connect("export.notification", 0)
 	if err != nil {
 		panic(err)
 	}
 
-	deviceHandler := handlers.NewDeviceHandler(db)
-	testSessionHandler := handlers.NewTestSessionHandler(db)
-	conditionHandler := handlers.NewConditionHandler(db)
-	conditionValueHandler := handlers.NewConditionValueHandler(db)
-	scenarioHandler := handlers.NewScenarioHandler(db)
+	deviceHandler := handlers.NewDeviceHandler(db, events)
+	testSessionHandler := handlers.NewTestSessionHandler(db, events, hooks)
+	conditionHandler := handlers.NewConditionHandler(db, events)
+	conditionValueHandler := handlers.NewConditionValueHandler(db, events)
+	scenarioHandler := handlers.NewScenarioHandler(db, events, hooks)
 	scenarioConditionHandler := handlers.NewScenarioConditionHandler(db)
 	scenarioValidationHandler := handlers.NewScenarioValidationHandler(db)
-	discoveryHandler := handlers.NewDiscoveryHandler(db)
-	exportHandler := handlers.NewExportHandler(kafkaConn, db)
-	return &Server{
+	exportHandler := handlers.NewExportHandler(kafkaConn, db, hooks)
+	workflowHandler := handlers.NewWorkflowHandler(db, events, hooks)
+	runtimeHandler := handlers.NewRuntimeHandler(db)
+	tokenHandler := handlers.NewTokenHandler(db)
+	namespaceHandler := handlers.NewNamespaceHandler(db, events)
+	webhookHandler := handlers.NewWebhookHandler(db)
+	s := &Server{
 		db:                        db,
 		router:                    r,
 		deviceHandler:             deviceHandler,
@@ -61,9 +80,15 @@ func NewServer(db *gorm.DB, r *chi.Mux) *Server {
 		scenarioHandler:           scenarioHandler,
 		scenarioConditionHandler:  scenarioConditionHandler,
 		scenarioValidationHandler: scenarioValidationHandler,
-		discoveryHandler:          discoveryHandler,
 		exportHandler:             exportHandler,
+		workflowHandler:           workflowHandler,
+		runtimeHandler:            runtimeHandler,
+		tokenHandler:              tokenHandler,
+		namespaceHandler:          namespaceHandler,
+		webhookHandler:            webhookHandler,
 	}
+	s.discoveryHandler = handlers.NewDiscoveryHandler(db, discovery(s.resources()))
+	return s
 }
 
 func (s *Server) Start() {
@@ -71,62 +96,20 @@ func (s *Server) Start() {
 	s.router.Use(middleware.Logger)
 
 	s.router.Route("/api/v1", func(r chi.Router) {
+		r.Use(s.authenticate)
+
 		r.Route("/", func(r chi.Router) {
 			r.Get("/", s.discoveryHandler.GetAPIResources)
 		})
 
+		r.Get("/openapi.json", s.GetOpenAPI)
+		r.Get("/whoami", s.tokenHandler.WhoAmI)
 		r.Post("/scenario-validation", s.scenarioValidationHandler.ValidateScenario)
-		r.Post("/export/{id}", s.exportHandler.ExportData)
-		r.Route("/device", func(r chi.Router) {
-			r.Post("/", s.deviceHandler.CreateDevice)
-			r.Put("/{id}", s.deviceHandler.UpdateDevice)
-			r.Delete("/{id}", s.deviceHandler.DeleteDevice)
-			r.Get("/{id}", s.deviceHandler.GetDevice)
-			r.Get("/", s.deviceHandler.GetDevices)
-		})
-
-		r.Route("/test-session", func(r chi.Router) {
-			r.Post("/", s.testSessionHandler.CreateTestSession)
-			r.Put("/{id}", s.testSessionHandler.UpdateTestSession)
-			r.Delete("/{id}", s.testSessionHandler.DeleteTestSession)
-			r.Get("/{id}", s.testSessionHandler.GetTestSession)
-			r.Get("/list/{deviceID}", s.testSessionHandler.GetTestSessionsByDevice)
-		})
-
-		r.Route("/condition", func(r chi.Router) {
-			r.Post("/", s.conditionHandler.CreateCondition)
-			r.Put("/{id}", s.conditionHandler.UpdateCondition)
-			r.Delete("/{id}", s.conditionHandler.DeleteCondition)
-			r.Get("/{id}", s.conditionHandler.GetCondition)
-			r.Get("/", s.conditionHandler.GetConditions)
-		})
-
-		r.Route("/condition-value", func(r chi.Router) {
-			r.Post("/", s.conditionValueHandler.CreateConditionValue)
-			r.Put("/{id}", s.conditionValueHandler.UpdateConditionValue)
-			r.Delete("/{id}", s.conditionValueHandler.DeleteConditionValue)
-			r.Get("/{id}", s.conditionValueHandler.GetConditionValue)
-			r.Get("/", s.conditionValueHandler.GetConditionValues)
-			r.Get("/list/{conditionID}", s.conditionValueHandler.GetConditionValuesByCondition)
-		})
-
-		r.Route("/scenario", func(r chi.Router) {
-			r.Post("/", s.scenarioHandler.CreateScenario)
-			r.Post("/with-condition-values", s.scenarioHandler.CreateScenarioWithConditionValues)
-			r.Put("/{id}", s.scenarioHandler.UpdateScenario)
-			r.Delete("/{id}", s.scenarioHandler.DeleteScenario)
-			r.Get("/{id}", s.scenarioHandler.GetScenario)
-			r.Get("/list/{testSessionID}", s.scenarioHandler.GetScenariosByTestSession)
-			r.Post("/activate/{id}", s.scenarioHandler.ActivateScenario)
-			r.Post("/deactivate/{id}", s.scenarioHandler.DeactivateScenario)
-			r.Post("/complete/{id}", s.scenarioHandler.CompleteScenario)
-		})
 
-		r.Route("/scenario-condition", func(r chi.Router) {
-			r.Post("/", s.scenarioConditionHandler.CreateScenarioCondition)
-			r.Put("/{id}", s.scenarioConditionHandler.UpdateScenarioCondition)
-			r.Delete("/{id}", s.scenarioConditionHandler.DeleteScenarioCondition)
-			r.Get("/{id}", s.scenarioConditionHandler.GetScenarioCondition)
-		})
+		s.registerResources(r, s.resources())
 	})
+
+	if err := s.checkEndpoints(); err != nil {
+		panic(err)
+	}
 }
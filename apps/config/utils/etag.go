@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ETag returns the entity tag of a row. The row's UpdatedAt acts as its
+// resource version, so the tag changes on every write. It is truncated to
+// the microseconds Postgres stores, so that the tag of a row just written
+// matches the one it has when read back.
+func ETag(model *gorm.Model) string {
+	return fmt.Sprintf(`"%d-%s"`, model.ID, strconv.FormatInt(model.UpdatedAt.Truncate(time.Microsecond).UnixNano(), 36))
+}
+
+// SetETag sets the ETag header of the response to the tag of model.
+func SetETag(w http.ResponseWriter, model *gorm.Model) {
+	w.Header().Set("ETag", ETag(model))
+}
+
+// IfMatch reports whether the If-Match header of r matches etag. Requests
+// without the header always match.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
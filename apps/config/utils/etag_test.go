@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestETagMatchesAfterReadBack(t *testing.T) {
+	tests := []struct {
+		name      string
+		updatedAt time.Time
+	}{
+		{name: "nanoseconds", updatedAt: time.Date(2025, 3, 14, 15, 9, 26, 535897932, time.UTC)},
+		{name: "microseconds", updatedAt: time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)},
+		{name: "whole second", updatedAt: time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)},
+		{name: "local time", updatedAt: time.Date(2025, 3, 14, 15, 9, 26, 999999999, time.FixedZone("CET", 3600))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The handler answers a write with the tag of the row in
+			// memory; a following GET reads it back at the microsecond
+			// precision of Postgres.
+			written := gorm.Model{ID: 7, UpdatedAt: tt.updatedAt}
+			read := gorm.Model{ID: 7, UpdatedAt: tt.updatedAt.Truncate(time.Microsecond).UTC()}
+
+			if ETag(&written) != ETag(&read) {
+				t.Fatalf("ETag after write %s, after read %s", ETag(&written), ETag(&read))
+			}
+
+			r := httptest.NewRequest("PUT", "/", nil)
+			r.Header.Set("If-Match", ETag(&written))
+			if !IfMatch(r, ETag(&read)) {
+				t.Errorf("If-Match with the tag returned by the write does not match the stored row")
+			}
+		})
+	}
+}
+
+func TestETagChangesOnWrite(t *testing.T) {
+	before := gorm.Model{ID: 7, UpdatedAt: time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)}
+	after := gorm.Model{ID: 7, UpdatedAt: before.UpdatedAt.Add(time.Microsecond)}
+	if ETag(&before) == ETag(&after) {
+		t.Errorf("ETag did not change when UpdatedAt moved by a microsecond")
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "no header", want: true},
+		{name: "same tag", header: `"7-abc"`, want: true},
+		{name: "wildcard", header: "*", want: true},
+		{name: "one of several", header: `"7-xyz", "7-abc"`, want: true},
+		{name: "other tag", header: `"7-xyz"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			if got := IfMatch(r, `"7-abc"`); got != tt.want {
+				t.Errorf("IfMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"types"
+
+	"config/labels"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultListLimit is the page size used when ?limit= is not given.
+	DefaultListLimit = 100
+	// MaxListLimit is the largest page size a client may ask for.
+	MaxListLimit = 1000
+)
+
+// ListSpec describes the list parameters a resource supports.
+type ListSpec struct {
+	// NameColumn is the column matched by the ?name= substring filter.
+	// The filter is rejected when empty.
+	NameColumn string
+	// SortFields are the columns accepted by ?sort= in addition to id and created_at.
+	SortFields []string
+	// Status enables the ?status= filter.
+	Status bool
+	// Labels enables the ?labelSelector= filter on the labels column. Only
+	// devices, test sessions, scenarios and conditions carry labels; the
+	// other lists reject a selector.
+	Labels bool
+}
+
+// ListOptions are the parsed list query parameters.
+type ListOptions struct {
+	Limit        int
+	Name         string
+	Statuses     []string
+	CreatedAfter *time.Time
+	// LabelSelector holds the requirements of ?labelSelector=.
+	LabelSelector labels.Selector
+	SortField     string
+	Descending    bool
+	// IncludeDeleted also returns soft-deleted rows.
+	IncludeDeleted bool
+	cursor         *continueToken
+}
+
+// continueToken is the opaque cursor handed out as ?continue=. It holds the
+// sort key of the last item of a page so the next page starts right after it.
+type continueToken struct {
+	SortField  string      `json:"s"`
+	Descending bool        `json:"d,omitempty"`
+	Value      interface{} `json:"v"`
+	ID         uint        `json:"id"`
+}
+
+// ParseListOptions reads limit, continue, name, status, labelSelector,
+// created_after, sort and includeDeleted from the query string.
+func ParseListOptions(r *http.Request, spec ListSpec) (ListOptions, error) {
+	query := r.URL.Query()
+	opts := ListOptions{
+		Limit:     DefaultListLimit,
+		SortField: "id",
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("limit must be a positive integer")
+		}
+		opts.Limit = min(limit, MaxListLimit)
+	}
+
+	if value := query.Get("sort"); value != "" {
+		field, descending := strings.CutPrefix(value, "-")
+		if field != "id" && field != "created_at" && !slices.Contains(spec.SortFields, field) {
+			return opts, fmt.Errorf("cannot sort by %q", field)
+		}
+		opts.SortField = field
+		opts.Descending = descending
+	}
+
+	if value := query.Get("name"); value != "" {
+		if spec.NameColumn == "" {
+			return opts, fmt.Errorf("name filter is not supported")
+		}
+		opts.Name = value
+	}
+
+	if value := query.Get("status"); value != "" {
+		if !spec.Status {
+			return opts, fmt.Errorf("status filter is not supported")
+		}
+		opts.Statuses = strings.Split(value, ",")
+	}
+
+	if value := query.Get("labelSelector"); value != "" {
+		if !spec.Labels {
+			return opts, fmt.Errorf("label selector is not supported: only devices, test sessions, scenarios and conditions carry labels")
+		}
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.LabelSelector = selector
+	}
+
+	if value := query.Get("created_after"); value != "" {
+		createdAfter, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return opts, fmt.Errorf("created_after must be an RFC 3339 timestamp")
+		}
+		opts.CreatedAfter = &createdAfter
+	}
+
+	if value := query.Get("includeDeleted"); value != "" {
+		includeDeleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("includeDeleted must be a boolean")
+		}
+		opts.IncludeDeleted = includeDeleted
+	}
+
+	if value := query.Get("continue"); value != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid continue token")
+		}
+		token := continueToken{}
+		if err := json.Unmarshal(raw, &token); err != nil {
+			return opts, fmt.Errorf("invalid continue token")
+		}
+		if token.SortField != opts.SortField || token.Descending != opts.Descending {
+			return opts, fmt.Errorf("continue token was issued for a different sort order")
+		}
+		opts.cursor = &token
+	}
+
+	return opts, nil
+}
+
+// Paginate applies the filters, sort order and cursor of opts to query and
+// loads one page of T. The returned metadata carries the token of the next
+// page, if any.
+func Paginate[T any](query *gorm.DB, opts ListOptions, spec ListSpec) ([]T, types.ListMeta, error) {
+	meta := types.ListMeta{}
+
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.Name != "" {
+		query = query.Where(spec.NameColumn+" ILIKE ?", "%"+escapeLike(opts.Name)+"%")
+	}
+	if len(opts.Statuses) > 0 {
+		query = query.Where("status IN ?", opts.Statuses)
+	}
+	for _, requirement := range opts.LabelSelector {
+		where, args := requirement.Where("labels")
+		query = query.Where(where, args...)
+	}
+	if opts.CreatedAfter != nil {
+		query = query.Where("created_at > ?", *opts.CreatedAfter)
+	}
+
+	comparison, direction := ">", "ASC"
+	if opts.Descending {
+		comparison, direction = "<", "DESC"
+	}
+	if opts.cursor != nil {
+		if opts.SortField == "id" {
+			query = query.Where("id "+comparison+" ?", opts.cursor.ID)
+		} else {
+			query = query.Where("("+opts.SortField+", id) "+comparison+" (?, ?)", opts.cursor.Value, opts.cursor.ID)
+		}
+	}
+	query = query.Order(opts.SortField + " " + direction)
+	if opts.SortField != "id" {
+		query = query.Order("id " + direction)
+	}
+
+	items := []T{}
+	if err := query.Limit(opts.Limit + 1).Find(&items).Error; err != nil {
+		return nil, meta, err
+	}
+
+	if len(items) > opts.Limit {
+		items = items[:opts.Limit]
+		token, err := nextToken(query, &items[len(items)-1], opts)
+		if err != nil {
+			return nil, meta, err
+		}
+		meta.Continue = token
+	}
+
+	return items, meta, nil
+}
+
+// nextToken builds the continue token pointing right after last.
+func nextToken(db *gorm.DB, last interface{}, opts ListOptions) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(last); err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	value := reflect.Indirect(reflect.ValueOf(last))
+	id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(ctx, value)
+
+	token := continueToken{
+		SortField:  opts.SortField,
+		Descending: opts.Descending,
+	}
+	if idValue, ok := id.(uint); ok {
+		token.ID = idValue
+	}
+	if opts.SortField != "id" {
+		field := stmt.Schema.LookUpField(opts.SortField)
+		if field == nil {
+			return "", fmt.Errorf("unknown sort field %q", opts.SortField)
+		}
+		token.Value, _ = field.ValueOf(ctx, value)
+	}
+
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"database"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Namespace returns the namespace addressed by the request: the {ns} URL
+// parameter of the /namespaces/{ns}/... routes, or the default namespace for
+// the routes that do not name one.
+func Namespace(r *http.Request) string {
+	if ns := chi.URLParam(r, "ns"); ns != "" {
+		return ns
+	}
+	return database.DefaultNamespace
+}
+
+// InNamespace restricts a query on a namespaced model to the namespace of the
+// request. The column is qualified with the queried table so the scope can be
+// combined with joins.
+func InNamespace(r *http.Request) func(*gorm.DB) *gorm.DB {
+	ns := Namespace(r)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "namespace"}, Value: ns})
+	}
+}
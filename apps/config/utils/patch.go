@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrInvalidPatch is returned by MergePatch when the patch document is not a
+// JSON object or produces a document that does not fit the target.
+var ErrInvalidPatch = errors.New("invalid merge patch")
+
+// MergePatch applies an RFC 7396 JSON merge patch to target, which must be a
+// pointer to a struct. Members present in the patch replace the matching
+// fields, null members reset them to their zero value and absent members are
+// left untouched.
+func MergePatch(target interface{}, patch []byte) error {
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if _, ok := patchDoc.(map[string]interface{}); !ok {
+		return fmt.Errorf("%w: patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	original, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(original, &doc); err != nil {
+		return err
+	}
+
+	patched, err := json.Marshal(mergePatch(doc, patchDoc))
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(target).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return nil
+}
+
+// mergePatch implements the MergePatch algorithm of RFC 7396, section 2.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
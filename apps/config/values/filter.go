@@ -0,0 +1,90 @@
+package values
+
+import (
+	"database"
+	"strings"
+)
+
+// Filter compares the values of a condition with an operand, as in
+// "temperature>=30C" or "firmware!=beta".
+type Filter struct {
+	Condition string
+	Operator  string
+	Operand   string
+}
+
+// operators are tried in this order at every position, so that ">=" is not
+// read as ">".
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseFilter splits a filter expression at its first operator.
+func ParseFilter(expr string) (Filter, error) {
+	for i := range expr {
+		for _, operator := range operators {
+			if !strings.HasPrefix(expr[i:], operator) {
+				continue
+			}
+			filter := Filter{
+				Condition: strings.TrimSpace(expr[:i]),
+				Operator:  operator,
+				Operand:   strings.TrimSpace(expr[i+len(operator):]),
+			}
+			if filter.Condition == "" || filter.Operand == "" {
+				return Filter{}, errorf("condition filter %q must look like temperature>=30C", expr)
+			}
+			return filter, nil
+		}
+	}
+	return Filter{}, errorf("condition filter %q has no operator; use one of =, !=, <, <=, > or >=", expr)
+}
+
+// Where returns the SQL condition on condition_values selecting the values
+// of condition that pass the filter. Numbers are compared in the canonical
+// unit. A range passes an ordering comparison only as a whole, e.g. ">= 30"
+// needs its lower bound to be at least 30, and equals a number it contains.
+func (f Filter) Where(condition *database.Condition) (string, []interface{}, error) {
+	ordering := f.Operator != "=" && f.Operator != "!="
+	sqlOperator := f.Operator
+	if sqlOperator == "!=" {
+		sqlOperator = "<>"
+	}
+
+	switch condition.ValueType {
+	case database.ValueTypeNumeric:
+		number, err := parseNumber(f.Operand, condition.Unit)
+		if err != nil {
+			return "", nil, err
+		}
+		return "condition_values.number " + sqlOperator + " ?", []interface{}{number}, nil
+	case database.ValueTypeRange:
+		number, err := parseNumber(f.Operand, condition.Unit)
+		if err != nil {
+			return "", nil, err
+		}
+		switch f.Operator {
+		case ">", ">=":
+			return "condition_values.number " + sqlOperator + " ?", []interface{}{number}, nil
+		case "<", "<=":
+			return "condition_values.number_max " + sqlOperator + " ?", []interface{}{number}, nil
+		case "=":
+			return "condition_values.number <= ? AND condition_values.number_max >= ?", []interface{}{number, number}, nil
+		default:
+			return "NOT (condition_values.number <= ? AND condition_values.number_max >= ?)", []interface{}{number, number}, nil
+		}
+	case database.ValueTypeBoolean:
+		if ordering {
+			break
+		}
+		parsed, err := parseBool(f.Operand)
+		if err != nil {
+			return "", nil, err
+		}
+		return "condition_values.boolean " + sqlOperator + " ?", []interface{}{parsed}, nil
+	default:
+		if ordering {
+			break
+		}
+		return "condition_values.value " + sqlOperator + " ?", []interface{}{f.Operand}, nil
+	}
+	return "", nil, errorf("%s values of condition %q cannot be compared with %s", condition.ValueType, f.Condition, f.Operator)
+}
@@ -0,0 +1,285 @@
+// Package values parses condition values according to the type of their
+// condition. Numbers are converted to the canonical unit of their dimension,
+// so that "25C", "25 °C" and "298.15K" are the same value and values can be
+// sorted and compared numerically.
+package values
+
+import (
+	"database"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Error is a value or condition definition that does not fit its type.
+type Error struct {
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func errorf(format string, args ...interface{}) error {
+	return &Error{Detail: fmt.Sprintf(format, args...)}
+}
+
+// unit converts a unit to the canonical unit of its dimension:
+// canonical = value*scale + offset.
+type unit struct {
+	canonical string
+	scale     float64
+	offset    float64
+}
+
+// units lists the accepted spellings of every unit.
+var units = map[string]unit{
+	"°C":   {"°C", 1, 0},
+	"C":    {"°C", 1, 0},
+	"degC": {"°C", 1, 0},
+	"℃":    {"°C", 1, 0},
+	"K":    {"°C", 1, -273.15},
+	"°F":   {"°C", 5.0 / 9, -32 * 5.0 / 9},
+	"F":    {"°C", 5.0 / 9, -32 * 5.0 / 9},
+	"degF": {"°C", 5.0 / 9, -32 * 5.0 / 9},
+	"℉":    {"°C", 5.0 / 9, -32 * 5.0 / 9},
+	"V":    {"V", 1, 0},
+	"mV":   {"V", 0.001, 0},
+	"%RH":  {"%RH", 1, 0},
+	"% RH": {"%RH", 1, 0},
+	"RH":   {"%RH", 1, 0},
+}
+
+// CanonicalUnit returns the canonical unit of the dimension u belongs to.
+// An empty unit stays empty.
+func CanonicalUnit(u string) (string, error) {
+	if u == "" {
+		return "", nil
+	}
+	known, ok := units[u]
+	if !ok {
+		return "", errorf("unknown unit %q; supported are °C, K, °F, V, mV and %%RH", u)
+	}
+	return known.canonical, nil
+}
+
+// Normalize checks the type definition of a condition and converts its unit
+// to the canonical one. Min and Max are taken to be in the declared unit and
+// converted along with it, so that unit=K, min=273 becomes -0.15 °C.
+func Normalize(condition *database.Condition) error {
+	if condition.ValueType == "" {
+		condition.ValueType = database.ValueTypeText
+	}
+
+	switch condition.ValueType {
+	case database.ValueTypeNumeric, database.ValueTypeRange:
+		canonical, err := CanonicalUnit(condition.Unit)
+		if err != nil {
+			return err
+		}
+		if declared, ok := units[condition.Unit]; ok {
+			for _, bound := range []**float64{&condition.Min, &condition.Max} {
+				if *bound != nil {
+					converted := round(**bound*declared.scale + declared.offset)
+					*bound = &converted
+				}
+			}
+		}
+		condition.Unit = canonical
+		if condition.Min != nil && condition.Max != nil && *condition.Min > *condition.Max {
+			return errorf("min %g is greater than max %g", *condition.Min, *condition.Max)
+		}
+		if len(condition.Options) > 0 {
+			return errorf("options only apply to enum conditions")
+		}
+		return nil
+	case database.ValueTypeEnum:
+		if len(condition.Options) == 0 {
+			return errorf("an enum condition needs options")
+		}
+		if len(slices.Compact(slices.Sorted(slices.Values(condition.Options)))) != len(condition.Options) {
+			return errorf("options must be unique")
+		}
+	case database.ValueTypeText, database.ValueTypeBoolean:
+		if len(condition.Options) > 0 {
+			return errorf("options only apply to enum conditions")
+		}
+	default:
+		return errorf("unknown value type %q", condition.ValueType)
+	}
+
+	if condition.Unit != "" || condition.Min != nil || condition.Max != nil {
+		return errorf("unit, min and max only apply to numeric and range conditions")
+	}
+	return nil
+}
+
+// Apply parses value.Value according to the type of condition and sets the
+// typed fields of value.
+func Apply(value *database.ConditionValue, condition *database.Condition) error {
+	value.Number, value.NumberMax, value.Boolean = nil, nil, nil
+
+	raw := strings.TrimSpace(value.Value)
+	switch condition.ValueType {
+	case database.ValueTypeNumeric:
+		number, err := parseNumber(raw, condition.Unit)
+		if err != nil {
+			return err
+		}
+		if err := inBounds(number, condition); err != nil {
+			return err
+		}
+		value.Number = &number
+	case database.ValueTypeRange:
+		low, high, err := parseRange(raw, condition.Unit)
+		if err != nil {
+			return err
+		}
+		if err := inBounds(low, condition); err != nil {
+			return err
+		}
+		if err := inBounds(high, condition); err != nil {
+			return err
+		}
+		value.Number, value.NumberMax = &low, &high
+	case database.ValueTypeEnum:
+		if !slices.Contains(condition.Options, raw) {
+			return errorf("%q is not one of the options %s", raw, strings.Join(condition.Options, ", "))
+		}
+	case database.ValueTypeBoolean:
+		parsed, err := parseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.Boolean = &parsed
+	}
+	return nil
+}
+
+// SameTyped reports whether two values of a typed condition denote the same
+// value, even when written differently.
+func SameTyped(a, b *database.ConditionValue) bool {
+	equal := func(x, y *float64) bool {
+		return x != nil && y != nil && *x == *y || x == nil && y == nil
+	}
+	if a.Boolean != nil || b.Boolean != nil {
+		return a.Boolean != nil && b.Boolean != nil && *a.Boolean == *b.Boolean
+	}
+	if a.Number == nil && b.Number == nil {
+		return false
+	}
+	return equal(a.Number, b.Number) && equal(a.NumberMax, b.NumberMax)
+}
+
+var numberPattern = regexp.MustCompile(`^([+-]?(?:\d+\.?\d*|\.\d+)(?:[eE][+-]?\d+)?)\s*(.*)$`)
+
+// parseNumber parses a number with an optional unit and converts it to the
+// canonical unit. A number without unit is taken to be in the canonical unit.
+func parseNumber(raw, canonical string) (float64, error) {
+	number, u, err := splitNumber(raw)
+	if err != nil {
+		return 0, err
+	}
+	return convert(number, u, canonical, raw)
+}
+
+func splitNumber(raw string) (float64, string, error) {
+	match := numberPattern.FindStringSubmatch(strings.TrimSpace(raw))
+	if match == nil {
+		return 0, "", errorf("%q is not a number", raw)
+	}
+	number, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, "", errorf("%q is not a number", raw)
+	}
+	return number, strings.TrimSpace(match[2]), nil
+}
+
+func convert(number float64, u, canonical, raw string) (float64, error) {
+	if u == "" {
+		return round(number), nil
+	}
+	known, ok := units[u]
+	if !ok {
+		return 0, errorf("%q has an unknown unit %q", raw, u)
+	}
+	if known.canonical != canonical {
+		if canonical == "" {
+			return 0, errorf("%q has a unit, but the condition takes plain numbers", raw)
+		}
+		return 0, errorf("%q is not in a unit of %s", raw, canonical)
+	}
+	return round(number*known.scale + known.offset), nil
+}
+
+// round drops the noise left by unit conversion, so that converted values
+// compare equal to the ones entered in the canonical unit.
+func round(number float64) float64 {
+	return math.Round(number*1e9) / 1e9
+}
+
+// parseRange parses "20..30 °C", "20 to 30C", "20C-30C" or "20-30C". A bound
+// without unit takes the unit of the other one.
+func parseRange(raw, canonical string) (float64, float64, error) {
+	for _, separator := range []string{"..", "–", " to ", "-"} {
+		for start := 1; start < len(raw); {
+			i := strings.Index(raw[start:], separator)
+			if i < 0 {
+				break
+			}
+			i += start
+			start = i + 1
+
+			lowNumber, lowUnit, err := splitNumber(raw[:i])
+			if err != nil {
+				continue
+			}
+			highNumber, highUnit, err := splitNumber(raw[i+len(separator):])
+			if err != nil {
+				continue
+			}
+			switch {
+			case lowUnit == "":
+				lowUnit = highUnit
+			case highUnit == "":
+				highUnit = lowUnit
+			}
+			low, err := convert(lowNumber, lowUnit, canonical, raw)
+			if err != nil {
+				return 0, 0, err
+			}
+			high, err := convert(highNumber, highUnit, canonical, raw)
+			if err != nil {
+				return 0, 0, err
+			}
+			if low > high {
+				return 0, 0, errorf("%q has a lower bound above its upper bound", raw)
+			}
+			return low, high, nil
+		}
+	}
+	return 0, 0, errorf("%q is not a range such as 20..30", raw)
+}
+
+func parseBool(raw string) (bool, error) {
+	switch strings.ToLower(raw) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	}
+	return false, errorf("%q is not a boolean", raw)
+}
+
+func inBounds(number float64, condition *database.Condition) error {
+	if condition.Min != nil && number < *condition.Min {
+		return errorf("%g%s is below the minimum %g%s", number, condition.Unit, *condition.Min, condition.Unit)
+	}
+	if condition.Max != nil && number > *condition.Max {
+		return errorf("%g%s is above the maximum %g%s", number, condition.Unit, *condition.Max, condition.Unit)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package values
+
+import (
+	"database"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		raw       string
+		canonical string
+		want      float64
+		wantErr   bool
+	}{
+		{raw: "25", canonical: "°C", want: 25},
+		{raw: "25C", canonical: "°C", want: 25},
+		{raw: "25 °C", canonical: "°C", want: 25},
+		{raw: "25degC", canonical: "°C", want: 25},
+		{raw: "25℃", canonical: "°C", want: 25},
+		{raw: "298.15K", canonical: "°C", want: 25},
+		{raw: "0 K", canonical: "°C", want: -273.15},
+		{raw: "77°F", canonical: "°C", want: 25},
+		{raw: "-40F", canonical: "°C", want: -40},
+		{raw: "212 degF", canonical: "°C", want: 100},
+		{raw: "3.3V", canonical: "V", want: 3.3},
+		{raw: "1800mV", canonical: "V", want: 1.8},
+		{raw: ".5 V", canonical: "V", want: 0.5},
+		{raw: "1e3 mV", canonical: "V", want: 1},
+		{raw: "45%RH", canonical: "%RH", want: 45},
+		{raw: "45 % RH", canonical: "%RH", want: 45},
+		{raw: "45 RH", canonical: "%RH", want: 45},
+		{raw: "12", canonical: "", want: 12},
+		{raw: "12V", canonical: "", wantErr: true},
+		{raw: "3.3V", canonical: "°C", wantErr: true},
+		{raw: "25 parsecs", canonical: "°C", wantErr: true},
+		{raw: "warm", canonical: "°C", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw+" in "+tt.canonical, func(t *testing.T) {
+			got, err := parseNumber(tt.raw, tt.canonical)
+			if tt.wantErr {
+				var valueErr *Error
+				if !errors.As(err, &valueErr) {
+					t.Fatalf("parseNumber() error = %v, want an *Error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseNumber() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		raw       string
+		canonical string
+		low, high float64
+		wantErr   bool
+	}{
+		{raw: "20..30", canonical: "°C", low: 20, high: 30},
+		{raw: "20..30 °C", canonical: "°C", low: 20, high: 30},
+		{raw: "20 to 30C", canonical: "°C", low: 20, high: 30},
+		{raw: "20C-30C", canonical: "°C", low: 20, high: 30},
+		{raw: "20-30C", canonical: "°C", low: 20, high: 30},
+		{raw: "20–30", canonical: "°C", low: 20, high: 30},
+		{raw: "293.15K-303", canonical: "°C", low: 20, high: 29.85},
+		{raw: "293.15-303.15K", canonical: "°C", low: 20, high: 30},
+		{raw: "-10..-5", canonical: "°C", low: -10, high: -5},
+		{raw: "-10-5", canonical: "°C", low: -10, high: 5},
+		{raw: "1800mV..3.3V", canonical: "V", low: 1.8, high: 3.3},
+		{raw: "40 to 60 %RH", canonical: "%RH", low: 40, high: 60},
+		{raw: "30..20", canonical: "°C", wantErr: true},
+		{raw: "20..30V", canonical: "°C", wantErr: true},
+		{raw: "20", canonical: "°C", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			low, high, err := parseRange(tt.raw, tt.canonical)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseRange() = %v, %v, want an error", low, high)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRange() error = %v", err)
+			}
+			if low != tt.low || high != tt.high {
+				t.Errorf("parseRange() = %v, %v, want %v, %v", low, high, tt.low, tt.high)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition database.Condition
+		want      database.Condition
+		wantErr   bool
+	}{
+		{
+			name:      "text by default",
+			condition: database.Condition{},
+			want:      database.Condition{ValueType: database.ValueTypeText},
+		},
+		{
+			name:      "canonical unit",
+			condition: database.Condition{ValueType: database.ValueTypeNumeric, Unit: "C", Min: ptr(-40), Max: ptr(85)},
+			want:      database.Condition{ValueType: database.ValueTypeNumeric, Unit: "°C", Min: ptr(-40), Max: ptr(85)},
+		},
+		{
+			name:      "bounds converted from kelvin",
+			condition: database.Condition{ValueType: database.ValueTypeRange, Unit: "K", Min: ptr(273.15), Max: ptr(373.15)},
+			want:      database.Condition{ValueType: database.ValueTypeRange, Unit: "°C", Min: ptr(0), Max: ptr(100)},
+		},
+		{
+			name:      "bounds converted from millivolts",
+			condition: database.Condition{ValueType: database.ValueTypeNumeric, Unit: "mV", Max: ptr(3300)},
+			want:      database.Condition{ValueType: database.ValueTypeNumeric, Unit: "V", Max: ptr(3.3)},
+		},
+		{
+			name:      "min above max",
+			condition: database.Condition{ValueType: database.ValueTypeNumeric, Min: ptr(2), Max: ptr(1)},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown unit",
+			condition: database.Condition{ValueType: database.ValueTypeNumeric, Unit: "lux"},
+			wantErr:   true,
+		},
+		{
+			name:      "enum without options",
+			condition: database.Condition{ValueType: database.ValueTypeEnum},
+			wantErr:   true,
+		},
+		{
+			name:      "duplicate options",
+			condition: database.Condition{ValueType: database.ValueTypeEnum, Options: []string{"a", "b", "a"}},
+			wantErr:   true,
+		},
+		{
+			name:      "unit on text",
+			condition: database.Condition{ValueType: database.ValueTypeText, Unit: "V"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition := tt.condition
+			err := Normalize(&condition)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Normalize() = %+v, want an error", condition)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if !reflect.DeepEqual(condition, tt.want) {
+				t.Errorf("Normalize() = %+v, want %+v", condition, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    Filter
+		wantErr bool
+	}{
+		{expr: "temperature=25C", want: Filter{Condition: "temperature", Operator: "=", Operand: "25C"}},
+		{expr: "firmware != beta", want: Filter{Condition: "firmware", Operator: "!=", Operand: "beta"}},
+		{expr: "temperature<30", want: Filter{Condition: "temperature", Operator: "<", Operand: "30"}},
+		{expr: "temperature<=30", want: Filter{Condition: "temperature", Operator: "<=", Operand: "30"}},
+		{expr: "temperature>30", want: Filter{Condition: "temperature", Operator: ">", Operand: "30"}},
+		{expr: "temperature>=30C", want: Filter{Condition: "temperature", Operator: ">=", Operand: "30C"}},
+		{expr: "voltage>=-5V", want: Filter{Condition: "voltage", Operator: ">=", Operand: "-5V"}},
+		{expr: "temperature", wantErr: true},
+		{expr: "=30", wantErr: true},
+		{expr: "temperature>=", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseFilter(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseFilter() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterWhere(t *testing.T) {
+	temperature := &database.Condition{Name: "temperature", ValueType: database.ValueTypeNumeric, Unit: "°C"}
+	window := &database.Condition{Name: "window", ValueType: database.ValueTypeRange, Unit: "°C"}
+	powered := &database.Condition{Name: "powered", ValueType: database.ValueTypeBoolean}
+	firmware := &database.Condition{Name: "firmware", ValueType: database.ValueTypeText}
+
+	tests := []struct {
+		name      string
+		condition *database.Condition
+		operator  string
+		operand   string
+		wantSQL   string
+		wantArgs  []interface{}
+		wantErr   bool
+	}{
+		{name: "numeric =", condition: temperature, operator: "=", operand: "25C", wantSQL: "condition_values.number = ?", wantArgs: []interface{}{25.0}},
+		{name: "numeric !=", condition: temperature, operator: "!=", operand: "25", wantSQL: "condition_values.number <> ?", wantArgs: []interface{}{25.0}},
+		{name: "numeric <", condition: temperature, operator: "<", operand: "273.15K", wantSQL: "condition_values.number < ?", wantArgs: []interface{}{0.0}},
+		{name: "numeric <=", condition: temperature, operator: "<=", operand: "32F", wantSQL: "condition_values.number <= ?", wantArgs: []interface{}{0.0}},
+		{name: "numeric >", condition: temperature, operator: ">", operand: "30", wantSQL: "condition_values.number > ?", wantArgs: []interface{}{30.0}},
+		{name: "numeric >=", condition: temperature, operator: ">=", operand: "30C", wantSQL: "condition_values.number >= ?", wantArgs: []interface{}{30.0}},
+		{name: "numeric in another dimension", condition: temperature, operator: ">=", operand: "3V", wantErr: true},
+		{name: "range =", condition: window, operator: "=", operand: "25", wantSQL: "condition_values.number <= ? AND condition_values.number_max >= ?", wantArgs: []interface{}{25.0, 25.0}},
+		{name: "range !=", condition: window, operator: "!=", operand: "25", wantSQL: "NOT (condition_values.number <= ? AND condition_values.number_max >= ?)", wantArgs: []interface{}{25.0, 25.0}},
+		{name: "range <", condition: window, operator: "<", operand: "25", wantSQL: "condition_values.number_max < ?", wantArgs: []interface{}{25.0}},
+		{name: "range <=", condition: window, operator: "<=", operand: "25", wantSQL: "condition_values.number_max <= ?", wantArgs: []interface{}{25.0}},
+		{name: "range >", condition: window, operator: ">", operand: "25", wantSQL: "condition_values.number > ?", wantArgs: []interface{}{25.0}},
+		{name: "range >=", condition: window, operator: ">=", operand: "25", wantSQL: "condition_values.number >= ?", wantArgs: []interface{}{25.0}},
+		{name: "boolean =", condition: powered, operator: "=", operand: "yes", wantSQL: "condition_values.boolean = ?", wantArgs: []interface{}{true}},
+		{name: "boolean !=", condition: powered, operator: "!=", operand: "off", wantSQL: "condition_values.boolean <> ?", wantArgs: []interface{}{false}},
+		{name: "boolean ordering", condition: powered, operator: ">", operand: "true", wantErr: true},
+		{name: "boolean operand", condition: powered, operator: "=", operand: "maybe", wantErr: true},
+		{name: "text =", condition: firmware, operator: "=", operand: "1.3", wantSQL: "condition_values.value = ?", wantArgs: []interface{}{"1.3"}},
+		{name: "text !=", condition: firmware, operator: "!=", operand: "beta", wantSQL: "condition_values.value <> ?", wantArgs: []interface{}{"beta"}},
+		{name: "text ordering", condition: firmware, operator: "<", operand: "1.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := Filter{Condition: tt.condition.Name, Operator: tt.operator, Operand: tt.operand}
+			sql, args, err := filter.Where(tt.condition)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Where() = %q, want an error", sql)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Where() error = %v", err)
+			}
+			if sql != tt.wantSQL || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Where() = %q %v, want %q %v", sql, args, tt.wantSQL, tt.wantArgs)
+			}
+		})
+	}
+}
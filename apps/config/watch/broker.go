@@ -0,0 +1,163 @@
+// Package watch fans out resource changes to the clients watching a list
+// route. Events are kept in memory only: a restarted server starts a new
+// resource version sequence and older versions are reported as expired.
+package watch
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+)
+
+const (
+	// historySize is the number of recent events kept for resuming watches.
+	historySize = 1024
+	// watcherBuffer is the number of events a watcher may fall behind before
+	// it is dropped.
+	watcherBuffer = 256
+)
+
+// ErrExpired is returned when a watch asks to resume from a resource version
+// whose events are no longer kept.
+var ErrExpired = errors.New("resource version is too old, list again to get a current one")
+
+// Event is a change of one object. Object holds the state after the change,
+// or the last state for deletions.
+type Event struct {
+	Type            EventType   `json:"type"`
+	Kind            string      `json:"kind"`
+	Namespace       string      `json:"namespace,omitempty"`
+	ResourceVersion uint64      `json:"resourceVersion,string"`
+	Object          interface{} `json:"object"`
+}
+
+// Broker assigns resource versions to events and delivers them to watchers.
+type Broker struct {
+	mu       sync.Mutex
+	base     uint64
+	version  uint64
+	history  []Event
+	watchers map[*Watcher]struct{}
+}
+
+func NewBroker() *Broker {
+	// Versions start from the clock so that versions handed out by a
+	// previous process are recognised as expired.
+	base := uint64(time.Now().UnixMilli()) << 16
+	return &Broker{
+		base:     base,
+		version:  base,
+		watchers: map[*Watcher]struct{}{},
+	}
+}
+
+// Version returns the resource version of the last published event.
+func (b *Broker) Version() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.version
+}
+
+// Publish records a change of object, a model value such as
+// database.Scenario, and delivers it to the matching watchers. It must be
+// called once the change has been committed.
+func (b *Broker) Publish(eventType EventType, namespace string, object interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.version++
+	event := Event{
+		Type:            eventType,
+		Kind:            reflect.Indirect(reflect.ValueOf(object)).Type().Name(),
+		Namespace:       namespace,
+		ResourceVersion: b.version,
+		Object:          object,
+	}
+
+	if len(b.history) == historySize {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:historySize-1]
+	}
+	b.history = append(b.history, event)
+
+	for watcher := range b.watchers {
+		if !watcher.match(event) {
+			continue
+		}
+		select {
+		case watcher.events <- event:
+		default:
+			// The client cannot keep up; end its stream so it resumes
+			// from the last version it has seen.
+			b.remove(watcher)
+		}
+	}
+}
+
+// Watch subscribes to the events accepted by match that come after the
+// given resource version. Kept events newer than since are delivered first.
+func (b *Broker) Watch(since uint64, match func(Event) bool) (*Watcher, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if since < b.base || since > b.version {
+		return nil, ErrExpired
+	}
+	if len(b.history) > 0 && since+1 < b.history[0].ResourceVersion {
+		return nil, ErrExpired
+	}
+
+	replay := []Event{}
+	for _, event := range b.history {
+		if event.ResourceVersion > since && match(event) {
+			replay = append(replay, event)
+		}
+	}
+
+	watcher := &Watcher{
+		broker: b,
+		match:  match,
+		events: make(chan Event, len(replay)+watcherBuffer),
+	}
+	for _, event := range replay {
+		watcher.events <- event
+	}
+	b.watchers[watcher] = struct{}{}
+	return watcher, nil
+}
+
+func (b *Broker) remove(watcher *Watcher) {
+	if _, ok := b.watchers[watcher]; ok {
+		delete(b.watchers, watcher)
+		close(watcher.events)
+	}
+}
+
+// Watcher receives the events of one watch.
+type Watcher struct {
+	broker *Broker
+	match  func(Event) bool
+	events chan Event
+}
+
+// Events returns the channel of events. It is closed when the watcher is
+// stopped or has fallen too far behind.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Stop unsubscribes the watcher.
+func (w *Watcher) Stop() {
+	w.broker.mu.Lock()
+	defer w.broker.mu.Unlock()
+	w.broker.remove(w)
+}
@@ -0,0 +1,272 @@
+// Package webhook delivers lab events to the webhooks registered through the
+// config API. Deliveries are stored before they are sent, so they survive a
+// restart and double as the delivery log of each webhook.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Lab events delivered to webhooks.
+const (
+	ScenarioActivated   = "scenario.activated"
+	ScenarioDeactivated = "scenario.deactivated"
+	ScenarioCompleted   = "scenario.completed"
+	ScenarioAborted     = "scenario.aborted"
+	ScenarioOverdue     = "scenario.overdue"
+	TestSessionCreated  = "test_session.created"
+	ExportFinished      = "export.finished"
+)
+
+const (
+	// maxAttempts is the number of times a delivery is tried before it is
+	// marked as failed.
+	maxAttempts = 8
+	// baseDelay is the wait before the first retry; it doubles with every
+	// further attempt.
+	baseDelay = 10 * time.Second
+	// pollInterval is how often due retries are looked for.
+	pollInterval = 5 * time.Second
+	// batchSize is the number of due deliveries sent concurrently.
+	batchSize = 16
+	// maxErrorLength caps the response excerpt kept in the delivery log.
+	maxErrorLength = 512
+)
+
+// Payload is the JSON body posted to a webhook.
+type Payload struct {
+	ID         uint        `json:"id"`
+	Event      string      `json:"event"`
+	Namespace  string      `json:"namespace"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Dispatcher queues events for the subscribed webhooks and sends them.
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: 10 * time.Second},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Notify queues event for every active webhook of the namespace subscribed
+// to it. It must be called once the change behind the event has been
+// committed. Failures are logged and do not affect the caller.
+func (d *Dispatcher) Notify(namespace, event string, data interface{}) {
+	if err := d.enqueue(namespace, event, data); err != nil {
+		log.Printf("webhook: queueing %s in namespace %q: %v", event, namespace, err)
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) enqueue(namespace, event string, data interface{}) error {
+	webhooks := []database.Webhook{}
+	if err := d.db.Where("namespace = ? AND active", namespace).Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	deliveries := pending(webhooks, event, now)
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// The payload carries the delivery ID, so the rows are created first
+	// and filled in afterwards.
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&deliveries).Error; err != nil {
+			return err
+		}
+		for i := range deliveries {
+			body, err := json.Marshal(Payload{
+				ID:         deliveries[i].ID,
+				Event:      event,
+				Namespace:  namespace,
+				OccurredAt: now,
+				Data:       data,
+			})
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&deliveries[i]).Update("payload", string(body)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// pending returns the deliveries of event, due at now, to the webhooks
+// subscribed to it.
+func pending(webhooks []database.Webhook, event string, now time.Time) []database.WebhookDelivery {
+	deliveries := []database.WebhookDelivery{}
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.Events, "*") && !slices.Contains(webhook.Events, event) {
+			continue
+		}
+		deliveries = append(deliveries, database.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Status:        database.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	return deliveries
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.sendDue(ctx) == batchSize && ctx.Err() == nil {
+			// A full batch; more may be due.
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// sendDue sends one batch of due deliveries and returns its size.
+func (d *Dispatcher) sendDue(ctx context.Context) int {
+	due := []database.WebhookDelivery{}
+	err := d.db.Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", database.DeliveryPending, time.Now().UTC()).
+		Order("next_attempt_at").
+		Limit(batchSize).
+		Find(&due).Error
+	if err != nil {
+		log.Printf("webhook: loading due deliveries: %v", err)
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(delivery *database.WebhookDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(&due[i])
+	}
+	wg.Wait()
+	return len(due)
+}
+
+// attempt sends a delivery once and records the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *database.WebhookDelivery) {
+	err := d.db.Model(&database.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(d.outcome(ctx, delivery)).Error
+	if err != nil {
+		log.Printf("webhook: recording delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// outcome sends a delivery once and returns the column updates recording
+// the result. A failed delivery stays pending until maxAttempts is reached.
+func (d *Dispatcher) outcome(ctx context.Context, delivery *database.WebhookDelivery) map[string]interface{} {
+	updates := map[string]interface{}{"attempts": delivery.Attempts + 1}
+
+	if delivery.Webhook == nil || !delivery.Webhook.Active {
+		// Deleted or deactivated since the event was queued.
+		updates["status"] = database.DeliveryFailed
+		updates["last_error"] = "webhook is no longer active"
+	} else {
+		statusCode, err := d.send(ctx, delivery.Webhook, delivery)
+		updates["last_status_code"] = statusCode
+		switch {
+		case err == nil:
+			now := time.Now().UTC()
+			updates["status"] = database.DeliverySucceeded
+			updates["last_error"] = ""
+			updates["delivered_at"] = &now
+		case delivery.Attempts+1 >= maxAttempts:
+			updates["status"] = database.DeliveryFailed
+			updates["last_error"] = err.Error()
+		default:
+			updates["last_error"] = err.Error()
+			updates["next_attempt_at"] = time.Now().UTC().Add(backoff(delivery.Attempts + 1))
+		}
+	}
+	return updates
+}
+
+// send posts the payload and returns the response status. Any status other
+// than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, webhook *database.Webhook, delivery *database.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "neuro-lab-config-webhook/1")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("receiver answered %s: %s", resp.Status, bytes.TrimSpace(excerpt))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the webhook secret. Receivers recompute it to authenticate a request
+// and reject old timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	return baseDelay << (attempts - 1)
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"context"
+	"database"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// receiver starts a webhook receiver answering every request with status
+// and hands the requests it got, with their bodies, to got.
+func receiver(t *testing.T, status int, got func(*http.Request, []byte)) *database.Webhook {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		if got != nil {
+			got(r, body)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(server.Close)
+	return &database.Webhook{Model: gorm.Model{ID: 3}, URL: server.URL, Secret: "s3cret", Events: []string{"*"}, Active: true}
+}
+
+func delivery(webhook *database.Webhook, attempts int) *database.WebhookDelivery {
+	return &database.WebhookDelivery{
+		Model:     gorm.Model{ID: 42},
+		WebhookID: webhook.ID,
+		Webhook:   webhook,
+		Event:     ScenarioActivated,
+		Payload:   `{"id":42,"event":"scenario.activated","namespace":"default","data":{"id":7}}`,
+		Status:    database.DeliveryPending,
+		Attempts:  attempts,
+	}
+}
+
+func TestSendSignsThePayload(t *testing.T) {
+	var header http.Header
+	var body []byte
+	webhook := receiver(t, http.StatusNoContent, func(r *http.Request, b []byte) {
+		header, body = r.Header, b
+	})
+	d := delivery(webhook, 0)
+
+	status, err := NewDispatcher(nil).send(context.Background(), webhook, d)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send() = %d, %v, want %d, nil", status, err, http.StatusNoContent)
+	}
+
+	if string(body) != d.Payload {
+		t.Errorf("body = %s, want %s", body, d.Payload)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-Event":    ScenarioActivated,
+		"X-Webhook-Delivery": "42",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	timestamp := header.Get("X-Webhook-Timestamp")
+	if timestamp == "" {
+		t.Fatal("X-Webhook-Timestamp is missing")
+	}
+	if got, want := header.Get("X-Webhook-Signature"), "sha256="+Sign(webhook.Secret, timestamp, body); got != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+	}
+	if got := header.Get("X-Webhook-Signature"); got == "sha256="+Sign("other", timestamp, body) {
+		t.Errorf("X-Webhook-Signature %q also matches another secret", got)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		// want are the expected updates besides attempts, last_error and
+		// next_attempt_at.
+		want      map[string]interface{}
+		wantError string
+		wantRetry time.Duration
+	}{
+		{
+			name:     "delivered",
+			status:   http.StatusOK,
+			attempts: 2,
+			want:     map[string]interface{}{"status": database.DeliverySucceeded, "last_status_code": http.StatusOK},
+		},
+		{
+			name:      "first failure is retried",
+			status:    http.StatusServiceUnavailable,
+			want:      map[string]interface{}{"last_status_code": http.StatusServiceUnavailable},
+			wantError: "receiver answered 503 Service Unavailable: Service Unavailable",
+			wantRetry: baseDelay,
+		},
+		{
+			name:      "retries back off",
+			status:    http.StatusInternalServerError,
+			attempts:  3,
+			want:      map[string]interface{}{"last_status_code": http.StatusInternalServerError},
+			wantError: "receiver answered 500 Internal Server Error: Internal Server Error",
+			wantRetry: 8 * baseDelay,
+		},
+		{
+			name:      "failed after the last attempt",
+			status:    http.StatusBadGateway,
+			attempts:  maxAttempts - 1,
+			want:      map[string]interface{}{"status": database.DeliveryFailed, "last_status_code": http.StatusBadGateway},
+			wantError: "receiver answered 502 Bad Gateway: Bad Gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			webhook := receiver(t, tt.status, func(*http.Request, []byte) { requests++ })
+
+			before := time.Now().UTC()
+			updates := NewDispatcher(nil).outcome(context.Background(), delivery(webhook, tt.attempts))
+			after := time.Now().UTC()
+
+			if requests != 1 {
+				t.Errorf("receiver got %d requests, want 1", requests)
+			}
+			if got := updates["attempts"]; got != tt.attempts+1 {
+				t.Errorf("attempts = %v, want %d", got, tt.attempts+1)
+			}
+			if got := updates["last_error"]; got != tt.wantError {
+				t.Errorf("last_error = %q, want %q", got, tt.wantError)
+			}
+			for column, want := range tt.want {
+				if got := updates[column]; got != want {
+					t.Errorf("%s = %v, want %v", column, got, want)
+				}
+			}
+			if _, ok := tt.want["status"]; !ok {
+				if got, ok := updates["status"]; ok {
+					t.Errorf("status = %v, want the delivery to stay pending", got)
+				}
+			}
+
+			next, ok := updates["next_attempt_at"].(time.Time)
+			if tt.wantRetry == 0 {
+				if ok {
+					t.Errorf("next_attempt_at = %v, want no retry", next)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("next_attempt_at = %v, want a retry after %v", updates["next_attempt_at"], tt.wantRetry)
+			}
+			if next.Before(before.Add(tt.wantRetry)) || next.After(after.Add(tt.wantRetry)) {
+				t.Errorf("next_attempt_at = %v, want %v after %v", next, tt.wantRetry, before)
+			}
+		})
+	}
+}
+
+func TestOutcomeInactiveWebhook(t *testing.T) {
+	requests := 0
+	webhook := receiver(t, http.StatusOK, func(*http.Request, []byte) { requests++ })
+	webhook.Active = false
+
+	updates := NewDispatcher(nil).outcome(context.Background(), delivery(webhook, 0))
+	if requests != 0 {
+		t.Errorf("receiver got %d requests, want none", requests)
+	}
+	if got := updates["status"]; got != database.DeliveryFailed {
+		t.Errorf("status = %v, want %v", got, database.DeliveryFailed)
+	}
+}
+
+func TestPending(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	webhooks := []database.Webhook{
+		{Model: gorm.Model{ID: 1}, Events: []string{ScenarioActivated, ScenarioCompleted}},
+		{Model: gorm.Model{ID: 2}, Events: []string{"*"}},
+		{Model: gorm.Model{ID: 3}, Events: []string{ExportFinished}},
+		{Model: gorm.Model{ID: 4}},
+	}
+
+	tests := []struct {
+		event string
+		want  []uint
+	}{
+		{event: ScenarioActivated, want: []uint{1, 2}},
+		{event: ExportFinished, want: []uint{2, 3}},
+		{event: TestSessionCreated, want: []uint{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			deliveries := pending(webhooks, tt.event, now)
+
+			got := []uint{}
+			for _, delivery := range deliveries {
+				got = append(got, delivery.WebhookID)
+				if delivery.Event != tt.event || delivery.Status != database.DeliveryPending || !delivery.NextAttemptAt.Equal(now) {
+					t.Errorf("delivery to webhook %d = %s %s at %v, want %s %s at %v",
+						delivery.WebhookID, delivery.Event, delivery.Status, delivery.NextAttemptAt, tt.event, database.DeliveryPending, now)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pending() queued for webhooks %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
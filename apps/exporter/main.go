@@ -32,18 +32,20 @@ type ExportKey struct {
 }
 
 // ParquetRow represents a single row in the wide-format parquet file
-// Each row contains all metrics for a given timestamp
+// Each row contains all metrics for a given timestamp, and the labels of the
+// scenario so that datasets can be grouped by them
 type ParquetRow struct {
-	Timestamp time.Time `parquet:"timestamp,timestamp(microsecond)"`
-	FrameID   uint      `parquet:"frame_id"`
-	AccX      *float64  `parquet:"acc_x,optional"`
-	AccY      *float64  `parquet:"acc_y,optional"`
-	AccZ      *float64  `parquet:"acc_z,optional"`
-	GyroX     *float64  `parquet:"gyro_x,optional"`
-	GyroY     *float64  `parquet:"gyro_y,optional"`
-	GyroZ     *float64  `parquet:"gyro_z,optional"`
-	CurrV     *float64  `parquet:"curr_v,optional"`
-	Temp      *float64  `parquet:"temp,optional"`
+	Timestamp time.Time         `parquet:"timestamp,timestamp(microsecond)"`
+	FrameID   uint              `parquet:"frame_id"`
+	AccX      *float64          `parquet:"acc_x,optional"`
+	AccY      *float64          `parquet:"acc_y,optional"`
+	AccZ      *float64          `parquet:"acc_z,optional"`
+	GyroX     *float64          `parquet:"gyro_x,optional"`
+	GyroY     *float64          `parquet:"gyro_y,optional"`
+	GyroZ     *float64          `parquet:"gyro_z,optional"`
+	CurrV     *float64          `parquet:"curr_v,optional"`
+	Temp      *float64          `parquet:"temp,optional"`
+	Labels    map[string]string `parquet:"labels"`
 }
 
 func createMinioClient() (*minio.Client, error) {
@@ -103,7 +105,7 @@ func PutObject(minioClient *minio.Client, bucketName string, objectName string,
 
 // pivotSamplesToRows converts long-format samples to wide-format parquet rows
 // Groups samples by timestamp - typically 8 metrics share the same timestamp
-func pivotSamplesToRows(samples []database.ProcessedSample) []ParquetRow {
+func pivotSamplesToRows(samples []database.ProcessedSample, labels database.Labels) []ParquetRow {
 	// Group by timestamp
 	timestampMap := make(map[ExportKey]map[string]float64)
 
@@ -118,7 +120,7 @@ func pivotSamplesToRows(samples []database.ProcessedSample) []ParquetRow {
 	rows := make([]ParquetRow, 0, len(timestampMap))
 
 	for exportKey, metrics := range timestampMap {
-		row := ParquetRow{Timestamp: exportKey.Timestamp, FrameID: exportKey.FrameID}
+		row := ParquetRow{Timestamp: exportKey.Timestamp, FrameID: exportKey.FrameID, Labels: labels}
 
 		// Map each metric to its corresponding field
 		if val, ok := metrics["acc_x"]; ok {
@@ -162,7 +164,7 @@ func pivotSamplesToRows(samples []database.ProcessedSample) []ParquetRow {
 
 // exportData exports scenario samples to a parquet file with partitioned naming
 // File path format: device_id=X/scenario_id=Y/data.parquet
-func exportData(minioClient *minio.Client, samples []database.ProcessedSample, deviceID, scenarioID uint) error {
+func exportData(minioClient *minio.Client, samples []database.ProcessedSample, labels database.Labels, deviceID, scenarioID uint) error {
 	if len(samples) == 0 {
 		return fmt.Errorf("no samples to export")
 	}
@@ -183,7 +185,7 @@ func exportData(minioClient *minio.Client, samples []database.ProcessedSample, d
 	outputPath := filepath.Join(partitionPath, "data.parquet")
 
 	// Pivot samples from long format to wide format
-	rows := pivotSamplesToRows(samples)
+	rows := pivotSamplesToRows(samples, labels)
 
 	// Write to parquet file
 	err := parquet.WriteFile(outputPath, rows)
@@ -253,9 +255,16 @@ func main() {
 			continue
 		}
 
+		// Labels of the device, test session and scenario
+		labels, err := database.ScenarioLabels(db, scenario.ID)
+		if err != nil {
+			fmt.Println("could not get labels:", err)
+			continue
+		}
+
 		// Export to parquet file
 		fmt.Printf("Exporting %d samples for scenario %d (device %d)\n", len(processedSamples), scenario.ID, deviceID)
-		if err := exportData(minioClient, processedSamples, deviceID, scenario.ID); err != nil {
+		if err := exportData(minioClient, processedSamples, labels, deviceID, scenario.ID); err != nil {
 			fmt.Println("could not export data:", err)
 			continue
 		}
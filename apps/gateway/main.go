@@ -8,9 +8,12 @@ import (
 	"communication"
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"os"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -25,6 +28,8 @@ var (
 	messageCounter     metric.Int64Counter
 	gatewayDuration    metric.Int64Histogram
 	validationDuration metric.Int64Histogram
+	validationFailures metric.Int64Counter
+	validationClient   *communication.ValidationClient
 )
 
 type SensorData struct {
@@ -46,10 +51,6 @@ type Data struct {
 	Temp  []float64 `json:"temp"`
 }
 
-type ValidationRequest struct {
-	ScenarioID int `json:"scenario_id"`
-}
-
 // Connect to the specified topic and partition in the server
 func connect(topic string, partition int) (*kafka.Conn, error) {
 	conn, err := kafka.DialLeader(context.Background(), "tcp",
@@ -92,33 +93,9 @@ func processMessage(msg []byte) (*SensorData, error) {
 		return nil, fmt.Errorf("error unmarshalling message: %v", err)
 	}
 
-	reqBytes, marshalErr := json.Marshal(ValidationRequest{ScenarioID: int(sensorData.ScenarioID)})
-	if marshalErr != nil {
-		fmt.Println("Error marshalling request:", marshalErr)
-		return nil, fmt.Errorf("error marshalling request: %v", marshalErr)
-	}
-
 	start := time.Now()
-	resp, httpErr := communication.SendRequest("POST", "http://localhost:3002/api/v1/scenario-validation", reqBytes)
-	if httpErr != nil {
-		fmt.Println("Error sending request:", httpErr)
-		return nil, fmt.Errorf("error sending request: %v", httpErr)
-	}
-	if resp.StatusCode != 200 {
-		var errorResponse apierrors.ErrorResponse
-		if err := json.Unmarshal(resp.Body, &errorResponse); err != nil {
-			return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, resp.Body)
-		}
-
-		if errorResponse.Type == apierrors.TypeValidationFailed {
-			return nil, fmt.Errorf("%s: %s\n%v", errorResponse.Title, errorResponse.Detail, errorResponse.Errors)
-		}
-		return nil, fmt.Errorf("error sending request: %v", resp.Body)
-	}
-
-	if err != nil {
-		fmt.Println("Error converting duration:", err)
-		return nil, fmt.Errorf("error converting duration: %v", err)
+	if err := validateScenario(&sensorData); err != nil {
+		return nil, err
 	}
 	validationDuration.Record(context.Background(), int64(time.Duration(time.Since(start).Milliseconds())))
 
@@ -128,6 +105,41 @@ func processMessage(msg []byte) (*SensorData, error) {
 	return &sensorData, nil
 }
 
+// validateScenario checks with the config API, through the validation cache,
+// that the frame belongs to an active scenario of its device.
+func validateScenario(sensorData *SensorData) error {
+	err := validationClient.Validate(context.Background(), sensorData.ScenarioID, sensorData.DeviceID)
+	var rejected *communication.ValidationError
+	if !errors.As(err, &rejected) {
+		if err != nil {
+			return fmt.Errorf("error validating scenario: %v", err)
+		}
+		return nil
+	}
+
+	if rejected.Problem.Type == "" {
+		countValidationFailure("unknown")
+		return rejected
+	}
+	countValidationFailure(rejected.Problem.Type)
+
+	switch rejected.Problem.Type {
+	case apierrors.TypeValidationFailed:
+		return fmt.Errorf("%s: %s\n%v", rejected.Problem.Title, rejected.Problem.Detail, rejected.Problem.Errors)
+	case apierrors.TypeScenarioNotActive:
+		return fmt.Errorf("scenario %d is not active: %s", sensorData.ScenarioID, rejected.Problem.Detail)
+	case apierrors.TypeDeviceMismatch:
+		return fmt.Errorf("scenario %d does not belong to device %d: %s", sensorData.ScenarioID, sensorData.DeviceID, rejected.Problem.Detail)
+	}
+	return fmt.Errorf("error validating scenario: %v", rejected)
+}
+
+// countValidationFailure counts a message rejected by scenario validation,
+// split by the problem type returned by the config API.
+func countValidationFailure(reason string) {
+	validationFailures.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
 func main() {
 	ctx := context.Background()
 	shutdown, err := setupOTelSDK(ctx)
@@ -168,6 +180,34 @@ func main() {
 		log.Fatalf("failed to create validation duration histogram: %v", err)
 	}
 
+	validationFailures, err = meter.Int64Counter(
+		"validation.failures",
+		metric.WithDescription("Number of messages rejected by scenario validation, by reason."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		log.Fatalf("failed to create validation failure counter: %v", err)
+	}
+
+	// GATEWAY_VALIDATION_TTL bounds how long a validated scenario is trusted, and so
+	// how long frames are still accepted after it stops being active,
+	// e.g. "500ms". It defaults to 2s.
+	var positiveTTL time.Duration
+	if value := os.Getenv("GATEWAY_VALIDATION_TTL"); value != "" {
+		if positiveTTL, err = time.ParseDuration(value); err != nil {
+			log.Fatalf("GATEWAY_VALIDATION_TTL: %v", err)
+		}
+	}
+	validationClient, err = communication.NewValidationClient(communication.ValidationConfig{
+		URL:         "http://localhost:3002/api/v1/scenario-validation",
+		Token:       os.Getenv("CONFIG_API_TOKEN"),
+		PositiveTTL: positiveTTL,
+		Meter:       meter,
+	})
+	if err != nil {
+		log.Fatalf("failed to create validation client: %v", err)
+	}
+
 	opts := mqtt.NewClientOptions().AddBroker("localhost:1884")
 	opts.SetClientID("go_mqtt_client")
 
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"communication"
 	"database"
+	"os"
 
 	"context"
 	"opentelemetry"
@@ -21,6 +23,9 @@ var (
 	frameIds        *map[int]int
 	meter           metric.Meter
 	gatewayDuration metric.Int64Histogram
+	// validationFailures counts messages rejected by scenario validation.
+	validationFailures metric.Int64Counter
+	validationClient   *communication.ValidationClient
 )
 
 func init() {
@@ -43,6 +48,33 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	validationFailures, err = meter.Int64Counter(
+		"processor.validation.failures",
+		metric.WithDescription("Number of messages rejected by scenario validation, by reason."),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+	// PROCESSOR_VALIDATION_TTL bounds how long a validated scenario is trusted, and so
+	// how long frames are still accepted after it stops being active,
+	// e.g. "500ms". It defaults to 2s.
+	var positiveTTL time.Duration
+	if value := os.Getenv("PROCESSOR_VALIDATION_TTL"); value != "" {
+		if positiveTTL, err = time.ParseDuration(value); err != nil {
+			panic(fmt.Errorf("PROCESSOR_VALIDATION_TTL: %w", err))
+		}
+	}
+	validationClient, err = communication.NewValidationClient(communication.ValidationConfig{
+		URL:          "http://localhost:3002/api/v1/scenario-validation",
+		Token:        os.Getenv("CONFIG_API_TOKEN"),
+		PositiveTTL:  positiveTTL,
+		Meter:        meter,
+		MetricPrefix: "processor.",
+	})
+	if err != nil {
+		panic(err)
+	}
 	otelSdkSetup := opentelemetry.NewOtelSdkSetup(opentelemetry.SetupOTelSDKOptions{
 		ServiceName:    "processor",
 		ServiceVersion: "0.1.0",
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"communication"
@@ -12,6 +14,8 @@ import (
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	apierrors "github.com/neuro-lab/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 type SensorDataRaw struct {
@@ -26,10 +30,6 @@ type ChannelData struct {
 	ChannelName string               `json:"channel_name"`
 }
 
-type ValidationRequest struct {
-	ScenarioID int `json:"scenario_id"`
-}
-
 func processMessage(mqttClient mqtt.Client, msg mqtt.Message) {
 	var sensorData *SensorDataRaw
 
@@ -48,29 +48,39 @@ func processMessage(mqttClient mqtt.Client, msg mqtt.Message) {
 	fmt.Println("Processed message successfully")
 }
 
+// validateScenarioRaw checks with the config API, through the validation
+// cache, that the frame belongs to an active scenario of its device.
 func validateScenarioRaw(sensorData *SensorDataRaw) error {
-	reqBytes, marshalErr := json.Marshal(ValidationRequest{ScenarioID: int(sensorData.ScenarioID)})
-	if marshalErr != nil {
-		return fmt.Errorf("error marshalling request: %v", marshalErr)
+	err := validationClient.Validate(context.Background(), sensorData.ScenarioID, sensorData.DeviceID)
+	var rejected *communication.ValidationError
+	if !errors.As(err, &rejected) {
+		if err != nil {
+			return fmt.Errorf("error sending request: %v", err)
+		}
+		return nil
 	}
 
-	resp, httpErr := communication.SendRequest("POST", "http://localhost:3002/api/v1/scenario-validation", reqBytes)
-	if httpErr != nil {
-		return fmt.Errorf("error sending request: %v", httpErr)
+	if rejected.Problem.Type == "" {
+		countValidationFailure("unknown")
+		return rejected
 	}
-	if resp.StatusCode != 200 {
-		var errorResponse apierrors.ErrorResponse
-		if err := json.Unmarshal(resp.Body, &errorResponse); err != nil {
-			return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, resp.Body)
-		}
-
-		if errorResponse.Type == apierrors.TypeValidationFailed {
-			return fmt.Errorf("%s: %s\n%v", errorResponse.Title, errorResponse.Detail, errorResponse.Errors)
-		}
-		return fmt.Errorf("error sending request: %v", resp.Body)
+	countValidationFailure(rejected.Problem.Type)
+
+	switch rejected.Problem.Type {
+	case apierrors.TypeValidationFailed:
+		return fmt.Errorf("%s: %s\n%v", rejected.Problem.Title, rejected.Problem.Detail, rejected.Problem.Errors)
+	case apierrors.TypeScenarioNotActive:
+		return fmt.Errorf("scenario %d is not active: %s", sensorData.ScenarioID, rejected.Problem.Detail)
+	case apierrors.TypeDeviceMismatch:
+		return fmt.Errorf("scenario %d does not belong to device %d: %s", sensorData.ScenarioID, sensorData.DeviceID, rejected.Problem.Detail)
 	}
+	return fmt.Errorf("error sending request: %v", rejected)
+}
 
-	return nil
+// countValidationFailure counts a message rejected by scenario validation,
+// split by the problem type returned by the config API.
+func countValidationFailure(reason string) {
+	validationFailures.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", reason)))
 }
 
 func getFrameId(scenarioId int) int {
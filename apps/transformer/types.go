@@ -9,7 +9,7 @@ import (
 type ProcessedSample struct {
 	gorm.Model
 	DeviceID   uint      `json:"device_id"`
-	ScenarioID uint      `json:"scenario_id"`
+	ScenarioID uint      `json:"scenario_id" gorm:"index"`
 	FrameID    uint      `json:"frame_id"`
 	MetricName string    `json:"metric_name"`
 	Value      float64   `json:"value"`
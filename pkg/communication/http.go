@@ -2,6 +2,7 @@ package communication
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -13,12 +14,27 @@ type Response struct {
 }
 
 func SendRequest(method string, url string, body []byte) (*Response, error) {
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	return SendRequestWithToken(method, url, "", body)
+}
+
+// SendRequestWithToken sends the request with token as its bearer token.
+// An empty token sends the request without an Authorization header.
+func SendRequestWithToken(method string, url string, token string, body []byte) (*Response, error) {
+	return SendRequestWithClient(context.Background(), http.DefaultClient, method, url, token, body)
+}
+
+// SendRequestWithClient sends the request through client, bounded by ctx.
+// It is the variant to use when the caller needs timeouts or cancellation.
+func SendRequestWithClient(ctx context.Context, client *http.Client, method string, url string, token string, body []byte) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,329 @@
+package communication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	apierrors "github.com/neuro-lab/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCircuitOpen is returned without contacting the config API while the
+// circuit breaker is open after repeated failures.
+var ErrCircuitOpen = errors.New("scenario validation unavailable: circuit breaker open")
+
+// ValidationError is a rejection by the config API. Problem holds the
+// problem document of the response; its Type is empty when the body was not
+// one.
+type ValidationError struct {
+	StatusCode int
+	Problem    apierrors.ErrorResponse
+	Body       json.RawMessage
+}
+
+func (e *ValidationError) Error() string {
+	if e.Problem.Type == "" {
+		return fmt.Sprintf("validation failed with status %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("%s: %s", e.Problem.Title, e.Problem.Detail)
+}
+
+// ValidationConfig configures a ValidationClient. Zero values take the
+// defaults noted on each field.
+type ValidationConfig struct {
+	// URL is the scenario validation endpoint of the config API.
+	URL string
+	// Token is sent as bearer token; empty sends no Authorization header.
+	Token string
+	// Timeout bounds a single request. Defaults to 2s.
+	Timeout time.Duration
+	// Retries is the number of further attempts after a transport error or
+	// a 5xx answer. Defaults to 2; a negative value disables retries.
+	Retries int
+	// RetryDelay is the wait before the first retry; it doubles with every
+	// further attempt. Defaults to 100ms.
+	RetryDelay time.Duration
+	// PositiveTTL is how long a valid scenario is remembered. It is also
+	// how long frames are still accepted after a scenario stops being
+	// active, as nothing invalidates the cache. Defaults to 2s.
+	PositiveTTL time.Duration
+	// NegativeTTL is how long a rejection is remembered. Defaults to 3s.
+	NegativeTTL time.Duration
+	// FailureThreshold is the number of consecutive failed validations
+	// that opens the circuit breaker. Defaults to 5.
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open before a single
+	// probe is let through. Defaults to 10s.
+	OpenDuration time.Duration
+	// Meter records the client metrics. Defaults to the global meter.
+	Meter metric.Meter
+	// MetricPrefix is prepended to the metric names, e.g. "processor.".
+	MetricPrefix string
+}
+
+// Circuit breaker states, as reported by the breaker state gauge.
+const (
+	breakerClosed int64 = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+type validationKey struct {
+	scenarioID int
+	deviceID   int
+}
+
+type validationEntry struct {
+	err     error
+	expires time.Time
+}
+
+// ValidationClient checks with the config API whether frames of a scenario
+// may be ingested for a device. Results are cached, so that a stream of
+// frames costs one request per TTL instead of one per frame, and concurrent
+// checks of the same pair share a single request.
+type ValidationClient struct {
+	config ValidationConfig
+	client *http.Client
+	group  singleflight.Group
+
+	mu        sync.Mutex
+	cache     map[validationKey]validationEntry
+	lastSweep time.Time
+
+	breakerMu sync.Mutex
+	state     int64
+	failures  int
+	openedAt  time.Time
+	probing   bool
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+func NewValidationClient(config ValidationConfig) (*ValidationClient, error) {
+	if config.Timeout == 0 {
+		config.Timeout = 2 * time.Second
+	}
+	if config.Retries == 0 {
+		config.Retries = 2
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = 100 * time.Millisecond
+	}
+	if config.PositiveTTL == 0 {
+		config.PositiveTTL = 2 * time.Second
+	}
+	if config.NegativeTTL == 0 {
+		config.NegativeTTL = 3 * time.Second
+	}
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenDuration == 0 {
+		config.OpenDuration = 10 * time.Second
+	}
+	if config.Meter == nil {
+		config.Meter = otel.Meter("neuro-lab.communication")
+	}
+
+	c := &ValidationClient{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		cache:  map[validationKey]validationEntry{},
+	}
+
+	var err error
+	c.hits, err = config.Meter.Int64Counter(
+		config.MetricPrefix+"validation.cache.hits",
+		metric.WithDescription("Number of scenario validations answered from the cache, by result."),
+		metric.WithUnit("{validation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.misses, err = config.Meter.Int64Counter(
+		config.MetricPrefix+"validation.cache.misses",
+		metric.WithDescription("Number of scenario validations not found in the cache."),
+		metric.WithUnit("{validation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = config.Meter.Int64ObservableGauge(
+		config.MetricPrefix+"validation.breaker.state",
+		metric.WithDescription("State of the validation circuit breaker: 0 closed, 1 half-open, 2 open."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			c.breakerMu.Lock()
+			defer c.breakerMu.Unlock()
+			o.Observe(c.state)
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate returns nil if frames of the scenario may be ingested for the
+// device, a *ValidationError if the config API rejects them, ErrCircuitOpen
+// while the config API is considered down, or the error of the last attempt.
+func (c *ValidationClient) Validate(ctx context.Context, scenarioID, deviceID int) error {
+	key := validationKey{scenarioID: scenarioID, deviceID: deviceID}
+	if err, ok := c.cached(key); ok {
+		result := "valid"
+		if err != nil {
+			result = "rejected"
+		}
+		c.hits.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+		return err
+	}
+	c.misses.Add(ctx, 1)
+
+	// The shared request outlives a caller that gives up, so that the
+	// others waiting on it still get an answer.
+	flight := c.group.DoChan(strconv.Itoa(scenarioID)+"/"+strconv.Itoa(deviceID), func() (interface{}, error) {
+		return nil, c.fetch(key)
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-flight:
+		return result.Err
+	}
+}
+
+// fetch asks the config API and caches the answer. Transport errors and 5xx
+// answers are retried and count towards opening the breaker; they are not
+// cached.
+func (c *ValidationClient) fetch(key validationKey) error {
+	if !c.allow() {
+		return ErrCircuitOpen
+	}
+
+	body, err := json.Marshal(struct {
+		ScenarioID int `json:"scenario_id"`
+		DeviceID   int `json:"device_id"`
+	}{key.scenarioID, key.deviceID})
+	if err != nil {
+		c.record(true)
+		return err
+	}
+
+	delay := c.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err = c.send(body)
+		var rejected *ValidationError
+		if err == nil || errors.As(err, &rejected) && rejected.StatusCode < 500 && rejected.StatusCode != http.StatusTooManyRequests {
+			c.record(true)
+			c.store(key, err)
+			return err
+		}
+		if attempt >= c.config.Retries {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	c.record(false)
+	return err
+}
+
+func (c *ValidationClient) send(body []byte) error {
+	resp, err := SendRequestWithClient(context.Background(), c.client, http.MethodPost, c.config.URL, c.config.Token, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	rejected := &ValidationError{StatusCode: resp.StatusCode, Body: resp.Body}
+	if err := json.Unmarshal(resp.Body, &rejected.Problem); err != nil {
+		rejected.Problem = apierrors.ErrorResponse{}
+	}
+	return rejected
+}
+
+func (c *ValidationClient) cached(key validationKey) (error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.err, true
+}
+
+func (c *ValidationClient) store(key validationKey, err error) {
+	ttl := c.config.PositiveTTL
+	if err != nil {
+		ttl = c.config.NegativeTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.cache[key] = validationEntry{err: err, expires: now.Add(ttl)}
+
+	// Scenarios come and go; drop the expired ones now and then so the
+	// cache does not grow with every scenario ever seen.
+	if now.Sub(c.lastSweep) > c.config.PositiveTTL {
+		for k, entry := range c.cache {
+			if now.After(entry.expires) {
+				delete(c.cache, k)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// allow reports whether a request may be sent. An open breaker lets a single
+// probe through once OpenDuration has passed.
+func (c *ValidationClient) allow() bool {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	switch c.state {
+	case breakerOpen:
+		if time.Since(c.openedAt) < c.config.OpenDuration {
+			return false
+		}
+		c.state = breakerHalfOpen
+		c.probing = true
+		return true
+	case breakerHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of a validation.
+func (c *ValidationClient) record(ok bool) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	c.probing = false
+	if ok {
+		c.state = breakerClosed
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.state == breakerHalfOpen || c.failures >= c.config.FailureThreshold {
+		c.state = breakerOpen
+		c.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,332 @@
+package communication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// validationServer answers scenario validations with the status returned by
+// answer for each request, counting the requests.
+type validationServer struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newValidationServer(t *testing.T, answer func(n int32, scenarioID int) int) *validationServer {
+	t.Helper()
+	s := &validationServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.requests.Add(1)
+		var req struct {
+			ScenarioID int `json:"scenario_id"`
+			DeviceID   int `json:"device_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding validation request: %v", err)
+		}
+		status := answer(n, req.ScenarioID)
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"type":   "about:blank",
+				"title":  http.StatusText(status),
+				"status": status,
+				"detail": "scenario is not active",
+			})
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestClient(t *testing.T, config ValidationConfig) *ValidationClient {
+	t.Helper()
+	if config.RetryDelay == 0 {
+		config.RetryDelay = time.Millisecond
+	}
+	c, err := NewValidationClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func always(status int) func(int32, int) int {
+	return func(int32, int) int { return status }
+}
+
+func TestValidateCachesResults(t *testing.T) {
+	server := newValidationServer(t, func(_ int32, scenarioID int) int {
+		if scenarioID == 2 {
+			return http.StatusUnprocessableEntity
+		}
+		return http.StatusOK
+	})
+	c := newTestClient(t, ValidationConfig{URL: server.URL, PositiveTTL: 50 * time.Millisecond, NegativeTTL: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		scenarioID   int
+		wait         time.Duration
+		wantStatus   int
+		wantRequests int32
+	}{
+		{name: "valid fetched", scenarioID: 1, wantRequests: 1},
+		{name: "valid cached", scenarioID: 1, wantRequests: 1},
+		{name: "rejection fetched", scenarioID: 2, wantStatus: http.StatusUnprocessableEntity, wantRequests: 2},
+		{name: "rejection cached", scenarioID: 2, wantStatus: http.StatusUnprocessableEntity, wantRequests: 2},
+		{name: "valid expired", scenarioID: 1, wait: 60 * time.Millisecond, wantRequests: 3},
+		{name: "rejection expired", scenarioID: 2, wantStatus: http.StatusUnprocessableEntity, wantRequests: 4},
+	}
+	for _, tt := range tests {
+		time.Sleep(tt.wait)
+		err := c.Validate(ctx, tt.scenarioID, 7)
+		if tt.wantStatus == 0 && err != nil {
+			t.Errorf("%s: Validate() error = %v", tt.name, err)
+		}
+		if tt.wantStatus != 0 {
+			var rejected *ValidationError
+			if !errors.As(err, &rejected) || rejected.StatusCode != tt.wantStatus {
+				t.Errorf("%s: Validate() error = %v, want a %d rejection", tt.name, err, tt.wantStatus)
+			} else if rejected.Problem.Detail != "scenario is not active" {
+				t.Errorf("%s: problem detail = %q", tt.name, rejected.Problem.Detail)
+			}
+		}
+		if got := server.requests.Load(); got != tt.wantRequests {
+			t.Errorf("%s: %d requests sent, want %d", tt.name, got, tt.wantRequests)
+		}
+	}
+}
+
+func TestValidateSharesConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := newValidationServer(t, func(int32, int) int {
+		<-release
+		return http.StatusOK
+	})
+	c := newTestClient(t, ValidationConfig{URL: server.URL})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.Validate(context.Background(), 1, 7)
+		}()
+	}
+	// Let every caller join the flight before the answer arrives.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Validate() error = %v", err)
+		}
+	}
+	if got := server.requests.Load(); got != 1 {
+		t.Errorf("%d requests sent for %d concurrent callers, want 1", got, callers)
+	}
+}
+
+func TestValidateCallerGivesUp(t *testing.T) {
+	release := make(chan struct{})
+	server := newValidationServer(t, func(int32, int) int {
+		<-release
+		return http.StatusOK
+	})
+	defer close(release)
+	c := newTestClient(t, ValidationConfig{URL: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Validate(ctx, 1, 7); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Validate() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestValidateRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		answer       func(n int32, scenarioID int) int
+		wantStatus   int
+		wantRequests int32
+	}{
+		{
+			name:    "recovers after server errors",
+			retries: 2,
+			answer: func(n int32, _ int) int {
+				if n < 3 {
+					return http.StatusServiceUnavailable
+				}
+				return http.StatusOK
+			},
+			wantRequests: 3,
+		},
+		{name: "gives up after the retries", retries: 2, answer: always(http.StatusInternalServerError), wantStatus: http.StatusInternalServerError, wantRequests: 3},
+		{name: "retries throttling", retries: 1, answer: always(http.StatusTooManyRequests), wantStatus: http.StatusTooManyRequests, wantRequests: 2},
+		{name: "rejections are final", retries: 2, answer: always(http.StatusNotFound), wantStatus: http.StatusNotFound, wantRequests: 1},
+		{name: "negative disables retries", retries: -1, answer: always(http.StatusBadGateway), wantStatus: http.StatusBadGateway, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newValidationServer(t, tt.answer)
+			c := newTestClient(t, ValidationConfig{URL: server.URL, Retries: tt.retries})
+
+			err := c.Validate(context.Background(), 1, 7)
+			if tt.wantStatus == 0 && err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if tt.wantStatus != 0 {
+				var rejected *ValidationError
+				if !errors.As(err, &rejected) || rejected.StatusCode != tt.wantStatus {
+					t.Fatalf("Validate() error = %v, want status %d", err, tt.wantStatus)
+				}
+			}
+			if got := server.requests.Load(); got != tt.wantRequests {
+				t.Errorf("%d requests sent, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestValidateBacksOff(t *testing.T) {
+	var times []time.Time
+	var mu sync.Mutex
+	server := newValidationServer(t, func(int32, int) int {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		return http.StatusInternalServerError
+	})
+	c := newTestClient(t, ValidationConfig{URL: server.URL, Retries: 2, RetryDelay: 20 * time.Millisecond})
+
+	c.Validate(context.Background(), 1, 7)
+	if len(times) != 3 {
+		t.Fatalf("%d requests sent, want 3", len(times))
+	}
+	if first, second := times[1].Sub(times[0]), times[2].Sub(times[1]); first < 20*time.Millisecond || second < 40*time.Millisecond {
+		t.Errorf("retries after %s and %s, want at least 20ms and then 40ms", first, second)
+	}
+}
+
+func TestValidateServerErrorsAreNotCached(t *testing.T) {
+	server := newValidationServer(t, func(n int32, _ int) int {
+		if n == 1 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	c := newTestClient(t, ValidationConfig{URL: server.URL, Retries: -1})
+
+	if err := c.Validate(context.Background(), 1, 7); err == nil {
+		t.Fatal("Validate() succeeded on a server error")
+	}
+	if err := c.Validate(context.Background(), 1, 7); err != nil {
+		t.Errorf("Validate() error = %v after the server recovered", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	server := newValidationServer(t, func(int32, int) int {
+		if healthy.Load() {
+			return http.StatusOK
+		}
+		return http.StatusInternalServerError
+	})
+	const openFor = 50 * time.Millisecond
+	c := newTestClient(t, ValidationConfig{URL: server.URL, Retries: -1, FailureThreshold: 2, OpenDuration: openFor})
+	ctx := context.Background()
+
+	// Distinct scenarios, so that no answer comes from the cache.
+	scenario := 0
+	validate := func() error {
+		scenario++
+		return c.Validate(ctx, scenario, 7)
+	}
+	state := func() int64 {
+		c.breakerMu.Lock()
+		defer c.breakerMu.Unlock()
+		return c.state
+	}
+
+	// Closed: failures below the threshold reach the server.
+	if err := validate(); errors.Is(err, ErrCircuitOpen) || err == nil {
+		t.Fatalf("first failure: Validate() error = %v", err)
+	}
+	if state() != breakerClosed {
+		t.Fatalf("state after one failure = %d, want closed", state())
+	}
+
+	// Open: the threshold is reached, further calls fail fast.
+	validate()
+	if state() != breakerOpen {
+		t.Fatalf("state after two failures = %d, want open", state())
+	}
+	sent := server.requests.Load()
+	if err := validate(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker: Validate() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if server.requests.Load() != sent {
+		t.Fatal("open breaker contacted the server")
+	}
+
+	// Half-open: a failing probe opens the breaker again.
+	time.Sleep(openFor)
+	if err := validate(); errors.Is(err, ErrCircuitOpen) || err == nil {
+		t.Fatalf("failing probe: Validate() error = %v", err)
+	}
+	if state() != breakerOpen {
+		t.Fatalf("state after a failing probe = %d, want open", state())
+	}
+	if err := validate(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("reopened breaker: Validate() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	// Half-open: a successful probe closes it.
+	time.Sleep(openFor)
+	healthy.Store(true)
+	if err := validate(); err != nil {
+		t.Fatalf("successful probe: Validate() error = %v", err)
+	}
+	if state() != breakerClosed {
+		t.Fatalf("state after a successful probe = %d, want closed", state())
+	}
+	if err := validate(); err != nil {
+		t.Errorf("closed breaker: Validate() error = %v", err)
+	}
+}
+
+func TestCircuitBreakerLetsOneProbeThrough(t *testing.T) {
+	c := newTestClient(t, ValidationConfig{URL: "http://127.0.0.1:0", OpenDuration: time.Millisecond})
+	c.state = breakerOpen
+	c.openedAt = time.Now().Add(-time.Second)
+
+	if !c.allow() {
+		t.Fatal("breaker open past OpenDuration did not let a probe through")
+	}
+	if c.state != breakerHalfOpen {
+		t.Fatalf("state = %d, want half-open", c.state)
+	}
+	if c.allow() {
+		t.Error("half-open breaker let a second request through while probing")
+	}
+	c.record(true)
+	if !c.allow() {
+		t.Error("breaker closed by the probe did not allow requests")
+	}
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"gorm.io/gorm"
+)
+
+// Labels are key/value pairs stored as a JSONB object. A nil map is stored as
+// an empty object, so that every row can be matched by a label selector.
+type Labels map[string]string
+
+func (l *Labels) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into labels", value)
+	}
+	labels := Labels{}
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return err
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	*l = labels
+	return nil
+}
+
+func (l Labels) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Metadata describes a device, test session, scenario or condition. Labels
+// identify and group resources and can be selected on; annotations hold
+// free-form notes that are never queried.
+type Metadata struct {
+	Description string `json:"description,omitempty"`
+	Labels      Labels `json:"labels,omitempty" gorm:"type:jsonb;not null;default:'{}';index:,type:gin"`
+	Annotations Labels `json:"annotations,omitempty" gorm:"type:jsonb;not null;default:'{}'"`
+}
+
+// GetLabels returns the labels of the resource embedding the metadata.
+func (m Metadata) GetLabels() Labels {
+	return m.Labels
+}
+
+// ScenarioLabels returns the labels a dataset recorded for a scenario is
+// grouped by: those of its device, test session and the scenario itself,
+// the more specific resource winning when keys clash.
+func ScenarioLabels(db *gorm.DB, scenarioID uint) (Labels, error) {
+	scenario := Scenario{}
+	if err := db.Preload("TestSession").Preload("TestSession.Device").First(&scenario, scenarioID).Error; err != nil {
+		return nil, err
+	}
+
+	labels := Labels{}
+	if scenario.TestSession != nil {
+		if scenario.TestSession.Device != nil {
+			maps.Copy(labels, scenario.TestSession.Device.Labels)
+		}
+		maps.Copy(labels, scenario.TestSession.Labels)
+	}
+	maps.Copy(labels, scenario.Labels)
+	return labels, nil
+}
@@ -17,39 +17,118 @@ const (
 	StatusCompleted Status = "COMPLETE"
 	StatusActive    Status = "ACTIVE"
 	StatusInactive  Status = "INACTIVE"
+	StatusAborted   Status = "ABORTED"
 )
 
+// DefaultNamespace holds the resources created through the routes that do
+// not name a namespace.
+const DefaultNamespace = "default"
+
+// Namespace groups the devices, conditions, test sessions and scenarios of
+// one lab or project. Names are unique per namespace.
+type Namespace struct {
+	gorm.Model
+	Name string `json:"name" validate:"required,dns_rfc1035_label" gorm:"uniqueIndex:idx_namespaces_name,where:deleted_at IS NULL"`
+}
+
 type Device struct {
 	gorm.Model
-	Name string `json:"name" validate:"required,min=1"`
+	Metadata
+	Namespace string `json:"namespace" gorm:"not null;default:default;uniqueIndex:idx_devices_namespace_name,priority:1,where:deleted_at IS NULL"`
+	Name      string `json:"name" validate:"required,min=1" gorm:"uniqueIndex:idx_devices_namespace_name,priority:2,where:deleted_at IS NULL"`
 }
 
 type TestSession struct {
 	gorm.Model
-	Name string `json:"name" validate:"required,min=1"`
+	Metadata
+	Namespace string `json:"namespace" gorm:"not null;default:default;index"`
+	Name      string `json:"name" validate:"required,min=1" gorm:"uniqueIndex:idx_test_sessions_device_name,priority:2,where:deleted_at IS NULL"`
 
-	DeviceID uint    `json:"device_id" validate:"required"`
+	DeviceID uint    `json:"device_id" validate:"required" gorm:"uniqueIndex:idx_test_sessions_device_name,priority:1,where:deleted_at IS NULL"`
 	Device   *Device `gorm:"foreignKey:DeviceID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"Device,omitempty"`
 }
 
+// ValueType is how the values of a condition are interpreted.
+type ValueType string
+
+const (
+	// ValueTypeText values are free strings, compared as written.
+	ValueTypeText ValueType = "text"
+	// ValueTypeNumeric values are numbers, optionally with a unit.
+	ValueTypeNumeric ValueType = "numeric"
+	// ValueTypeEnum values are one of the options of the condition.
+	ValueTypeEnum ValueType = "enum"
+	// ValueTypeBoolean values are true or false.
+	ValueTypeBoolean ValueType = "boolean"
+	// ValueTypeRange values are numeric ranges such as "20-30C".
+	ValueTypeRange ValueType = "range"
+)
+
+// Condition is a parameter varied between scenarios. Unit, Min and Max
+// apply to numeric and range conditions and are given in the canonical unit;
+// Options lists the values of an enum condition.
 type Condition struct {
 	gorm.Model
-	Name string `json:"name" validate:"required,min=1"`
+	Metadata
+	Namespace string    `json:"namespace" gorm:"not null;default:default;uniqueIndex:idx_conditions_namespace_name,priority:1,where:deleted_at IS NULL"`
+	Name      string    `json:"name" validate:"required,min=1" gorm:"uniqueIndex:idx_conditions_namespace_name,priority:2,where:deleted_at IS NULL"`
+	ValueType ValueType `json:"value_type" gorm:"not null;default:text"`
+	Unit      string    `json:"unit,omitempty"`
+	Options   []string  `json:"options,omitempty" gorm:"serializer:json"`
+	Min       *float64  `json:"min,omitempty"`
+	Max       *float64  `json:"max,omitempty"`
 }
 
+// ConditionValue is one value of a condition. Value is the string as
+// entered; the typed value parsed from it is kept alongside, numbers
+// converted to the canonical unit of the condition. A range keeps its bounds
+// in Number and NumberMax.
 type ConditionValue struct {
 	gorm.Model
-	Value       string     `json:"value" validate:"required"`
-	ConditionID uint       `json:"condition_id" validate:"required"`
+	Value       string     `json:"value" validate:"required" gorm:"uniqueIndex:idx_condition_values_condition_value,priority:2,where:deleted_at IS NULL"`
+	ConditionID uint       `json:"condition_id" validate:"required" gorm:"uniqueIndex:idx_condition_values_condition_value,priority:1,where:deleted_at IS NULL"`
 	Condition   *Condition `gorm:"foreignKey:ConditionID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"Condition,omitempty"`
+	Number      *float64   `json:"number,omitempty" gorm:"index"`
+	NumberMax   *float64   `json:"number_max,omitempty"`
+	Boolean     *bool      `json:"boolean,omitempty"`
+}
+
+// TargetAction is what happens to an active scenario that reaches its target.
+type TargetAction string
+
+const (
+	// TargetActionComplete completes the scenario.
+	TargetActionComplete TargetAction = "complete"
+	// TargetActionFlag leaves the scenario active and flags it as overdue.
+	TargetActionFlag TargetAction = "flag"
+)
+
+// Target bounds the acquisition of a scenario: the time spent active, the
+// number of frames and the number of samples recorded on every channel. The
+// scenario has reached its target as soon as one of the set limits is met.
+type Target struct {
+	DurationSeconds *int64       `json:"duration_seconds,omitempty"`
+	Frames          *int64       `json:"frames,omitempty"`
+	Samples         *int64       `json:"samples,omitempty"`
+	Action          TargetAction `json:"action,omitempty"`
+}
+
+// IsSet reports whether any limit is set.
+func (t Target) IsSet() bool {
+	return t.DurationSeconds != nil || t.Frames != nil || t.Samples != nil
 }
 
 type Scenario struct {
 	gorm.Model
-	Name               string              `json:"name" validate:"required,min=1"`
+	Metadata
+	Namespace          string              `json:"namespace" gorm:"not null;default:default;index"`
+	Name               string              `json:"name" validate:"required,min=1" gorm:"uniqueIndex:idx_scenarios_test_session_name,priority:2,where:deleted_at IS NULL"`
 	Status             Status              `json:"status" gorm:"default:INACTIVE"`
-	TestSessionID      uint                `json:"test_session_id" validate:"required"`
+	Target             Target              `json:"target" gorm:"embedded;embeddedPrefix:target_"`
+	OverdueAt          *time.Time          `json:"overdue_at,omitempty"`
+	TestSessionID      uint                `json:"test_session_id" validate:"required" gorm:"uniqueIndex:idx_scenarios_test_session_name,priority:1,where:deleted_at IS NULL;index:idx_scenarios_test_session_position,priority:1"`
 	TestSession        *TestSession        `gorm:"foreignKey:TestSessionID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"TestSession,omitempty"`
+	Position           int                 `json:"position" gorm:"not null;default:0;index:idx_scenarios_test_session_position,priority:2"`
 	ScenarioConditions []ScenarioCondition `gorm:"foreignKey:ScenarioID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"ScenarioConditions,omitempty"`
 }
 
@@ -61,10 +140,82 @@ type ScenarioCondition struct {
 	ConditionValue   *ConditionValue `gorm:"foreignKey:ConditionValueID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"ConditionValue,omitempty"`
 }
 
+// ScenarioTransition is an entry of the history of a scenario. An entry with
+// equal statuses records an event that left the status unchanged, such as the
+// scenario being flagged overdue.
+type ScenarioTransition struct {
+	gorm.Model
+	ScenarioID uint      `json:"scenario_id" gorm:"index"`
+	Scenario   *Scenario `gorm:"foreignKey:ScenarioID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"Scenario,omitempty"`
+	FromStatus Status    `json:"from_status"`
+	ToStatus   Status    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Role is the set of permissions granted to an API token.
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleAnalyst  Role = "analyst"
+	RoleDevice   Role = "device"
+)
+
+// APIToken is a bearer token for the config API. Only the SHA-256 hash of
+// the token is stored.
+type APIToken struct {
+	gorm.Model
+	Name      string     `json:"name"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex:idx_api_tokens_token_hash"`
+	Role      Role       `json:"role"`
+	DeviceID  *uint      `json:"device_id,omitempty"`
+	Device    *Device    `gorm:"foreignKey:DeviceID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"Device,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Webhook delivers the lab events of one namespace to an external URL.
+// Every request is signed with Secret, which is never returned by the API.
+type Webhook struct {
+	gorm.Model
+	Namespace string   `json:"namespace" gorm:"not null;default:default;uniqueIndex:idx_webhooks_namespace_name,priority:1,where:deleted_at IS NULL"`
+	Name      string   `json:"name" gorm:"uniqueIndex:idx_webhooks_namespace_name,priority:2,where:deleted_at IS NULL"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"-"`
+	Events    []string `json:"events" gorm:"serializer:json"`
+	Active    bool     `json:"active" gorm:"not null;default:true"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliverySucceeded DeliveryStatus = "SUCCEEDED"
+	DeliveryFailed    DeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery is one event sent to a webhook. It is retried until the
+// receiver answers with a 2xx status or the attempts are used up, and is
+// kept afterwards as the delivery log of the webhook.
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID      uint           `json:"webhook_id" gorm:"index"`
+	Webhook        *Webhook       `gorm:"foreignKey:WebhookID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"Webhook,omitempty"`
+	Event          string         `json:"event"`
+	Payload        string         `json:"payload"`
+	Status         DeliveryStatus `json:"status" gorm:"default:PENDING;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+}
+
 type ProcessedSample struct {
 	gorm.Model
 	DeviceID   uint      `json:"device_id"`
-	ScenarioID uint      `json:"scenario_id"`
+	ScenarioID uint      `json:"scenario_id" gorm:"index"`
 	FrameID    uint      `json:"frame_id"`
 	MetricName string    `json:"metric_name"`
 	Value      float64   `json:"value"`
@@ -118,5 +269,5 @@ type ProcessedChannel struct {
 	FrameID    uint        `json:"frame_id"`
 	MetricName string      `json:"metric_name"`
 	DeviceID   uint        `json:"device_id"`
-	ScenarioID uint        `json:"scenario_id"`
+	ScenarioID uint        `json:"scenario_id" gorm:"index"`
 }
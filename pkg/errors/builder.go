@@ -55,6 +55,28 @@ func NewValidationError(err error, instance string) *ErrorResponse {
 	return errResp
 }
 
+// NewUnauthorizedError creates a 401 Unauthorized error
+func NewUnauthorizedError(detail, instance string) *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusUnauthorized,
+		TypeUnauthorized,
+		TitleUnauthorized,
+		detail,
+		instance,
+	)
+}
+
+// NewForbiddenError creates a 403 Forbidden error
+func NewForbiddenError(detail, instance string) *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusForbidden,
+		TypeForbidden,
+		TitleForbidden,
+		detail,
+		instance,
+	)
+}
+
 // NewNotFoundError creates a 404 Not Found error
 func NewNotFoundError(resourceType, instance string) *ErrorResponse {
 	detail := fmt.Sprintf("The requested %s was not found", resourceType)
@@ -89,6 +111,52 @@ func NewUnprocessableEntityError(detail, instance string) *ErrorResponse {
 	)
 }
 
+// NewPreconditionFailedError creates a 412 Precondition Failed error
+func NewPreconditionFailedError(detail, instance string) *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusPreconditionFailed,
+		TypePreconditionFailed,
+		TitlePreconditionFailed,
+		detail,
+		instance,
+	)
+}
+
+// NewGoneError creates a 410 Gone error
+func NewGoneError(detail, instance string) *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusGone,
+		TypeGone,
+		TitleGone,
+		detail,
+		instance,
+	)
+}
+
+// NewScenarioNotActiveError creates a 422 error for data sent to a scenario
+// that is not ACTIVE
+func NewScenarioNotActiveError(detail, instance string) *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusUnprocessableEntity,
+		TypeScenarioNotActive,
+		TitleScenarioNotActive,
+		detail,
+		instance,
+	)
+}
+
+// NewDeviceMismatchError creates a 422 error for data sent by a device the
+// scenario does not belong to
+func NewDeviceMismatchError(detail, instance string) *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusUnprocessableEntity,
+		TypeDeviceMismatch,
+		TitleDeviceMismatch,
+		detail,
+		instance,
+	)
+}
+
 // NewInternalError creates a 500 Internal Server Error
 func NewInternalError(instance string) *ErrorResponse {
 	return NewErrorResponse(
@@ -112,14 +180,12 @@ func NewDatabaseError(err error, instance string) *ErrorResponse {
 	}
 
 	// Check for duplicate key or unique constraint violations
-	errMsg := err.Error()
-	if strings.Contains(errMsg, "duplicate key") ||
-		strings.Contains(errMsg, "UNIQUE constraint failed") ||
-		strings.Contains(errMsg, "Duplicate entry") {
+	if IsDuplicateKeyError(err) {
 		return NewConflictError("A resource with the same unique identifier already exists", instance)
 	}
 
 	// Check for foreign key constraint violations
+	errMsg := err.Error()
 	if strings.Contains(errMsg, "foreign key constraint") ||
 		strings.Contains(errMsg, "FOREIGN KEY constraint failed") {
 		return NewUnprocessableEntityError("The request references a resource that does not exist", instance)
@@ -129,6 +195,20 @@ func NewDatabaseError(err error, instance string) *ErrorResponse {
 	return NewInternalError(instance)
 }
 
+// IsDuplicateKeyError reports whether err is a unique constraint violation
+func IsDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	errMsg := err.Error()
+	return strings.Contains(errMsg, "duplicate key") ||
+		strings.Contains(errMsg, "UNIQUE constraint failed") ||
+		strings.Contains(errMsg, "Duplicate entry")
+}
+
 // WriteError writes a JSON error response with proper headers
 func WriteError(w http.ResponseWriter, errResp *ErrorResponse) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -44,6 +44,18 @@ const (
 	TypeBadRequest = "bad-request"
 	// TypeUnprocessableEntity indicates semantically invalid request
 	TypeUnprocessableEntity = "unprocessable-entity"
+	// TypePreconditionFailed indicates a conditional request (If-Match) did not match
+	TypePreconditionFailed = "precondition-failed"
+	// TypeUnauthorized indicates missing or invalid credentials
+	TypeUnauthorized = "unauthorized"
+	// TypeForbidden indicates the credentials do not allow the request
+	TypeForbidden = "forbidden"
+	// TypeGone indicates the requested state is no longer available
+	TypeGone = "gone"
+	// TypeScenarioNotActive indicates data was sent for a scenario that is not ACTIVE
+	TypeScenarioNotActive = "scenario-not-active"
+	// TypeDeviceMismatch indicates data was sent for a scenario of another device
+	TypeDeviceMismatch = "device-mismatch"
 )
 
 // Error titles for consistent messaging
@@ -54,4 +66,10 @@ const (
 	TitleInternalError       = "Internal Server Error"
 	TitleBadRequest          = "Bad Request"
 	TitleUnprocessableEntity = "Unprocessable Entity"
+	TitlePreconditionFailed  = "Precondition Failed"
+	TitleUnauthorized        = "Unauthorized"
+	TitleForbidden           = "Forbidden"
+	TitleGone                = "Gone"
+	TitleScenarioNotActive   = "Scenario Not Active"
+	TitleDeviceMismatch      = "Device Mismatch"
 )
@@ -3,9 +3,35 @@ package types
 type APIResource struct {
 	Name         string   `json:"name"`
 	SingularName string   `json:"singularName"`
+	Namespaced   bool     `json:"namespaced"`
 	Kind         string   `json:"kind"`
 	Verbs        []string `json:"verbs"`
 	ShortNames   []string `json:"shortNames,omitempty"`
+	Categories   []string `json:"categories,omitempty"`
+	// NameField and IDField are the JSON fields holding the name and the
+	// ID of an object of this kind.
+	NameField string `json:"nameField,omitempty"`
+	IDField   string `json:"idField,omitempty"`
+	// Endpoints maps each verb to the request that performs it.
+	Endpoints    map[string]APIEndpoint `json:"endpoints"`
+	Subresources []APISubresource       `json:"subresources,omitempty"`
+}
+
+// APISubresource is an operation or collection below a resource, such as
+// scenarios/activate or conditions/values.
+type APISubresource struct {
+	Name      string                 `json:"name"`
+	Kind      string                 `json:"kind,omitempty"`
+	Verbs     []string               `json:"verbs"`
+	Endpoints map[string]APIEndpoint `json:"endpoints"`
+}
+
+// APIEndpoint is the method and URL template of a verb. Templates are
+// relative to the API root; {ns} stands for the namespace and the other
+// placeholders for the path parameters named in them.
+type APIEndpoint struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
 }
 
 type APIResourceList struct {
@@ -1,32 +1,68 @@
 package types
 
+import "time"
+
+type CreateNamespaceRequest struct {
+	Name string `json:"name" validate:"required,dns_rfc1035_label"`
+}
+
+// Metadata is the description, labels and annotations of a device, test
+// session, scenario or condition. Labels are validated like Kubernetes labels
+// and can be selected on with ?labelSelector=; annotations are free-form.
+type Metadata struct {
+	Description string            `json:"description,omitempty" validate:"max=4096"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
 type CreateDeviceRequest struct {
 	Name string `json:"name" validate:"required,min=1"`
+	Metadata
 }
 
 type UpdateDeviceRequest struct {
 	ID   uint   `json:"id" validate:"required"`
 	Name string `json:"name" validate:"required,min=1"`
+	Metadata
 }
 
 type CreateTestSessionRequest struct {
 	Name     string `json:"name" validate:"required,min=1"`
 	DeviceID uint   `json:"device_id" validate:"required"`
+	Metadata
 }
 
 type UpdateTestSessionRequest struct {
 	ID       uint   `json:"id" validate:"required"`
 	Name     string `json:"name" validate:"required,min=1"`
 	DeviceID uint   `json:"device_id" validate:"required"`
+	Metadata
 }
 
+// CreateConditionRequest creates a condition. ValueType defaults to text;
+// numeric and range conditions take a Unit and optional Min and Max bounds in
+// that unit, enum conditions their Options.
 type CreateConditionRequest struct {
-	Name string `json:"name" validate:"required,min=1"`
+	Name      string   `json:"name" validate:"required,min=1"`
+	ValueType string   `json:"value_type,omitempty" validate:"omitempty,oneof=text numeric enum boolean range"`
+	Unit      string   `json:"unit,omitempty"`
+	Options   []string `json:"options,omitempty" validate:"dive,required"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Metadata
 }
 
+// UpdateConditionRequest replaces a condition. Changing the type definition
+// re-parses the existing values, which must all fit the new one.
 type UpdateConditionRequest struct {
-	ID   uint   `json:"id" validate:"required"`
-	Name string `json:"name" validate:"required,min=1"`
+	ID        uint     `json:"id" validate:"required"`
+	Name      string   `json:"name" validate:"required,min=1"`
+	ValueType string   `json:"value_type,omitempty" validate:"omitempty,oneof=text numeric enum boolean range"`
+	Unit      string   `json:"unit,omitempty"`
+	Options   []string `json:"options,omitempty" validate:"dive,required"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Metadata
 }
 
 type CreateConditionValueRequest struct {
@@ -40,21 +76,38 @@ type UpdateConditionValueRequest struct {
 	ConditionID uint   `json:"condition_id" validate:"required"`
 }
 
+// ScenarioTarget bounds the acquisition of a scenario. Once the scenario has
+// been active for DurationSeconds, or has recorded Frames frames or Samples
+// samples on every channel, it is completed, or only flagged overdue when
+// Action is "flag". Limits left out do not apply.
+type ScenarioTarget struct {
+	DurationSeconds *int64 `json:"duration_seconds,omitempty" validate:"omitempty,gt=0"`
+	Frames          *int64 `json:"frames,omitempty" validate:"omitempty,gt=0"`
+	Samples         *int64 `json:"samples,omitempty" validate:"omitempty,gt=0"`
+	Action          string `json:"action,omitempty" validate:"omitempty,oneof=complete flag"`
+}
+
 type CreateScenarioRequest struct {
-	Name          string `json:"name" validate:"required,min=1"`
-	TestSessionID uint   `json:"test_session_id" validate:"required"`
+	Name          string         `json:"name" validate:"required,min=1"`
+	TestSessionID uint           `json:"test_session_id" validate:"required"`
+	Target        ScenarioTarget `json:"target"`
+	Metadata
 }
 
 type CreateScenarioWithConditionValuesRequest struct {
-	Name              string `json:"name" validate:"required,min=1"`
-	TestSessionID     uint   `json:"test_session_id" validate:"required"`
-	ConditionValueIDs []uint `json:"condition_value_ids" validate:"required"`
+	Name              string         `json:"name" validate:"required,min=1"`
+	TestSessionID     uint           `json:"test_session_id" validate:"required"`
+	ConditionValueIDs []uint         `json:"condition_value_ids" validate:"required"`
+	Target            ScenarioTarget `json:"target"`
+	Metadata
 }
 
 type UpdateScenarioRequest struct {
-	ID            uint   `json:"id" validate:"required"`
-	Name          string `json:"name" validate:"required,min=1"`
-	TestSessionID uint   `json:"test_session_id" validate:"required"`
+	ID            uint           `json:"id" validate:"required"`
+	Name          string         `json:"name" validate:"required,min=1"`
+	TestSessionID uint           `json:"test_session_id" validate:"required"`
+	Target        ScenarioTarget `json:"target"`
+	Metadata
 }
 
 type CreateScenarioConditionRequest struct {
@@ -68,6 +121,140 @@ type UpdateScenarioConditionRequest struct {
 	ConditionValueID uint `json:"condition_value_id" validate:"required"`
 }
 
+// ValidationRequest asks whether data from DeviceID may be recorded for
+// ScenarioID.
 type ValidationRequest struct {
 	ScenarioID int `json:"scenario_id" validate:"required,gt=0"`
+	DeviceID   int `json:"device_id" validate:"required,gt=0"`
+}
+
+type ScenarioTransitionRequest struct {
+	Reason string `json:"reason" validate:"max=255"`
+}
+
+// ReorderScenariosRequest sets the order in which the scenarios of a test
+// session are run. ScenarioIDs must list every scenario of the session
+// exactly once.
+type ReorderScenariosRequest struct {
+	ScenarioIDs []uint `json:"scenario_ids" validate:"required,min=1,unique"`
+}
+
+// TestSessionAdvanceResponse reports a step of a test session run. Completed
+// is the scenario that was active before the step, Active the one activated
+// by it with the condition values to set up. Done is set once no pending
+// scenario is left.
+type TestSessionAdvanceResponse struct {
+	TestSessionID uint             `json:"test_session_id"`
+	Completed     *uint            `json:"completed,omitempty"`
+	Active        *RunbookScenario `json:"active,omitempty"`
+	Remaining     int              `json:"remaining"`
+	Done          bool             `json:"done"`
+}
+
+// RunbookScenario is a scenario of a test session run with the values of its
+// conditions, keyed by condition name.
+type RunbookScenario struct {
+	ID                uint              `json:"id"`
+	Name              string            `json:"name"`
+	Position          int               `json:"position"`
+	Conditions        map[string]string `json:"conditions"`
+	ConditionValueIDs []uint            `json:"condition_value_ids"`
+}
+
+// TestSessionDeleteResponse lists the rows removed (or, for a dry run, the
+// rows that would be removed) by a cascading test session delete.
+type TestSessionDeleteResponse struct {
+	DryRun             bool   `json:"dry_run"`
+	TestSessionID      uint   `json:"test_session_id"`
+	Scenarios          []uint `json:"scenarios"`
+	ScenarioConditions []uint `json:"scenario_conditions"`
+}
+
+// CloneTestSessionRequest copies a test session with its scenarios. DeviceID
+// retargets the copy to another device of the namespace; without a Name the
+// copy keeps the source name on another device and gets a " (copy)" suffix on
+// the same one.
+type CloneTestSessionRequest struct {
+	Name     string `json:"name,omitempty" validate:"omitempty,min=1"`
+	DeviceID uint   `json:"device_id,omitempty"`
+}
+
+// CloneScenarioRequest copies a scenario with its condition values.
+// TestSessionID moves the copy to another test session of the namespace; the
+// Name defaults like for CloneTestSessionRequest.
+type CloneScenarioRequest struct {
+	Name          string `json:"name,omitempty" validate:"omitempty,min=1"`
+	TestSessionID uint   `json:"test_session_id,omitempty"`
+}
+
+// TestSessionCloneResponse lists the rows created by cloning a test session.
+type TestSessionCloneResponse struct {
+	SourceID           uint   `json:"source_id"`
+	TestSessionID      uint   `json:"test_session_id"`
+	Scenarios          []uint `json:"scenarios"`
+	ScenarioConditions []uint `json:"scenario_conditions"`
+}
+
+// GenerateScenariosRequest designs the scenarios of a test session from
+// combinations of condition values. Strategy "factorial" creates every
+// combination, "pairwise" a smaller set in which every pair of values of two
+// conditions still occurs. Each Exclude entry maps condition names to values
+// that must never occur together. NameTemplate names the scenarios:
+// "{index}" is replaced by the 1-based position and "{<condition>}" by the
+// value of that condition; by default the values are joined with "-".
+type GenerateScenariosRequest struct {
+	Strategy     string              `json:"strategy" validate:"required,oneof=factorial pairwise"`
+	Conditions   []GenerateCondition `json:"conditions" validate:"required,min=1,dive"`
+	Exclude      []map[string]string `json:"exclude,omitempty" validate:"dive,min=1,dive,keys,required,endkeys,required"`
+	NameTemplate string              `json:"name_template,omitempty"`
+}
+
+// GenerateCondition selects a condition by name and, optionally, the subset
+// of its values to combine; without Values all of them are used.
+type GenerateCondition struct {
+	Name   string   `json:"name" validate:"required,min=1"`
+	Values []string `json:"values,omitempty" validate:"dive,required"`
+}
+
+// GenerateScenariosResponse lists the generated scenarios. For a dry run
+// nothing is created and the scenarios carry no ID.
+type GenerateScenariosResponse struct {
+	DryRun        bool                `json:"dry_run"`
+	TestSessionID uint                `json:"test_session_id"`
+	Strategy      string              `json:"strategy"`
+	Combinations  int                 `json:"combinations"`
+	Scenarios     []GeneratedScenario `json:"scenarios"`
+}
+
+type GeneratedScenario struct {
+	ID                uint              `json:"id,omitempty"`
+	Name              string            `json:"name"`
+	Conditions        map[string]string `json:"conditions"`
+	ConditionValueIDs []uint            `json:"condition_value_ids"`
+}
+
+// ScenarioStats describes the data recorded for a scenario, read from Source,
+// the first of processed_channels and processed_samples holding any. Without
+// data Source is empty and the counts are zero. SampleRate is the effective
+// rate of the channel with the fewest samples in Hz, left out when the data
+// spans no time.
+type ScenarioStats struct {
+	ScenarioID     uint           `json:"scenario_id"`
+	Source         string         `json:"source,omitempty"`
+	Frames         int64          `json:"frames"`
+	FirstTimestamp *time.Time     `json:"first_timestamp,omitempty"`
+	LastTimestamp  *time.Time     `json:"last_timestamp,omitempty"`
+	SampleRate     *float64       `json:"sample_rate,omitempty"`
+	Channels       []ChannelStats `json:"channels"`
+}
+
+// ChannelStats summarizes the samples of one channel. StdDev is the sample
+// standard deviation, left out for a single sample.
+type ChannelStats struct {
+	Name   string   `json:"name"`
+	Count  int64    `json:"count"`
+	Min    float64  `json:"min"`
+	Max    float64  `json:"max"`
+	Mean   float64  `json:"mean"`
+	StdDev *float64 `json:"stddev,omitempty"`
 }
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Role      string     `json:"role" validate:"required,oneof=admin operator analyst device"`
+	DeviceID  *uint      `json:"device_id,omitempty" validate:"required_if=Role device,excluded_unless=Role device"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APITokenResponse describes an API token. Token holds the secret and is only
+// set in the response to the request that created it.
+type APITokenResponse struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	DeviceID  *uint      `json:"device_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Token     string     `json:"token,omitempty"`
+}
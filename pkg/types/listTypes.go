@@ -0,0 +1,34 @@
+package types
+
+// ListMeta carries the paging state of a list response. Continue is empty on
+// the last page; otherwise it is passed back as ?continue= to fetch the next one.
+// ResourceVersion is the point the list reflects; passing it back with
+// ?watch=true streams the changes made after it.
+type ListMeta struct {
+	Continue        string `json:"continue,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// List is the envelope returned by every list endpoint. It replaced the bare
+// JSON arrays the device, condition, condition value, test session and
+// scenario lists used to return, so clients must read the rows from items
+// and follow metadata.continue to fetch the pages after the first.
+type List[T any] struct {
+	Kind       string   `json:"kind"`
+	APIVersion string   `json:"apiVersion"`
+	Metadata   ListMeta `json:"metadata"`
+	Items      []T      `json:"items"`
+}
+
+// NewList wraps a page of items in a list envelope.
+func NewList[T any](kind string, items []T, meta ListMeta) List[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return List[T]{
+		Kind:       kind,
+		APIVersion: "v1",
+		Metadata:   meta,
+		Items:      items,
+	}
+}
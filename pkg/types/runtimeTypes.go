@@ -0,0 +1,81 @@
+package types
+
+import "time"
+
+// Runtime phases of a test session.
+const (
+	RuntimePhaseReady     = "Ready"
+	RuntimePhaseRunning   = "Running"
+	RuntimePhaseCompleted = "Completed"
+)
+
+// TestSessionRuntime is the JSON runtime state of a test session described in
+// section 4 of specs/cli-design.md.
+type TestSessionRuntime struct {
+	APIVersion string                     `json:"apiVersion"`
+	Kind       string                     `json:"kind"`
+	Metadata   TestSessionRuntimeMetadata `json:"metadata"`
+	Spec       TestSessionRuntimeSpec     `json:"spec"`
+	Status     TestSessionRuntimeStatus   `json:"status"`
+}
+
+type TestSessionRuntimeMetadata struct {
+	ID       uint      `json:"id"`
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	DeviceID uint      `json:"deviceId"`
+}
+
+type TestSessionRuntimeSpec struct {
+	Conditions []RuntimeCondition `json:"conditions"`
+	Scenarios  []RuntimeScenario  `json:"scenarios"`
+}
+
+type RuntimeCondition struct {
+	ID     uint           `json:"id"`
+	Name   string         `json:"name"`
+	Values []RuntimeValue `json:"values"`
+}
+
+type RuntimeValue struct {
+	ID    uint   `json:"id"`
+	Value string `json:"value"`
+}
+
+type RuntimeScenario struct {
+	ID              uint                    `json:"id"`
+	Name            string                  `json:"name"`
+	Position        int                     `json:"position"`
+	Status          string                  `json:"status"`
+	ConditionValues []RuntimeConditionValue `json:"conditionValues"`
+}
+
+type RuntimeConditionValue struct {
+	ConditionID uint   `json:"conditionId"`
+	ValueID     uint   `json:"valueId"`
+	Value       string `json:"value"`
+}
+
+type TestSessionRuntimeStatus struct {
+	Phase              string     `json:"phase"`
+	ActiveScenario     *uint      `json:"activeScenario"`
+	CompletedScenarios []uint     `json:"completedScenarios"`
+	StartTime          *time.Time `json:"startTime"`
+}
+
+// DeviceRuntimeConfig is the simplified per-device view of a test session
+// that station firmware consumes.
+type DeviceRuntimeConfig struct {
+	SessionID  uint                    `json:"sessionId"`
+	DeviceID   uint                    `json:"deviceId"`
+	DeviceName string                  `json:"deviceName"`
+	Scenarios  []DeviceRuntimeScenario `json:"scenarios"`
+}
+
+type DeviceRuntimeScenario struct {
+	ID         uint                    `json:"id"`
+	Name       string                  `json:"name"`
+	Position   int                     `json:"position"`
+	Status     string                  `json:"status"`
+	Conditions map[string]RuntimeValue `json:"conditions"`
+}
@@ -0,0 +1,21 @@
+package types
+
+// CreateWebhookRequest registers a webhook. Events lists the lab events to
+// deliver; "*" subscribes to all of them.
+type CreateWebhookRequest struct {
+	Name   string   `json:"name" validate:"required,min=1,max=100"`
+	URL    string   `json:"url" validate:"required,http_url"`
+	Secret string   `json:"secret" validate:"required,min=16"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=* scenario.activated scenario.deactivated scenario.completed scenario.aborted scenario.overdue test_session.created export.finished"`
+}
+
+// UpdateWebhookRequest replaces a webhook. An empty Secret keeps the current
+// one and a missing Active keeps the current state.
+type UpdateWebhookRequest struct {
+	ID     uint     `json:"id" validate:"required"`
+	Name   string   `json:"name" validate:"required,min=1,max=100"`
+	URL    string   `json:"url" validate:"required,http_url"`
+	Secret string   `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=* scenario.activated scenario.deactivated scenario.completed scenario.aborted scenario.overdue test_session.created export.finished"`
+	Active *bool    `json:"active,omitempty"`
+}
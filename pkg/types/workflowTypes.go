@@ -0,0 +1,82 @@
+package types
+
+// TestSessionWorkflowRequest is the TestSessionWorkflow manifest described in
+// specs/manifests.md. Conditions and scenarios reference each other by name.
+type TestSessionWorkflowRequest struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind" validate:"omitempty,eq=TestSessionWorkflow"`
+	Metadata   WorkflowMetadata        `json:"metadata"`
+	Spec       TestSessionWorkflowSpec `json:"spec"`
+}
+
+type WorkflowMetadata struct {
+	Name     string `json:"name"`
+	Device   string `json:"device" validate:"required_without=DeviceID"`
+	DeviceID uint   `json:"device_id"`
+}
+
+type TestSessionWorkflowSpec struct {
+	TestSession WorkflowTestSession `json:"testSession"`
+	Conditions  []WorkflowCondition `json:"conditions" validate:"dive"`
+	Scenarios   []WorkflowScenario  `json:"scenarios" validate:"required,min=1,dive"`
+}
+
+type WorkflowTestSession struct {
+	Name        string            `json:"name" validate:"required,min=1"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+type WorkflowCondition struct {
+	Name        string   `json:"name" validate:"required,min=1"`
+	Description string   `json:"description"`
+	Values      []string `json:"values" validate:"dive,required"`
+}
+
+type WorkflowScenario struct {
+	Name        string            `json:"name" validate:"required,min=1"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Conditions  map[string]string `json:"conditions" validate:"required,min=1,dive,keys,required,endkeys,required"`
+}
+
+// WorkflowEntityCounts counts entities per kind touched by a workflow.
+type WorkflowEntityCounts struct {
+	Conditions         int `json:"conditions"`
+	ConditionValues    int `json:"conditionValues"`
+	Scenarios          int `json:"scenarios"`
+	ScenarioConditions int `json:"scenarioConditions"`
+}
+
+type WorkflowScenarioRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// TestSessionWorkflowResponse summarizes what applying a workflow created and
+// which existing entities it reused.
+type TestSessionWorkflowResponse struct {
+	TestSessionID   uint                  `json:"testSessionId"`
+	TestSessionName string                `json:"testSessionName"`
+	DeviceID        uint                  `json:"deviceId"`
+	Scenarios       []WorkflowScenarioRef `json:"scenarios"`
+	Created         WorkflowEntityCounts  `json:"created"`
+	Reused          WorkflowEntityCounts  `json:"reused"`
+}
+
+// ConditionLibraryRequest is the spec of the ConditionLibrary manifest.
+type ConditionLibraryRequest struct {
+	Conditions []WorkflowCondition `json:"conditions" validate:"required,min=1,dive"`
+}
+
+type ConditionLibraryCounts struct {
+	Conditions      int `json:"conditions"`
+	ConditionValues int `json:"conditionValues"`
+}
+
+// ConditionLibraryResponse reports how many entities applying a library
+// created and how many already existed.
+type ConditionLibraryResponse struct {
+	Created   ConditionLibraryCounts `json:"created"`
+	Unchanged ConditionLibraryCounts `json:"unchanged"`
+}